@@ -0,0 +1,496 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/resource"
+)
+
+// The key of the resource.EventStore aggregates are stored with
+const EventStoreResource = "events"
+
+// How many events an aggregate has between snapshots of its state, unless it's
+// set with SetSnapshotInterval
+const DefaultSnapshotInterval = 100
+
+// Sets how many events an aggregate has between snapshots of its state, so that
+// loading one only replays the events after the latest snapshot. Zero turns
+// snapshots off
+func (ctx *BuildContext) SetSnapshotInterval(events int) {
+	ctx.snapshots = events
+}
+
+// Aggregate represents a type whose state is made from the events it's emitted
+// instead of being stored. Methods emit events, and apply methods fold each
+// event into the state
+// i.e. aggregate Order { order_id String  status String = "new" }
+//
+//	func (Order) apply(e: OrderPlaced) { self.status = "placed" }
+//	func (Order) place() { emit(OrderPlaced({ order_id: self.order_id })) }
+type Aggregate struct {
+	Name     string
+	Private  bool
+	Comment  string
+	Identity string
+	fields   map[string]Class
+	// the values fields have before any events are applied
+	defaults map[string]ValueObject
+	// the methods that emit events by name, and the ones that apply events by
+	// the subject of the event
+	methods  map[string]aggregateMethod
+	appliers map[string]aggregateMethod
+	// the prefix of the stream of every instance, which is qualified by the name
+	// of the context so aggregates with the same name don't collide
+	stream string
+	ctx    *Context
+	store  *aggregateStore
+}
+
+type aggregateMethod struct {
+	name      string
+	node      nodes.FunctionBlock
+	arguments []Class
+	returns   Class
+	event     Event
+}
+
+type aggregateStore struct {
+	events resource.EventStore
+}
+
+func (a Aggregate) ClassName() string {
+	return a.Name
+}
+func (a Aggregate) Fields() map[string]Class {
+	return a.fields
+}
+func (a Aggregate) Constructors() ConstructorMap {
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(a, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddGenericConstructor(a, func(data map[string]ValueObject) (ValueObject, error) {
+		obj := AggregateObject{a, data, &aggregateState{}}
+		for key, class := range a.fields {
+			if nilableClass, ok := class.(NilableObject); ok {
+				if _, isNilable := data[key].(NilableObject); !isNilable {
+					nilableClass.Object = data[key]
+					obj.fields[key] = nilableClass
+				}
+			}
+		}
+		return obj, nil
+	})
+	return csMap
+}
+
+func (a Aggregate) eventStore() (resource.EventStore, error) {
+	if a.store == nil || a.store.events == nil {
+		return nil, fmt.Errorf("aggregate %s is not attached to an event store", a.Name)
+	}
+	return a.store.events, nil
+}
+
+// Returns the stream the events of an instance are stored in, which ends with
+// its identity as JSON
+func (a Aggregate) streamOf(identity ValueObject) (string, error) {
+	bytes, err := json.Marshal(ToInterface(identity))
+	if err != nil {
+		return "", err
+	}
+	return a.stream + "/" + string(bytes), nil
+}
+
+// Returns an instance that nothing has happened to yet
+func (a Aggregate) initial(identity ValueObject) (AggregateObject, error) {
+	identity, err := Convert(a.fields[a.Identity], identity)
+	if err != nil {
+		return AggregateObject{}, err
+	}
+	data := map[string]ValueObject{a.Identity: identity}
+	for key, value := range a.defaults {
+		data[key] = value
+	}
+	obj, err := Construct(a, GenericObject{data: data})
+	if err != nil {
+		return AggregateObject{}, err
+	}
+	return obj.(AggregateObject), nil
+}
+
+// Returns an instance as it is after every event that's been stored for it, and
+// the ones a unit of work is keeping to store
+func (a Aggregate) load(identity ValueObject, work *UnitOfWork) (AggregateObject, error) {
+	store, err := a.eventStore()
+	if err != nil {
+		return AggregateObject{}, err
+	}
+	stream, err := a.streamOf(identity)
+	if err != nil {
+		return AggregateObject{}, err
+	}
+	obj, err := a.initial(identity)
+	if err != nil {
+		return AggregateObject{}, err
+	}
+	snapshot, err := store.LoadSnapshot(stream)
+	if err == nil {
+		state, err := FromBytes(snapshot.Data, a)
+		if err != nil {
+			return AggregateObject{}, fmt.Errorf("cannot read snapshot of %s: %s", stream, err.Error())
+		}
+		obj = state.(AggregateObject)
+		obj.state.version = snapshot.Version
+	} else if !errors.Is(err, resource.ErrNotFound) {
+		return AggregateObject{}, err
+	}
+	events, err := store.Load(stream, obj.state.version)
+	if err != nil {
+		return AggregateObject{}, err
+	}
+	if work != nil {
+		events = append(events, work.pending(store, stream)...)
+	}
+	if obj.state.version == 0 && len(events) == 0 {
+		bytes, _ := json.Marshal(ToInterface(identity))
+		return AggregateObject{}, fmt.Errorf("%s %s not found", a.Name, string(bytes))
+	}
+	for _, stored := range events {
+		if err := a.replay(obj, stored); err != nil {
+			return AggregateObject{}, err
+		}
+		obj.state.version = stored.Version
+	}
+	return obj, nil
+}
+
+// Applies an event that was read from the event store
+func (a Aggregate) replay(obj AggregateObject, stored resource.StoredEvent) error {
+	var msg EventMessage
+	if err := json.Unmarshal(stored.Data, &msg); err != nil {
+		return fmt.Errorf("cannot read event %d of %s: %s", stored.Version, stored.Stream, err.Error())
+	}
+	applier, ok := a.appliers[msg.Event]
+	if !ok {
+		return fmt.Errorf("%s has no apply method for %s", a.Name, msg.Event)
+	}
+	ev, _, err := applier.event.Decode(resource.Message{Subject: msg.Event, Data: stored.Data})
+	if err != nil {
+		return err
+	}
+	return a.apply(obj, ev)
+}
+
+// Folds an event into the state of an instance
+func (a Aggregate) apply(obj AggregateObject, ev EventObject) error {
+	applier, ok := a.appliers[ev.ParentEvent.subject]
+	if !ok {
+		return fmt.Errorf("%s has no apply method for %s", a.Name, ev.ParentEvent.Name)
+	}
+	table := a.ctx.Symbols().WithMethod(a.Name + ".apply").ReadOnly()
+	_, err := table.callFunctionBlock(applier.node, nil, []ValueObject{ev}, obj)
+	return err
+}
+
+// Calls a method of an instance. The events it emits are applied as they're
+// emitted and kept in the unit of work it was called in once it's done, so if
+// it fails the instance is left as it was. Without a unit of work it's called
+// in one of its own
+func (a Aggregate) call(method aggregateMethod, obj AggregateObject, site CallSite, args []ValueObject) (ValueObject, error) {
+	store, err := a.eventStore()
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[string]ValueObject)
+	for key, value := range obj.fields {
+		previous[key] = value
+	}
+	restore := func() {
+		for key := range obj.fields {
+			delete(obj.fields, key)
+		}
+		for key, value := range previous {
+			obj.fields[key] = value
+		}
+	}
+
+	work := site.Work
+	if work == nil {
+		work = NewUnitOfWork(a.ctx.buildCtx)
+	}
+	emitted := []EventObject{}
+	table := a.ctx.Symbols().WithMethod(a.Name + "." + method.name).WithWork(work)
+	table.frame.Emitter = func(ev EventObject) error {
+		if err := a.apply(obj, ev); err != nil {
+			return err
+		}
+		emitted = append(emitted, ev)
+		return nil
+	}
+	version := obj.state.version
+	result, err := table.callFunctionBlock(method.node, method.returns, args, obj)
+	if err == nil && len(emitted) > 0 {
+		err = a.stage(store, work, obj, emitted)
+	}
+	if err == nil && site.Work == nil {
+		err = work.Commit()
+	}
+	if err != nil {
+		if site.Work == nil {
+			work.Discard()
+		}
+		obj.state.version = version
+		restore()
+		return nil, err
+	}
+	return result, nil
+}
+
+// Keeps the events an instance emitted in a unit of work, along with a
+// snapshot of its state if it's due for one, so they're stored and published
+// once the unit of work is committed
+func (a Aggregate) stage(store resource.EventStore, work *UnitOfWork, obj AggregateObject, emitted []EventObject) error {
+	buildCtx := a.ctx.buildCtx
+	stream, err := a.streamOf(obj.Identity())
+	if err != nil {
+		return err
+	}
+	msgs := make([]resource.Message, len(emitted))
+	for idx, ev := range emitted {
+		msgs[idx], err = buildCtx.eventMessage(ev)
+		if err != nil {
+			return err
+		}
+	}
+	version := obj.state.version
+	obj.state.version += len(emitted)
+
+	var snapshot *resource.Snapshot
+	if interval := buildCtx.snapshots; interval > 0 && obj.state.version/interval > version/interval {
+		state, err := json.Marshal(ToInterface(obj))
+		if err != nil {
+			return err
+		}
+		snapshot = &resource.Snapshot{Stream: stream, Version: obj.state.version, Data: state}
+	}
+	return work.append(store, stream, version, msgs, snapshot)
+}
+
+func (a Aggregate) Get(key string) Object {
+	identity := a.fields[a.Identity]
+	methods := map[string]Object{
+		// returns an instance that nothing has happened to yet, which is stored
+		// once it emits an event
+		"new": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				identity,
+			},
+			Returns: a,
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return a.initial(args[0])
+			},
+		}),
+		// returns an instance as it is after every event it's emitted, failing if
+		// it hasn't emitted any
+		"load": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				identity,
+			},
+			Returns: a,
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return a.load(args[0], site.Work)
+			},
+		}),
+	}
+	if method, ok := methods[key]; ok {
+		return method
+	}
+	return nil
+}
+
+// Returns the methods available on an instance so they can be validated
+// without a value
+func (a Aggregate) InstanceGet(key string) Object {
+	if _, ok := a.methods[key]; !ok {
+		return nil
+	}
+	return AggregateObject{ParentAggregate: a}.Get(key)
+}
+
+func (a Aggregate) ObjectClassFromNode(ctx *Context, node nodes.ContextObject) (Class, error) {
+	a.Name = node.Name
+	a.Private = node.Private
+	a.Comment = node.Comment
+	a.stream = ctx.Name + "." + node.Name
+	a.ctx = ctx
+	a.store = &aggregateStore{}
+
+	a.fields = make(map[string]Class)
+	a.defaults = make(map[string]ValueObject)
+	a.methods = make(map[string]aggregateMethod)
+	a.appliers = make(map[string]aggregateMethod)
+
+	if node.Extends != nil {
+		return nil, NodeError(node, "aggregate %s cannot extend %s", node.Name, node.Extends.Members[len(node.Extends.Members)-1])
+	}
+	symbols := ctx.Symbols()
+	for _, item := range node.Fields {
+		typeExpr, ok := item.Init.(nodes.TypeStatement)
+		if !ok {
+			return nil, fmt.Errorf("expected type statement")
+		}
+		class, err := ctx.EvaluateTypeExpression(typeExpr.Init)
+		if err != nil {
+			return nil, err
+		}
+		a.fields[typeExpr.Name] = class
+		if a.Identity == "" {
+			if _, ok := class.(NilableObject); ok {
+				return nil, NodeError(item, "identity %s of %s cannot be optional", typeExpr.Name, node.Name)
+			}
+			a.Identity = typeExpr.Name
+			continue
+		}
+		if typeExpr.Default == nil {
+			if _, ok := class.(NilableObject); !ok {
+				return nil, NodeError(item, "%s needs a default since %s starts out with only its identity", typeExpr.Name, node.Name)
+			}
+			continue
+		}
+		defaultClass, err := symbols.ValidateExpression(*typeExpr.Default)
+		if err != nil {
+			return nil, err
+		}
+		if err := ShouldConvert(class, defaultClass); err != nil {
+			return nil, NodeError(typeExpr, "invalid default for %s: %s", typeExpr.Name, err.Error())
+		}
+		value, err := symbols.ResolveValueObject(*typeExpr.Default)
+		if err != nil {
+			return nil, err
+		}
+		value, err = Convert(class, value)
+		if err != nil {
+			return nil, NodeError(typeExpr, "invalid default for %s: %s", typeExpr.Name, err.Error())
+		}
+		a.defaults[typeExpr.Name] = value
+	}
+	if a.Identity == "" {
+		return nil, NodeError(node, "%s needs a field to identify it by", node.Name)
+	}
+
+	return a, nil
+}
+
+// Adds a method that emits events, or one named apply that folds an event
+// into the state
+func (a Aggregate) AddMethod(ctx *Context, node nodes.ContextObjectMethod) error {
+	if _, ok := a.fields[node.Name]; ok {
+		return NodeError(node, "%s already has a field named %s", a.Name, node.Name)
+	}
+	if _, ok := a.methods[node.Name]; ok {
+		return NodeError(node, "%s already has a method named %s", a.Name, node.Name)
+	}
+	if node.Name == "new" || node.Name == "load" {
+		return NodeError(node, "%s cannot have a method named %s", a.Name, node.Name)
+	}
+	table := ctx.Symbols().WithMethod(a.Name + "." + node.Name)
+	// apply methods only fold events, so they can't make changes of their own
+	if node.Name == "apply" {
+		table = table.ReadOnly()
+	}
+	table.immutable["self"] = a
+	fn, err := table.ResolveFunctionBlock(node.Block, nil)
+	if err != nil {
+		return err
+	}
+	method := aggregateMethod{
+		name:      node.Name,
+		node:      node.Block,
+		arguments: fn.arguments,
+		returns:   fn.returns,
+	}
+	if node.Name != "apply" {
+		a.methods[node.Name] = method
+		return nil
+	}
+
+	if len(fn.arguments) != 1 {
+		return NodeError(node, "apply should have one argument for the event, got %d", len(fn.arguments))
+	}
+	event, ok := fn.arguments[0].(Event)
+	if !ok {
+		return NodeError(node, "%s can only apply events, got %s", a.Name, fn.arguments[0].ClassName())
+	}
+	if fn.returns != nil {
+		return NodeError(node, "apply cannot return a value")
+	}
+	if _, ok := a.appliers[event.subject]; ok {
+		return NodeError(node, "%s already applies %s", a.Name, event.Name)
+	}
+	method.event = event
+	a.appliers[event.subject] = method
+	return nil
+}
+
+// Connects the aggregate to the event store of the build context
+func (a Aggregate) Attach(ctx Context) error {
+	var store resource.EventStore
+	if err := ctx.Resource(EventStoreResource, &store); err != nil {
+		return fmt.Errorf("aggregate %s: %s", a.Name, err.Error())
+	}
+	a.store.events = store
+	return nil
+}
+func (a Aggregate) Detach() error {
+	a.store.events = nil
+	return nil
+}
+
+// AggregateObject represents an instance of an Aggregate
+type AggregateObject struct {
+	ParentAggregate Aggregate
+	fields          map[string]ValueObject
+	state           *aggregateState
+}
+
+type aggregateState struct {
+	// how many events of the instance have been stored
+	version int
+}
+
+// Returns the value of the field the instance is identified by
+func (ao AggregateObject) Identity() ValueObject {
+	return ao.fields[ao.ParentAggregate.Identity]
+}
+
+func (ao AggregateObject) Class() Class {
+	return ao.ParentAggregate
+}
+func (ao AggregateObject) Value() interface{} {
+	out := make(map[string]interface{})
+	for key, obj := range ao.fields {
+		out[key] = obj.Value()
+	}
+	return out
+}
+func (ao AggregateObject) Set(key string, obj ValueObject) error {
+	ao.fields[key] = obj
+	return nil
+}
+func (ao AggregateObject) Get(key string) Object {
+	a := ao.ParentAggregate
+	if method, ok := a.methods[key]; ok {
+		return NewFunction(FunctionOptions{
+			Arguments: method.arguments,
+			Returns:   method.returns,
+			Mutates:   true,
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return a.call(method, ao, site, args)
+			},
+		})
+	}
+	return ao.fields[key]
+}
@@ -0,0 +1,137 @@
+package build
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hntrl/lang/resource"
+)
+
+const ordersSource = `context acme.orders {
+	event OrderPlaced {
+		order_id String
+	}
+	event OrderShipped {
+		order_id String
+	}
+
+	aggregate Order {
+		order_id String
+		status String = "new"
+		changes Int = 0
+	}
+	func (Order) apply(e: OrderPlaced) {
+		self.status = "placed"
+		self.changes = self.changes + 1
+	}
+	func (Order) apply(e: OrderShipped) {
+		self.status = "shipped"
+		self.changes = self.changes + 1
+	}
+	func (Order) place() {
+		emit(OrderPlaced{ order_id: self.order_id })
+	}
+	func (Order) ship() {
+		emit(OrderShipped{ order_id: self.order_id })
+	}
+
+	entity Receipt {
+		order_id String
+	}
+
+	command Place(order_id: String) {
+		order := Order.new(order_id)
+		order.place()
+		Receipt.create(Receipt{ order_id: order_id })
+	}
+	command Ship(order_id: String) {
+		order := Order.load(order_id)
+		order.ship()
+	}
+	query Status(order_id: String) String {
+		return Order.load(order_id).status
+	}
+}`
+
+// CAN STORE THE EVENTS AN AGGREGATE EMITS AND LOAD IT FROM THEM
+func TestAggregate(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	buildCtx.SetSnapshotInterval(2)
+	ctx := setupContext(t, buildCtx, ordersSource)
+
+	published := []string{}
+	buildCtx.EventBus().Subscribe("acme.orders.OrderShipped", "", func(msg resource.Message) error {
+		published = append(published, msg.Subject)
+		return nil
+	})
+	invoke(t, ctx, "Place", `{"order_id": "o1"}`)
+	invoke(t, ctx, "Ship", `{"order_id": "o1"}`)
+	if out := invoke(t, ctx, "Status", `{"order_id": "o1"}`); out != `"shipped"` {
+		t.Errorf("expected shipped, got %s", out)
+	}
+	if len(published) != 1 {
+		t.Errorf("expected the event to be published once, got %v", published)
+	}
+
+	store := buildCtx.resources[EventStoreResource].(resource.EventStore)
+	events, err := store.Load("acme.orders.Order/\"o1\"", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Errorf("expected 2 events, got %d", len(events))
+	}
+	if snapshot, err := store.LoadSnapshot("acme.orders.Order/\"o1\""); err != nil || snapshot.Version != 2 {
+		t.Errorf("expected a snapshot at version 2, got %+v (%v)", snapshot, err)
+	}
+
+	// an instance that's already been created can't be created again
+	if _, err := ctx.Invoke("Place", []byte(`{"order_id": "o1"}`)); err == nil {
+		t.Errorf("expected an error creating an instance that exists")
+	}
+}
+
+// failingBatches is a storage driver whose writes can only be made one at a
+// time, so that committing a unit of work fails
+type failingBatches struct {
+	resource.MemoryStore
+}
+
+func (store failingBatches) Attach() (resource.Resource, error) {
+	return store, nil
+}
+func (store failingBatches) Apply(writes []resource.Write) error {
+	return errors.New("storage is unavailable")
+}
+
+// CAN PUBLISH THE EVENTS THAT WERE APPENDED WHEN THE REST OF THE CHANGES CAN'T
+// BE KEPT
+func TestAggregateKeepsAppendedEvents(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	driver := failingBatches{resource.NewMemoryStore()}
+	buildCtx.RegisterResource(StorageResource, driver)
+	ctx := setupContext(t, buildCtx, ordersSource)
+
+	if _, err := ctx.Invoke("Place", []byte(`{"order_id": "o1"}`)); err == nil {
+		t.Fatalf("expected the unit of work to fail")
+	}
+	if _, err := driver.Get("acme.orders.Receipt", `"o1"`); !errors.Is(err, resource.ErrNotFound) {
+		t.Errorf("expected the receipt to be lost, got %v", err)
+	}
+	count, err := driver.Count(OutboxCollection, resource.Document{"subject": "acme.orders.OrderPlaced"})
+	if err != nil || count != 1 {
+		t.Fatalf("expected the appended event to be left in the outbox, got %d (%v)", count, err)
+	}
+
+	published := 0
+	buildCtx.EventBus().Subscribe("acme.orders.OrderPlaced", "", func(msg resource.Message) error {
+		published++
+		return nil
+	})
+	if err := buildCtx.RelayOutbox(); err != nil {
+		t.Fatal(err)
+	}
+	if published != 1 {
+		t.Errorf("expected the event to be relayed, got %d", published)
+	}
+}
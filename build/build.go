@@ -2,9 +2,13 @@ package build
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/hntrl/lang/language"
 	"github.com/hntrl/lang/language/nodes"
@@ -21,17 +25,53 @@ type BuildContext struct {
 	imports   map[string]*Context
 	classes   map[string]Class
 	resources map[string]resource.Resource
+	// fills the settings of a resource that's made when it's requested
+	configure func(key string, target interface{}) error
+	bus       resource.EventBus
+	delivery  DeliveryPolicy
+	snapshots int
+	scheduler *Scheduler
+	// how long the results of methods with an idempotency key are kept, and how
+	// long their keys are reserved for while they're running
+	idempotencyTTL   time.Duration
+	idempotencyLease time.Duration
+
+	clock  Clock
+	random *rand.Rand
+	logger *slog.Logger
+	output io.Writer
 }
 
 func NewBuildContext() *BuildContext {
-	return &BuildContext{
+	ctx := &BuildContext{
 		packages: make(map[string]Object),
 		imports:  make(map[string]*Context),
 		classes: map[string]Class{
-			"type": &Type{},
+			"type":       &Type{},
+			"enum":       &Enum{},
+			"entity":     &Entity{},
+			"query":      &Operation{Kind: QueryOperation},
+			"command":    &Operation{Kind: CommandOperation},
+			"event":      &Event{},
+			"subscriber": &Subscriber{},
+			"on":         &Subscriber{},
+			"aggregate":  &Aggregate{},
+			"projection": &Entity{Projection: true},
+			"saga":       &Saga{},
+			"schedule":   &Schedule{},
 		},
 		resources: make(map[string]resource.Resource),
+		bus:       resource.NewMemoryBus(),
+		delivery:  DefaultDeliveryPolicy,
+		snapshots: DefaultSnapshotInterval,
+		clock:     SystemClock{},
+		random:    cryptoRandom,
+
+		idempotencyTTL:   DefaultIdempotencyTTL,
+		idempotencyLease: DefaultIdempotencyLease,
 	}
+	ctx.scheduler = newScheduler(ctx)
+	return ctx
 }
 
 func (ctx *BuildContext) GetPackage(pkg string) (interface{}, error) {
@@ -59,6 +99,20 @@ func (ctx *BuildContext) RegisterClass(key string, class Class) {
 	ctx.classes[key] = class
 }
 
+// Registers a resource that's already attached so that it's used instead of
+// one being made when it's requested. This is how resources that are only
+// known by an interface (i.e. resource.StorageDriver) are provided
+func (ctx *BuildContext) RegisterResource(key string, res resource.Resource) {
+	ctx.resources[key] = res
+}
+
+// Sets how the settings of a resource that isn't registered are filled before
+// it's made and attached, which is given the key the resource is requested by
+// and a pointer to it, i.e. packages.ConfigSources.Configure
+func (ctx *BuildContext) SetResourceConfigurer(configure func(key string, target interface{}) error) {
+	ctx.configure = configure
+}
+
 // Context represents a single context as defined in a manifest
 type Context struct {
 	Name     string
@@ -82,16 +136,19 @@ func NewContext(buildCtx *BuildContext, path string, node nodes.Manifest) (*Cont
 			"String":   String{},
 			"Double":   Double{},
 			"Float":    Float{},
+			"Decimal":  Decimal{},
 			"Int":      Integer{},
 			"Bool":     Boolean{},
-			"Date":     Date{},
-			"DateTime": DateTime{},
+			"Date":     Date{buildCtx: buildCtx},
+			"DateTime": DateTime{buildCtx: buildCtx},
+			"Duration": Duration{},
+			"Bytes":    Bytes{},
 			"print": NewFunction(FunctionOptions{
 				Arguments: []Class{
 					GenericObject{},
 				},
 				Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
-					fmt.Println(args[0])
+					fmt.Fprintln(buildCtx.Output(), printable(args[0]))
 					return nil, nil
 				},
 			}),
@@ -110,15 +167,64 @@ func NewContext(buildCtx *BuildContext, path string, node nodes.Manifest) (*Cont
 					return nil, fmt.Errorf("cannot get length of %s", args[0].Class().ClassName())
 				},
 			}),
-			"emit": NewFunction(FunctionOptions{
-				Arguments: []Class{
-					// FIXME: same problem as len(), but instead of indexable it should be
-					// classes.EventInstance
-					GenericObject{},
-				},
-				Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+			"emit": NewGenericFunction(GenericFunctionOptions{
+				Validator: func(args []Class) (Class, error) {
+					if len(args) != 1 {
+						return nil, fmt.Errorf("expected 1 argument, got %d", len(args))
+					}
+					if _, ok := args[0].(Event); !ok {
+						return nil, fmt.Errorf("cannot emit %s since it isn't an event", args[0].ClassName())
+					}
 					return nil, nil
 				},
+				Mutates: true,
+				SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+					obj, ok := args[0].(EventObject)
+					if !ok {
+						return nil, fmt.Errorf("cannot emit %s since it isn't an event", args[0].Class().ClassName())
+					}
+					if site.Emitter != nil {
+						return nil, site.Emitter(obj)
+					}
+					// events emitted by a command are only published once its
+					// changes are kept
+					if site.Work != nil {
+						return nil, site.Work.Emit(obj)
+					}
+					return nil, buildCtx.Emit(obj)
+				},
+			}),
+			// invokes a command with a payload once a duration has passed,
+			// returning the ID of the job
+			"later": NewGenericFunction(GenericFunctionOptions{
+				Validator: func(args []Class) (Class, error) {
+					if len(args) != 3 {
+						return nil, fmt.Errorf("expected 3 arguments, got %d", len(args))
+					}
+					if _, ok := args[0].(Duration); !ok {
+						return nil, fmt.Errorf("expected Duration, got %s", args[0].ClassName())
+					}
+					op, ok := args[1].(Operation)
+					if !ok || op.Kind != CommandOperation {
+						return nil, fmt.Errorf("can only schedule commands, got %s", args[1].ClassName())
+					}
+					if err := ShouldConvert(op.payload, args[2]); err != nil {
+						return nil, fmt.Errorf("invalid payload for %s: %s", op.Name, err.Error())
+					}
+					return String{}, nil
+				},
+				Mutates: true,
+				SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+					op, ok := args[1].(Operation)
+					if !ok || op.Kind != CommandOperation {
+						return nil, fmt.Errorf("can only schedule commands, got %s", args[1].Class().ClassName())
+					}
+					id, err := buildCtx.scheduler.later(site, time.Duration(args[0].(DurationLiteral)), op, args[2])
+					if err != nil {
+						return nil, err
+					}
+					return StringLiteral(id), nil
+				},
 			}),
 		},
 		unresolvedObjects: make(map[string]nodes.ContextObject),
@@ -260,6 +366,7 @@ func (ctx Context) Symbols() SymbolTable {
 	return SymbolTable{
 		immutable: global,
 		local:     make(map[string]Object),
+		frame:     Frame{Context: ctx.Name, File: ctx.filePath},
 	}
 }
 
@@ -274,18 +381,40 @@ func (ctx *Context) EvaluateTypeExpression(expr nodes.TypeExpression) (Class, er
 	return ctx.Symbols().ResolveTypeExpression(expr)
 }
 
-func (ctx *Context) Attach() {
+// Attaches the runtime objects of the context, detaching the ones that were
+// attached when one of them can't be
+func (ctx *Context) Attach() error {
+	if err := ctx.buildCtx.scheduler.Attach(*ctx); err != nil {
+		return err
+	}
+	var attached []RuntimeNode
 	for _, obj := range ctx.objects {
 		if runtimeObj, ok := obj.(RuntimeNode); ok {
 			err := runtimeObj.Attach(*ctx)
 			if err != nil {
-				ctx.Detach()
-				panic(err)
+				for _, obj := range attached {
+					obj.Detach()
+				}
+				ctx.buildCtx.scheduler.Detach()
+				return err
 			}
+			attached = append(attached, runtimeObj)
 		}
 	}
+	// events that were committed but never published, i.e. because the host
+	// stopped before they were, are published now that subscribers are attached
+	if err := ctx.buildCtx.RelayOutbox(); err != nil {
+		ctx.buildCtx.Logger().Warn(fmt.Sprintf("cannot relay outbox: %s", err.Error()))
+	}
+	return nil
 }
 func (ctx *Context) Detach() {
+	for _, obj := range ctx.objects {
+		if runtimeObj, ok := obj.(RuntimeNode); ok {
+			runtimeObj.Detach()
+		}
+	}
+	ctx.buildCtx.scheduler.Detach()
 	for _, res := range ctx.buildCtx.resources {
 		res.Detach()
 	}
@@ -298,7 +427,16 @@ func (ctx *Context) Resource(key string, vPtr interface{}) error {
 		return fmt.Errorf("%s is not a pointer", key)
 	}
 	if buildCtx.resources[key] == nil {
-		if blankRes, ok := reflect.New(ptr.Type().Elem()).Interface().(resource.Resource); ok {
+		if ptr.Type().Elem().Kind() == reflect.Interface {
+			return fmt.Errorf("no resource registered as %s", key)
+		}
+		blank := reflect.New(ptr.Type().Elem())
+		if blankRes, ok := blank.Interface().(resource.Resource); ok {
+			if buildCtx.configure != nil {
+				if err := buildCtx.configure(key, blank.Interface()); err != nil {
+					return err
+				}
+			}
 			res, err := blankRes.Attach()
 			if err != nil {
 				return err
@@ -308,7 +446,11 @@ func (ctx *Context) Resource(key string, vPtr interface{}) error {
 			return fmt.Errorf("%T is not a resource", vPtr)
 		}
 	}
-	if ptr.Type().Elem() != reflect.TypeOf(buildCtx.resources[key]) {
+	if ptr.Type().Elem().Kind() == reflect.Interface {
+		if !reflect.TypeOf(buildCtx.resources[key]).Implements(ptr.Type().Elem()) {
+			return fmt.Errorf("resource %s does not implement %s", key, ptr.Type().Elem())
+		}
+	} else if ptr.Type().Elem() != reflect.TypeOf(buildCtx.resources[key]) {
 		return fmt.Errorf("resource %s is not of type %s", key, ptr.Type().Elem())
 	}
 	ptr.Elem().Set(reflect.ValueOf(buildCtx.resources[key]))
@@ -0,0 +1,138 @@
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/language/parser"
+	"github.com/hntrl/lang/language/tokens"
+	"github.com/hntrl/lang/resource"
+)
+
+var testEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var discardLogs = slog.NewTextHandler(io.Discard, nil)
+
+// Returns a build context that keeps everything in memory, runs on a manual
+// clock and has a fixed seed so that tests are reproducible
+func setupBuildContext(t *testing.T) (*BuildContext, *ManualClock) {
+	t.Helper()
+	clock := NewManualClock(testEpoch)
+	buildCtx := NewBuildContext()
+	buildCtx.SetClock(clock)
+	buildCtx.SetSeed(1)
+	buildCtx.SetOutput(io.Discard)
+	buildCtx.SetLogHandler(discardLogs)
+	buildCtx.RegisterResource(StorageResource, resource.NewMemoryStore())
+	buildCtx.RegisterResource(EventStoreResource, resource.NewMemoryEventStore())
+	return buildCtx, clock
+}
+
+// Builds a context from its source
+func newContext(buildCtx *BuildContext, src string) (*Context, error) {
+	var lexErr error
+	reader := bufio.NewReader(strings.NewReader(src))
+	lexer := parser.NewLexer(reader, func(pos tokens.Position, msg string) {
+		if lexErr == nil {
+			lexErr = fmt.Errorf("%s, %s", pos.String(), msg)
+		}
+	})
+	manifest, err := nodes.ParseManifest(parser.NewParser(lexer))
+	if lexErr != nil {
+		return nil, lexErr
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot parse: %s", err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("cannot validate: %s", err)
+	}
+	return NewContext(buildCtx, manifest.Context.Name, *manifest)
+}
+
+// Builds a context from its source and attaches it
+func setupContext(t *testing.T, buildCtx *BuildContext, src string) *Context {
+	t.Helper()
+	ctx, err := newContext(buildCtx, src)
+	if err != nil {
+		t.Fatalf("cannot build: %s", err)
+	}
+	if err := ctx.Attach(); err != nil {
+		t.Fatalf("cannot attach: %s", err)
+	}
+	t.Cleanup(ctx.Detach)
+	return ctx
+}
+
+// Invokes an operation of a context, failing the test if it returns an error
+func invoke(t *testing.T, ctx *Context, name string, payload string) string {
+	t.Helper()
+	out, err := ctx.Invoke(name, []byte(payload))
+	if err != nil {
+		t.Fatalf("cannot invoke %s: %s", name, err)
+	}
+	return string(out)
+}
+
+// Evaluates the statements of a function block in a query, returning the result
+// as JSON
+func evaluate(t *testing.T, returns string, block string) (string, error) {
+	buildCtx, _ := setupBuildContext(t)
+	src := fmt.Sprintf("context acme.test {\n\tquery Run() %s {\n%s\n\t}\n}", returns, block)
+	ctx, err := newContext(buildCtx, src)
+	if err != nil {
+		return "", err
+	}
+	out, err := ctx.Invoke("Run", nil)
+	return string(out), err
+}
+
+// Calls a function, failing the test if it doesn't return in time
+func withTimeout(t *testing.T, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out")
+	}
+}
+
+// settingsResource is a resource whose settings are filled when it's made
+type settingsResource struct {
+	URL string
+}
+
+func (res settingsResource) Attach() (resource.Resource, error) {
+	return res, nil
+}
+func (res settingsResource) Detach() error {
+	return nil
+}
+
+// CAN FILL THE SETTINGS OF A RESOURCE THAT'S MADE WHEN IT'S REQUESTED
+func TestResourceConfigurer(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	buildCtx.SetResourceConfigurer(func(key string, target interface{}) error {
+		target.(*settingsResource).URL = "test://" + key
+		return nil
+	})
+	ctx := setupContext(t, buildCtx, `context acme.settings {}`)
+
+	var res settingsResource
+	if err := ctx.Resource("custom", &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.URL != "test://custom" {
+		t.Errorf("expected the resource to be configured, got %q", res.URL)
+	}
+}
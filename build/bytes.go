@@ -0,0 +1,147 @@
+package build
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hntrl/lang/language/tokens"
+)
+
+// Bytes represents arbitrary binary data, i.e. the result of a hash
+type Bytes struct{}
+
+func (b Bytes) ClassName() string {
+	return "Bytes"
+}
+func (b Bytes) Constructors() ConstructorMap {
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(Bytes{}, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	// strings are taken as their UTF-8 encoding
+	csMap.AddConstructor(String{}, func(obj ValueObject) (ValueObject, error) {
+		return BytesLiteral(obj.(StringLiteral)), nil
+	})
+	return csMap
+}
+
+func bytesComparePredicate(cb func(a, b []byte) bool) OperatorFn {
+	return func(a, b ValueObject) (ValueObject, error) {
+		return BooleanLiteral(cb(a.(BytesLiteral), b.(BytesLiteral))), nil
+	}
+}
+func (b Bytes) ComparableRules() ComparatorRules {
+	rules := NewComparatorRules()
+	rules.AddComparator(Bytes{}, tokens.EQUALS, bytesComparePredicate(func(a, b []byte) bool {
+		return bytes.Equal(a, b)
+	}))
+	rules.AddComparator(Bytes{}, tokens.NOT_EQUALS, bytesComparePredicate(func(a, b []byte) bool {
+		return !bytes.Equal(a, b)
+	}))
+	return rules
+}
+func (b Bytes) OperatorRules() OperatorRules {
+	rules := NewOperatorRules()
+	rules.AddOperator(Bytes{}, tokens.ADD, func(a, b ValueObject) (ValueObject, error) {
+		out := make([]byte, 0, len(a.(BytesLiteral))+len(b.(BytesLiteral)))
+		return BytesLiteral(append(append(out, a.(BytesLiteral)...), b.(BytesLiteral)...)), nil
+	})
+	return rules
+}
+func (b Bytes) ElementClass() Class {
+	return Integer{}
+}
+func (b Bytes) Get(key string) Object {
+	// methods available on the instance so they can be validated without a value
+	return BytesLiteral{}.Get(key)
+}
+
+type BytesLiteral []byte
+
+func (bl BytesLiteral) Class() Class {
+	return Bytes{}
+}
+func (bl BytesLiteral) Value() interface{} {
+	return []byte(bl)
+}
+func (bl BytesLiteral) Set(key string, obj ValueObject) error {
+	return fmt.Errorf("cannot set property %s of Bytes", key)
+}
+func (bl BytesLiteral) Get(key string) Object {
+	switch key {
+	case "text":
+		return NewFunction(FunctionOptions{
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				if !utf8.Valid(bl) {
+					return nil, fmt.Errorf("bytes are not valid UTF-8")
+				}
+				return StringLiteral(bl), nil
+			},
+		})
+	case "hex":
+		return NewFunction(FunctionOptions{
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return StringLiteral(hex.EncodeToString(bl)), nil
+			},
+		})
+	case "base64":
+		return NewFunction(FunctionOptions{
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return StringLiteral(base64.StdEncoding.EncodeToString(bl)), nil
+			},
+		})
+	}
+	return nil
+}
+
+func (bl BytesLiteral) GetIndex(index int) (ValueObject, error) {
+	if index < 0 || index >= len(bl) {
+		return nil, fmt.Errorf("index %d out of range with length %d", index, len(bl))
+	}
+	return IntegerLiteral(bl[index]), nil
+}
+func (bl BytesLiteral) SetIndex(index int, obj ValueObject) (Indexable[ValueObject], error) {
+	if index < 0 || index >= len(bl) {
+		return nil, fmt.Errorf("index %d out of range with length %d", index, len(bl))
+	}
+	value, ok := obj.(IntegerLiteral)
+	if !ok {
+		return nil, fmt.Errorf("cannot assign %s to Bytes index", obj.Class().ClassName())
+	}
+	if value < 0 || value > 255 {
+		return nil, fmt.Errorf("%d overflows byte", value)
+	}
+	out := make(BytesLiteral, len(bl))
+	copy(out, bl)
+	out[index] = byte(value)
+	return out, nil
+}
+func (bl BytesLiteral) Range(a, b int) (Indexable[ValueObject], error) {
+	if a < 0 || b > len(bl) || a > b {
+		return nil, fmt.Errorf("slice bounds [%d:%d] out of range with length %d", a, b, len(bl))
+	}
+	return bl[a:b], nil
+}
+func (bl BytesLiteral) Len() int {
+	return len(bl)
+}
+
+// Bytes are represented as standard base64 in JSON, the same as []byte
+func (bl BytesLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]byte(bl))
+}
+func (bl *BytesLiteral) UnmarshalJSON(data []byte) error {
+	var raw []byte
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*bl = raw
+	return nil
+}
@@ -1,10 +1,15 @@
 package build
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"reflect"
+	"time"
 
 	"github.com/hntrl/lang/language/tokens"
 
@@ -13,31 +18,55 @@ import (
 
 /* Class helpers + definitions */
 
-// Converts a byte array into a ValueObject
-func FromBytes(bytes []byte) (ValueObject, error) {
-	var str string
-	isString := json.Unmarshal(bytes, &str) == nil
-	var obj map[string]interface{}
-	isJSON := json.Unmarshal(bytes, &obj) == nil
-
-	if isJSON {
+// Converts JSON into a ValueObject of the given class. If the class is nil the
+// JSON is converted without a target and objects become GenericObjects
+func FromBytes(data []byte, class Class) (ValueObject, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var obj interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal %s: %s", string(data), err.Error())
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("cannot unmarshal %s: unexpected data after value", string(data))
+	}
+	if class == nil {
 		return FromInterface(obj)
-	} else if isString {
-		return StringLiteral(str), nil
-	} else {
-		return nil, fmt.Errorf("cannot unmarshal %s", string(bytes))
 	}
+	return FromInterfaceAs(obj, class)
 }
 
 // Converts a standard interface into a ValueObject
 func FromInterface(obj interface{}) (ValueObject, error) {
+	switch obj := obj.(type) {
+	case time.Time:
+		return DateTimeLiteral{obj}, nil
+	case time.Duration:
+		return DurationLiteral(obj), nil
+	case []byte:
+		return BytesLiteral(obj), nil
+	case json.Number:
+		if value, err := obj.Int64(); err == nil {
+			return IntegerLiteral(value), nil
+		}
+		value, err := obj.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return NumberLiteral(value), nil
+	}
 	items := reflect.ValueOf(obj)
 	switch items.Kind() {
 	case reflect.String:
 		return StringLiteral(items.String()), nil
-	case reflect.Int:
-		return NumberLiteral(items.Int()), nil
-	case reflect.Float64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return IntegerLiteral(items.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if items.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows Integer", items.Uint())
+		}
+		return IntegerLiteral(items.Uint()), nil
+	case reflect.Float32, reflect.Float64:
 		return NumberLiteral(items.Float()), nil
 	case reflect.Slice:
 		var arr []ValueObject
@@ -69,6 +98,199 @@ func FromInterface(obj interface{}) (ValueObject, error) {
 	}
 }
 
+// Returns the form of a value that's given to encoding/json. Anything that
+// knows how to marshal itself is left alone, and objects are walked by their
+// fields so that nested values keep their own representation
+func ToInterface(obj ValueObject) interface{} {
+	if obj == nil {
+		return nil
+	}
+	if nilable, ok := obj.(NilableObject); ok {
+		return ToInterface(nilable.Object)
+	}
+	if _, ok := obj.(json.Marshaler); ok {
+		return obj
+	}
+	if iterable, ok := obj.(Iterable); ok {
+		items := make([]interface{}, len(iterable.Items))
+		for idx, item := range iterable.Items {
+			items[idx] = ToInterface(item)
+		}
+		return items
+	}
+	if class, ok := obj.Class().(ObjectClass); ok && class.Fields() != nil {
+		out := make(map[string]interface{})
+		for key := range class.Fields() {
+			if value, ok := obj.Get(key).(ValueObject); ok {
+				out[key] = ToInterface(value)
+			} else {
+				out[key] = nil
+			}
+		}
+		return out
+	}
+	return obj.Value()
+}
+
+// Converts a standard interface into a ValueObject of the given class. Objects
+// are decoded using the fields of the class so that anything nested comes out
+// as the class it's declared as. Errors include the path of the value that
+// couldn't be decoded
+func FromInterfaceAs(obj interface{}, class Class) (ValueObject, error) {
+	return decodeInterface(obj, class, "")
+}
+
+func decodeError(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %s", path, err.Error())
+}
+func decodePath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Returns the name of a decoded JSON value for error messages
+func jsonKind(obj interface{}) string {
+	switch obj.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", obj)
+}
+
+func decodeInterface(obj interface{}, class Class, path string) (ValueObject, error) {
+	switch class := class.(type) {
+	case GenericObject:
+		value, err := FromInterface(obj)
+		if err != nil {
+			return nil, decodeError(path, err)
+		}
+		return value, nil
+	case NilableObject:
+		if obj == nil {
+			return NilableObject{class.ClassObject, nil}, nil
+		}
+		value, err := decodeInterface(obj, class.ClassObject, path)
+		if err != nil {
+			return nil, err
+		}
+		return NilableObject{class.ClassObject, value}, nil
+	case Iterable:
+		items, ok := obj.([]interface{})
+		if !ok {
+			return nil, decodeError(path, fmt.Errorf("expected array, got %s", jsonKind(obj)))
+		}
+		iterable := NewIterable(class.ParentType, len(items))
+		for idx, item := range items {
+			value, err := decodeInterface(item, class.ParentType, fmt.Sprintf("%s[%d]", path, idx))
+			if err != nil {
+				return nil, err
+			}
+			iterable.Items[idx] = value
+		}
+		return iterable, nil
+	case Decimal:
+		// decimals are parsed from the original text so they stay exact
+		if number, ok := obj.(json.Number); ok {
+			value, err := ParseDecimal(number.String())
+			if err != nil {
+				return nil, decodeError(path, err)
+			}
+			return value, nil
+		}
+	case Bytes:
+		// bytes are encoded as base64 the same as in MarshalJSON
+		if str, ok := obj.(string); ok {
+			value, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return nil, decodeError(path, err)
+			}
+			return BytesLiteral(value), nil
+		}
+	case Integer:
+		if number, ok := obj.(json.Number); ok {
+			value, err := number.Int64()
+			if err != nil {
+				return nil, decodeError(path, fmt.Errorf("expected Int, got %s", number.String()))
+			}
+			return IntegerLiteral(value), nil
+		}
+	}
+	if obj == nil {
+		return nil, decodeError(path, fmt.Errorf("expected %s, got null", class.ClassName()))
+	}
+	if objectClass, ok := class.(ObjectClass); ok && objectClass.Fields() != nil {
+		props, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil, decodeError(path, fmt.Errorf("expected %s, got %s", class.ClassName(), jsonKind(obj)))
+		}
+		fields := objectClass.Fields()
+		generic := NewGenericObject()
+		for key := range props {
+			if fields[key] == nil {
+				return nil, decodeError(decodePath(path, key), fmt.Errorf("unknown property of %s", class.ClassName()))
+			}
+		}
+		for key, fieldClass := range fields {
+			prop, ok := props[key]
+			if !ok {
+				if _, isNilable := fieldClass.(NilableObject); !isNilable {
+					return nil, decodeError(decodePath(path, key), fmt.Errorf("missing property of %s", class.ClassName()))
+				}
+			}
+			value, err := decodeInterface(prop, fieldClass, decodePath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			generic.fields[key] = fieldClass
+			generic.data[key] = value
+		}
+		value, err := Construct(class, generic)
+		if err != nil {
+			return nil, decodeError(path, err)
+		}
+		return value, nil
+	}
+	// primitives have to match the kind of JSON value exactly, everything else
+	// is constructed from its plain form (i.e. dates and enums from strings)
+	kind := jsonKind(obj)
+	mismatch := false
+	switch class.(type) {
+	case String:
+		mismatch = kind != "string"
+	case Boolean:
+		mismatch = kind != "boolean"
+	case Integer, Float, Double, Number:
+		mismatch = kind != "number"
+	}
+	plain, err := FromInterface(obj)
+	if err != nil {
+		return nil, decodeError(path, err)
+	}
+	if mismatch || ShouldConstruct(class, plain.Class()) != nil {
+		return nil, decodeError(path, fmt.Errorf("expected %s, got %s", class.ClassName(), kind))
+	}
+	value, err := Construct(class, plain)
+	if err != nil {
+		return nil, decodeError(path, err)
+	}
+	return value, nil
+}
+
 // Recursively flatten a given ObjectClass into a period delimited map with all fields
 func FlattenObject(val ValueObject, m map[string]interface{}, p string) error {
 	obj, ok := val.Class().(ObjectClass)
@@ -159,6 +381,11 @@ func ShouldConstruct(class, from Class) error {
 	if ClassEquals(class, from) {
 		return nil
 	}
+	if _, ok := class.(String); ok {
+		if _, ok := from.(StringableClass); ok {
+			return nil
+		}
+	}
 	if iterable, ok := class.(Iterable); ok {
 		if fromIterable, ok := from.(Iterable); ok {
 			if fn := iterable.ParentType.Constructors().Get(fromIterable.ParentType); fn != nil {
@@ -205,6 +432,15 @@ func Construct(class Class, from ValueObject) (ValueObject, error) {
 	if ClassEquals(class, from.Class()) {
 		return from, nil
 	}
+	if _, ok := class.(String); ok {
+		if stringable, ok := from.Class().(StringableClass); ok {
+			str, err := stringable.ToString(from)
+			if err != nil {
+				return nil, err
+			}
+			return StringLiteral(str), nil
+		}
+	}
 	if iterable, ok := class.(Iterable); ok {
 		if fromIterable, ok := from.(Iterable); ok {
 			if fn := iterable.ParentType.Constructors().Get(fromIterable.ParentType); fn != nil {
@@ -272,14 +508,57 @@ func Construct(class Class, from ValueObject) (ValueObject, error) {
 	return nil, CannotConstructError(class.ClassName(), from.Class().ClassName())
 }
 
+// Returns an error if converting from into class would lose information. This
+// is the case when narrowing a number down the numeric tower, or when moving
+// between exact decimals and floating point numbers
+func checkNarrowing(class, from Class) error {
+	if nilable, ok := class.(NilableObject); ok {
+		class = nilable.ClassObject
+	}
+	if nilable, ok := from.(NilableObject); ok {
+		from = nilable.ClassObject
+	}
+	if iterable, ok := class.(Iterable); ok {
+		if fromIterable, ok := from.(Iterable); ok {
+			return checkNarrowing(iterable.ParentType, fromIterable.ParentType)
+		}
+		return nil
+	}
+	classRank, fromRank := numericRank(class), numericRank(from)
+	_, toDecimal := class.(Decimal)
+	_, fromDecimal := from.(Decimal)
+	if (classRank != -1 && fromRank > classRank) || (toDecimal && fromRank > 0) || (fromDecimal && classRank != -1) {
+		return NarrowingError(class.ClassName(), from.ClassName())
+	}
+	return nil
+}
+
+// Returns an error if a value of class from can't be implicitly converted to
+// class. This is used anywhere a value is passed somewhere that expects a
+// class (arguments, assignments, returns) and is the same as ShouldConstruct
+// except that numbers can only be widened
+func ShouldConvert(class, from Class) error {
+	if err := checkNarrowing(class, from); err != nil {
+		return err
+	}
+	return ShouldConstruct(class, from)
+}
+func Convert(class Class, from ValueObject) (ValueObject, error) {
+	if err := checkNarrowing(class, from.Class()); err != nil {
+		return nil, err
+	}
+	return Construct(class, from)
+}
+
 type OperatorFn func(ValueObject, ValueObject) (ValueObject, error)
 type OperatorMap map[tokens.Token]OperatorFn
 type OperatorRules struct {
-	values classMap
+	values  classMap
+	returns classMap
 }
 
 func NewOperatorRules() OperatorRules {
-	return OperatorRules{values: classMap{}}
+	return OperatorRules{values: classMap{}, returns: classMap{}}
 }
 func (rules *OperatorRules) AddOperator(class Class, token tokens.Token, fn OperatorFn) error {
 	if rules.values.get(class) == nil {
@@ -289,6 +568,20 @@ func (rules *OperatorRules) AddOperator(class Class, token tokens.Token, fn Oper
 	newMap.(OperatorMap)[token] = fn
 	return rules.values.set(class, newMap)
 }
+
+// Adds an operator whose result isn't the same class as the left operand
+// i.e. DateTime - DateTime = Duration
+func (rules *OperatorRules) AddOperatorReturning(class Class, token tokens.Token, returns Class, fn OperatorFn) error {
+	if rules.returns.get(class) == nil {
+		rules.returns.set(class, map[tokens.Token]Class{})
+	}
+	returnsMap := rules.returns.get(class)
+	returnsMap.(map[tokens.Token]Class)[token] = returns
+	if err := rules.returns.set(class, returnsMap); err != nil {
+		return err
+	}
+	return rules.AddOperator(class, token, fn)
+}
 func (rules OperatorRules) Get(class Class, token tokens.Token) OperatorFn {
 	obj := rules.values.get(class)
 	if obj != nil {
@@ -297,6 +590,16 @@ func (rules OperatorRules) Get(class Class, token tokens.Token) OperatorFn {
 	return nil
 }
 
+// Returns the class an operator yields, or nil if it yields the class of the
+// left operand
+func (rules OperatorRules) Returns(class Class, token tokens.Token) Class {
+	obj := rules.returns.get(class)
+	if obj != nil {
+		return obj.(map[tokens.Token]Class)[token]
+	}
+	return nil
+}
+
 type ComparatorMap map[tokens.Token]OperatorFn
 type ComparatorRules struct {
 	values classMap
@@ -344,6 +647,25 @@ func ShouldOperate(token tokens.Token, left, right Class) error {
 	_, err := getOperatorFn(token, left, right)
 	return err
 }
+
+// Returns the class of the result of a binary expression between left and right
+func OperatorReturns(token tokens.Token, left, right Class) (Class, error) {
+	if err := ShouldOperate(token, left, right); err != nil {
+		return nil, err
+	}
+	if token.IsComparableOperator() {
+		return Boolean{}, nil
+	}
+	if nilableObject, ok := right.(NilableObject); ok {
+		right = nilableObject.ClassObject
+	}
+	if operable, ok := left.(OperableClass); ok {
+		if returns := operable.OperatorRules().Returns(right, token); returns != nil {
+			return returns, nil
+		}
+	}
+	return left, nil
+}
 func Operate(token tokens.Token, left, right ValueObject) (ValueObject, error) {
 	if nilableObject, ok := right.(NilableObject); ok {
 		if nilableObject.Object == nil {
@@ -0,0 +1,124 @@
+package build
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock represents where the current time comes from for anything evaluated in
+// a BuildContext
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is a Clock that uses the time of the host
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock that only moves when it's told to, so that anything
+// depending on the current time can be reproduced
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// lockedSource is a rand.Source that's safe to share between goroutines
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+func newRandom(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewSource(seed).(rand.Source64)})
+}
+
+// cryptoSource is a rand.Source that reads from the random source of the
+// operating system. It can't be seeded, and it's safe to share between
+// goroutines
+type cryptoSource struct{}
+
+func (cryptoSource) Int63() int64 {
+	return int64(cryptoSource{}.Uint64() & (1<<63 - 1))
+}
+func (cryptoSource) Uint64() uint64 {
+	var buf [8]byte
+	if _, err := crand.Read(buf[:]); err != nil {
+		panic(err)
+	}
+	return binary.LittleEndian.Uint64(buf[:])
+}
+func (cryptoSource) Seed(seed int64) {}
+
+// The random source of a build context that hasn't been seeded
+var cryptoRandom = rand.New(cryptoSource{})
+
+// Sets the clock that's used for anything that depends on the current time
+func (ctx *BuildContext) SetClock(clock Clock) {
+	ctx.clock = clock
+}
+
+// Returns the current time according to the clock of the build context. If
+// there isn't a build context the time of the host is used
+func (ctx *BuildContext) Now() time.Time {
+	if ctx == nil || ctx.clock == nil {
+		return time.Now()
+	}
+	return ctx.clock.Now()
+}
+
+// Seeds the random source of the build context so that anything depending on
+// randomness is reproducible. Until it's called, random values (including ids)
+// are taken from the operating system
+func (ctx *BuildContext) SetSeed(seed int64) {
+	ctx.random = newRandom(seed)
+}
+
+// Returns the random source every random value should be taken from
+func (ctx *BuildContext) Random() *rand.Rand {
+	if ctx == nil || ctx.random == nil {
+		return cryptoRandom
+	}
+	return ctx.random
+}
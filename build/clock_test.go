@@ -0,0 +1,16 @@
+package build
+
+import "testing"
+
+// CAN TAKE IDS FROM THE OPERATING SYSTEM UNLESS THE BUILD CONTEXT IS SEEDED
+func TestRandom(t *testing.T) {
+	if NewBuildContext().newEventID() == NewBuildContext().newEventID() {
+		t.Errorf("expected ids of build contexts that aren't seeded to differ")
+	}
+	a, b := NewBuildContext(), NewBuildContext()
+	a.SetSeed(1)
+	b.SetSeed(1)
+	if id := a.newEventID(); id != b.newEventID() {
+		t.Errorf("expected seeded build contexts to give the same ids, got %s", id)
+	}
+}
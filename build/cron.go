@@ -0,0 +1,159 @@
+package build
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expressions that can be used instead of the five fields of a cron expression
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSchedule is a parsed cron expression, which has the minutes, hours, days
+// of the month, months and days of the week it matches as bits
+// i.e. "*/15 9-17 * * 1-5" is every 15 minutes during work hours on weekdays
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// when both days of the month and days of the week are restricted a time
+	// matches if either of them do
+	domAny, dowAny bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func parseCron(expr string) (cronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return cronSchedule{}, fmt.Errorf("cron expression %q should have %d fields, got %d", expr, len(cronFields), len(parts))
+	}
+	bits := make([]uint64, len(parts))
+	for idx, part := range parts {
+		value, err := parseCronField(part, cronFields[idx])
+		if err != nil {
+			return cronSchedule{}, fmt.Errorf("cron expression %q: %s", expr, err.Error())
+		}
+		bits[idx] = value
+	}
+	// 7 is another way of writing sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	c := cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}
+	if c.next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return cronSchedule{}, fmt.Errorf("cron expression %q never matches", expr)
+	}
+	return c, nil
+}
+
+// Parses a field of a cron expression, which is a list of values, ranges or
+// wildcards that can each have a step
+func parseCronField(part string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(part, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepExpr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q for %s", stepExpr, field.name)
+			}
+			step = n
+		}
+		start, end := field.min, field.max
+		if rangeExpr != "*" {
+			lowExpr, highExpr, isRange := strings.Cut(rangeExpr, "-")
+			low, err := strconv.Atoi(lowExpr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s %q", field.name, lowExpr)
+			}
+			start, end = low, low
+			if isRange {
+				high, err := strconv.Atoi(highExpr)
+				if err != nil {
+					return 0, fmt.Errorf("invalid %s %q", field.name, highExpr)
+				}
+				end = high
+			} else if hasStep {
+				end = field.max
+			}
+		}
+		if start < field.min || end > field.max || start > end {
+			return 0, fmt.Errorf("%s %q is out of range %d-%d", field.name, rangeExpr, field.min, field.max)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func (c cronSchedule) matchesDay(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Returns the first time after a time that the expression matches, in the
+// location of the time. If it doesn't match within a few years it never does,
+// so the zero time is returned
+func (c cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// every combination of fields repeats within a few years, so anything past
+	// that never matches (i.e. february 30th)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
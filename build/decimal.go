@@ -0,0 +1,440 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/hntrl/lang/language/tokens"
+)
+
+// The minimum number of decimal places kept when dividing two decimals whose
+// quotient can't be represented exactly
+const DecimalDivisionScale = 16
+
+// The most digits a decimal can be parsed or raised to a power with, on either
+// side of the decimal point, so that a string like "1e2000000000" can't make a
+// number too large to work with
+const MaxDecimalDigits = 10000
+
+// RoundingMode represents how a decimal is rounded when digits are dropped
+type RoundingMode string
+
+const (
+	RoundHalfEven RoundingMode = "halfEven"
+	RoundHalfUp   RoundingMode = "halfUp"
+	RoundHalfDown RoundingMode = "halfDown"
+	RoundUp       RoundingMode = "up"
+	RoundDown     RoundingMode = "down"
+	RoundCeiling  RoundingMode = "ceiling"
+	RoundFloor    RoundingMode = "floor"
+)
+
+func ParseRoundingMode(str string) (RoundingMode, error) {
+	switch mode := RoundingMode(str); mode {
+	case RoundHalfEven, RoundHalfUp, RoundHalfDown, RoundUp, RoundDown, RoundCeiling, RoundFloor:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown rounding mode %q", str)
+}
+
+var bigTen = big.NewInt(10)
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// Divides num by den and rounds the result to an integer using mode
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	sign := num.Sign() * den.Sign()
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	cmp := half.Cmp(new(big.Int).Abs(den))
+
+	var increment bool
+	switch mode {
+	case RoundUp:
+		increment = true
+	case RoundDown:
+		increment = false
+	case RoundCeiling:
+		increment = sign > 0
+	case RoundFloor:
+		increment = sign < 0
+	case RoundHalfUp:
+		increment = cmp >= 0
+	case RoundHalfDown:
+		increment = cmp > 0
+	default:
+		increment = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+	}
+	if increment {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return q
+}
+
+type Decimal struct{}
+
+func (d Decimal) ClassName() string {
+	return "Decimal"
+}
+func (d Decimal) Constructors() ConstructorMap {
+	floatConstructor := func(obj ValueObject) (ValueObject, error) {
+		return ParseDecimal(strconv.FormatFloat(obj.Value().(float64), 'f', -1, 64))
+	}
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(Decimal{}, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddConstructor(Integer{}, func(obj ValueObject) (ValueObject, error) {
+		return NewDecimalFromInt(int64(obj.(IntegerLiteral))), nil
+	})
+	csMap.AddConstructor(Number{}, floatConstructor)
+	csMap.AddConstructor(Double{}, floatConstructor)
+	csMap.AddConstructor(Float{}, floatConstructor)
+	csMap.AddConstructor(String{}, func(obj ValueObject) (ValueObject, error) {
+		return ParseDecimal(string(obj.(StringLiteral)))
+	})
+	return csMap
+}
+
+// Decimals only operate with other decimals and integers since anything else
+// would bring float imprecision back into the result
+func decimalOperand(obj ValueObject) DecimalLiteral {
+	if integer, ok := obj.(IntegerLiteral); ok {
+		return NewDecimalFromInt(int64(integer))
+	}
+	return obj.(DecimalLiteral)
+}
+
+func decimalComparePredicate(cb func(cmp int) bool) OperatorFn {
+	return func(a, b ValueObject) (ValueObject, error) {
+		return BooleanLiteral(cb(decimalOperand(a).Cmp(decimalOperand(b)))), nil
+	}
+}
+func (d Decimal) ComparableRules() ComparatorRules {
+	rules := NewComparatorRules()
+	for _, class := range []Class{Decimal{}, Integer{}} {
+		rules.AddComparator(class, tokens.EQUALS, decimalComparePredicate(func(cmp int) bool {
+			return cmp == 0
+		}))
+		rules.AddComparator(class, tokens.NOT_EQUALS, decimalComparePredicate(func(cmp int) bool {
+			return cmp != 0
+		}))
+		rules.AddComparator(class, tokens.LESS, decimalComparePredicate(func(cmp int) bool {
+			return cmp < 0
+		}))
+		rules.AddComparator(class, tokens.GREATER, decimalComparePredicate(func(cmp int) bool {
+			return cmp > 0
+		}))
+		rules.AddComparator(class, tokens.LESS_EQUAL, decimalComparePredicate(func(cmp int) bool {
+			return cmp <= 0
+		}))
+		rules.AddComparator(class, tokens.GREATER_EQUAL, decimalComparePredicate(func(cmp int) bool {
+			return cmp >= 0
+		}))
+	}
+	return rules
+}
+
+func decimalOperatorPredicate(cb func(a, b DecimalLiteral) (DecimalLiteral, error)) OperatorFn {
+	return func(a, b ValueObject) (ValueObject, error) {
+		return cb(decimalOperand(a), decimalOperand(b))
+	}
+}
+func (d Decimal) OperatorRules() OperatorRules {
+	rules := NewOperatorRules()
+	for _, class := range []Class{Decimal{}, Integer{}} {
+		rules.AddOperator(class, tokens.ADD, decimalOperatorPredicate(func(a, b DecimalLiteral) (DecimalLiteral, error) {
+			return a.Add(b), nil
+		}))
+		rules.AddOperator(class, tokens.SUB, decimalOperatorPredicate(func(a, b DecimalLiteral) (DecimalLiteral, error) {
+			return a.Sub(b), nil
+		}))
+		rules.AddOperator(class, tokens.MUL, decimalOperatorPredicate(func(a, b DecimalLiteral) (DecimalLiteral, error) {
+			return a.Mul(b), nil
+		}))
+		rules.AddOperator(class, tokens.QUO, decimalOperatorPredicate(func(a, b DecimalLiteral) (DecimalLiteral, error) {
+			return a.Quo(b)
+		}))
+		rules.AddOperator(class, tokens.REM, decimalOperatorPredicate(func(a, b DecimalLiteral) (DecimalLiteral, error) {
+			return a.Rem(b)
+		}))
+	}
+	rules.AddOperator(Integer{}, tokens.PWR, func(a, b ValueObject) (ValueObject, error) {
+		return a.(DecimalLiteral).Pow(int64(b.(IntegerLiteral)))
+	})
+	return rules
+}
+
+func (d Decimal) Get(key string) Object {
+	// methods available on the instance so they can be validated without a value
+	return DecimalLiteral{}.Get(key)
+}
+
+// DecimalLiteral represents an exact decimal number as an unscaled integer
+// and the number of digits that come after the decimal point
+type DecimalLiteral struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+func NewDecimalFromInt(value int64) DecimalLiteral {
+	return DecimalLiteral{unscaled: big.NewInt(value)}
+}
+
+// Parses a decimal from its string representation, i.e. "-12.50" or "1.5e3"
+func ParseDecimal(str string) (DecimalLiteral, error) {
+	invalid := fmt.Errorf("cannot parse %q as Decimal", str)
+	mantissa, exponent := strings.TrimSpace(str), int64(0)
+	if idx := strings.IndexAny(mantissa, "eE"); idx != -1 {
+		exp, err := strconv.ParseInt(mantissa[idx+1:], 10, 32)
+		if err != nil {
+			return DecimalLiteral{}, invalid
+		}
+		if exp > MaxDecimalDigits || exp < -MaxDecimalDigits {
+			return DecimalLiteral{}, fmt.Errorf("cannot parse %q as Decimal: exponent is out of range", str)
+		}
+		mantissa, exponent = mantissa[:idx], exp
+	}
+	var scale int64
+	if idx := strings.IndexByte(mantissa, '.'); idx != -1 {
+		scale = int64(len(mantissa) - idx - 1)
+		mantissa = mantissa[:idx] + mantissa[idx+1:]
+	}
+	if mantissa == "" || mantissa == "-" || mantissa == "+" || strings.ContainsAny(mantissa[1:], "+-") {
+		return DecimalLiteral{}, invalid
+	}
+	unscaled, ok := new(big.Int).SetString(mantissa, 10)
+	if !ok {
+		return DecimalLiteral{}, invalid
+	}
+	scale -= exponent
+	if scale > MaxDecimalDigits {
+		return DecimalLiteral{}, fmt.Errorf("cannot parse %q as Decimal: more than %d digits after the decimal point", str, MaxDecimalDigits)
+	}
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(int32(-scale)))
+		scale = 0
+	}
+	return DecimalLiteral{unscaled: unscaled, scale: int32(scale)}, nil
+}
+
+func (dl DecimalLiteral) int() *big.Int {
+	if dl.unscaled == nil {
+		return new(big.Int)
+	}
+	return dl.unscaled
+}
+
+// Returns the decimal with the given number of digits after the decimal point,
+// rounding with mode if digits need to be dropped
+func (dl DecimalLiteral) Rescale(scale int32, mode RoundingMode) DecimalLiteral {
+	if scale < 0 {
+		scale = 0
+	}
+	switch {
+	case scale == dl.scale:
+		return DecimalLiteral{unscaled: dl.int(), scale: scale}
+	case scale > dl.scale:
+		return DecimalLiteral{unscaled: new(big.Int).Mul(dl.int(), pow10(scale-dl.scale)), scale: scale}
+	default:
+		return DecimalLiteral{unscaled: roundQuo(dl.int(), pow10(dl.scale-scale), mode), scale: scale}
+	}
+}
+
+// Returns the scale that both decimals can be represented in without losing
+// any digits
+func alignScale(a, b DecimalLiteral) int32 {
+	if a.scale > b.scale {
+		return a.scale
+	}
+	return b.scale
+}
+
+func (dl DecimalLiteral) Add(other DecimalLiteral) DecimalLiteral {
+	scale := alignScale(dl, other)
+	a, b := dl.Rescale(scale, RoundHalfEven), other.Rescale(scale, RoundHalfEven)
+	return DecimalLiteral{unscaled: new(big.Int).Add(a.unscaled, b.unscaled), scale: scale}
+}
+func (dl DecimalLiteral) Sub(other DecimalLiteral) DecimalLiteral {
+	return dl.Add(other.Neg())
+}
+func (dl DecimalLiteral) Mul(other DecimalLiteral) DecimalLiteral {
+	return DecimalLiteral{unscaled: new(big.Int).Mul(dl.int(), other.int()), scale: dl.scale + other.scale}
+}
+
+// Divides two decimals. If the quotient can't be represented exactly it is
+// rounded half-even to DecimalDivisionScale digits
+func (dl DecimalLiteral) Quo(other DecimalLiteral) (DecimalLiteral, error) {
+	if other.int().Sign() == 0 {
+		return DecimalLiteral{}, fmt.Errorf("division by zero")
+	}
+	minScale := alignScale(dl, other)
+	scale := minScale
+	if scale < DecimalDivisionScale {
+		scale = DecimalDivisionScale
+	}
+	num := new(big.Int).Mul(dl.int(), pow10(scale+other.scale-dl.scale))
+	result := DecimalLiteral{unscaled: roundQuo(num, other.int(), RoundHalfEven), scale: scale}
+	return result.trim(minScale), nil
+}
+func (dl DecimalLiteral) Rem(other DecimalLiteral) (DecimalLiteral, error) {
+	if other.int().Sign() == 0 {
+		return DecimalLiteral{}, fmt.Errorf("division by zero")
+	}
+	scale := alignScale(dl, other)
+	a, b := dl.Rescale(scale, RoundHalfEven), other.Rescale(scale, RoundHalfEven)
+	return DecimalLiteral{unscaled: new(big.Int).Rem(a.unscaled, b.unscaled), scale: scale}, nil
+}
+
+// Raises a decimal to an integer power. The result is refused if it would have
+// more than MaxDecimalDigits digits on either side of the decimal point
+func (dl DecimalLiteral) Pow(exp int64) (DecimalLiteral, error) {
+	if exp > MaxDecimalDigits || exp < -MaxDecimalDigits {
+		return DecimalLiteral{}, fmt.Errorf("exponent %d is out of range", exp)
+	}
+	// the base has at least as many digits as its bits less one make, which is
+	// enough to tell a result that's far too large before it's worked out
+	digits := int64(float64(dl.int().BitLen()-1)*math.Log10(2)) * exp
+	if scale := int64(dl.scale) * exp; digits > MaxDecimalDigits+scale || scale > MaxDecimalDigits {
+		return DecimalLiteral{}, fmt.Errorf("%s ** %d has too many digits", dl.String(), exp)
+	}
+	if exp < 0 {
+		denom, err := dl.Pow(-exp)
+		if err != nil {
+			return DecimalLiteral{}, err
+		}
+		return NewDecimalFromInt(1).Quo(denom)
+	}
+	return DecimalLiteral{
+		unscaled: new(big.Int).Exp(dl.int(), big.NewInt(exp), nil),
+		scale:    dl.scale * int32(exp),
+	}, nil
+}
+func (dl DecimalLiteral) Neg() DecimalLiteral {
+	return DecimalLiteral{unscaled: new(big.Int).Neg(dl.int()), scale: dl.scale}
+}
+func (dl DecimalLiteral) Abs() DecimalLiteral {
+	return DecimalLiteral{unscaled: new(big.Int).Abs(dl.int()), scale: dl.scale}
+}
+func (dl DecimalLiteral) Sign() int {
+	return dl.int().Sign()
+}
+func (dl DecimalLiteral) Scale() int32 {
+	return dl.scale
+}
+func (dl DecimalLiteral) Cmp(other DecimalLiteral) int {
+	scale := alignScale(dl, other)
+	return dl.Rescale(scale, RoundHalfEven).unscaled.Cmp(other.Rescale(scale, RoundHalfEven).unscaled)
+}
+
+// Drops trailing zeros until the decimal has minScale digits after the point
+func (dl DecimalLiteral) trim(minScale int32) DecimalLiteral {
+	unscaled, scale := new(big.Int).Set(dl.int()), dl.scale
+	rem := new(big.Int)
+	for scale > minScale {
+		quo, _ := new(big.Int).QuoRem(unscaled, bigTen, rem)
+		if rem.Sign() != 0 {
+			break
+		}
+		unscaled, scale = quo, scale-1
+	}
+	return DecimalLiteral{unscaled: unscaled, scale: scale}
+}
+
+func (dl DecimalLiteral) Float64() float64 {
+	f, _ := strconv.ParseFloat(dl.String(), 64)
+	return f
+}
+
+func (dl DecimalLiteral) Class() Class {
+	return Decimal{}
+}
+func (dl DecimalLiteral) Value() interface{} {
+	return dl.String()
+}
+func (dl DecimalLiteral) Set(key string, obj ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, dl.Class().ClassName())
+}
+func (dl DecimalLiteral) Get(key string) Object {
+	switch key {
+	case "scale":
+		return IntegerLiteral(dl.scale)
+	case "round":
+		return NewFunction(FunctionOptions{
+			Arguments: []Class{
+				Integer{},
+				String{},
+			},
+			Returns: Decimal{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				mode, err := ParseRoundingMode(string(args[1].(StringLiteral)))
+				if err != nil {
+					return nil, err
+				}
+				scale := int64(args[0].(IntegerLiteral))
+				if scale > MaxDecimalDigits {
+					return nil, fmt.Errorf("scale %d is out of range", scale)
+				}
+				return dl.Rescale(int32(max(scale, 0)), mode), nil
+			},
+		})
+	case "abs":
+		return NewFunction(FunctionOptions{
+			Returns: Decimal{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return dl.Abs(), nil
+			},
+		})
+	case "negate":
+		return NewFunction(FunctionOptions{
+			Returns: Decimal{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return dl.Neg(), nil
+			},
+		})
+	}
+	return nil
+}
+
+func (dl DecimalLiteral) String() string {
+	digits := new(big.Int).Abs(dl.int()).String()
+	if dl.scale > 0 {
+		if pad := int(dl.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		point := len(digits) - int(dl.scale)
+		digits = digits[:point] + "." + digits[point:]
+	}
+	if dl.int().Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Decimals are written as JSON strings so they don't lose precision when read
+// back as a float
+func (dl DecimalLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dl.String())
+}
+func (dl *DecimalLiteral) UnmarshalJSON(data []byte) error {
+	str := string(data)
+	if unquoted, err := strconv.Unquote(str); err == nil {
+		str = unquoted
+	}
+	parsed, err := ParseDecimal(str)
+	if err != nil {
+		return err
+	}
+	*dl = parsed
+	return nil
+}
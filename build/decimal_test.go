@@ -0,0 +1,51 @@
+package build
+
+import "testing"
+
+// CAN PARSE DECIMALS WITH AN EXPONENT
+func TestParseDecimal(t *testing.T) {
+	cases := map[string]string{
+		"-12.50": "-12.50",
+		"1.5e3":  "1500",
+		"25e-2":  "0.25",
+	}
+	for str, expected := range cases {
+		dl, err := ParseDecimal(str)
+		if err != nil {
+			t.Errorf("%s: %s", str, err)
+		} else if dl.String() != expected {
+			t.Errorf("%s: expected %s, got %s", str, expected, dl.String())
+		}
+	}
+}
+
+// CANNOT PARSE DECIMALS WITH AN EXPONENT OR SCALE THAT'S OUT OF RANGE
+func TestParseDecimalOutOfRange(t *testing.T) {
+	for _, str := range []string{"1e2000000000", "1e-2000000000", "1e10001", "1e-10001", "1e99999999999"} {
+		if _, err := ParseDecimal(str); err == nil {
+			t.Errorf("%s: expected an error", str)
+		}
+	}
+}
+
+// CANNOT RAISE A DECIMAL TO A POWER WITH TOO MANY DIGITS
+func TestDecimalPowOutOfRange(t *testing.T) {
+	two := NewDecimalFromInt(2)
+	if dl, err := two.Pow(10); err != nil || dl.String() != "1024" {
+		t.Errorf("expected 1024, got %s (%v)", dl.String(), err)
+	}
+	tenth, _ := ParseDecimal("0.1")
+	for _, c := range []struct {
+		base DecimalLiteral
+		exp  int64
+	}{
+		{two, 1 << 40},
+		{two, -1 << 62},
+		{NewDecimalFromInt(1000000), 5000},
+		{tenth, 10001},
+	} {
+		if _, err := c.base.Pow(c.exp); err == nil {
+			t.Errorf("%s ** %d: expected an error", c.base.String(), c.exp)
+		}
+	}
+}
@@ -0,0 +1,515 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/resource"
+)
+
+// The key of the resource.StorageDriver entities are stored with
+const StorageResource = "storage"
+
+// The methods every entity instance has, which fields can't be named after
+var entityInstanceMethods = []string{"save", "delete", "reload"}
+
+// Entity represents a type whose instances are persisted and identified by
+// their first field
+// i.e. entity Product { product_id String  name String }
+type Entity struct {
+	Name     string
+	Private  bool
+	Comment  string
+	Identity string
+	// Whether the entity is a projection, which is only changed by its handlers
+	// as events are stored
+	Projection bool
+	fields     map[string]Class
+	// the collection the entity is stored in, which is qualified by the name of
+	// the context so entities with the same name don't collide
+	collection string
+	store      *entityStore
+	projection *projectionState
+}
+
+type entityStore struct {
+	driver resource.StorageDriver
+}
+
+func (e Entity) ClassName() string {
+	return e.Name
+}
+func (e Entity) Fields() map[string]Class {
+	return e.fields
+}
+func (e Entity) Constructors() ConstructorMap {
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(e, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddGenericConstructor(e, func(data map[string]ValueObject) (ValueObject, error) {
+		obj := EntityObject{e, data}
+		for key, class := range e.fields {
+			if nilableClass, ok := class.(NilableObject); ok {
+				if _, isNilable := data[key].(NilableObject); !isNilable {
+					nilableClass.Object = data[key]
+					obj.fields[key] = nilableClass
+				}
+			}
+		}
+		return obj, nil
+	})
+	return csMap
+}
+
+// Returns the driver the entity is stored with, which changes are made through
+// the unit of work of the call site if there is one
+func (e Entity) driver(site CallSite) (resource.StorageDriver, error) {
+	if e.store == nil || e.store.driver == nil {
+		return nil, fmt.Errorf("entity %s is not attached to storage", e.Name)
+	}
+	if site.Work != nil {
+		return site.Work.Storage(e.store.driver), nil
+	}
+	return e.store.driver, nil
+}
+
+// Returns an error if the call site isn't allowed to change instances
+func (e Entity) writable(site CallSite) error {
+	if e.Projection && site.Projection != e.collection {
+		return fmt.Errorf("%s is a projection, so only its handlers can change it", e.Name)
+	}
+	return nil
+}
+
+// Returns the key an instance is stored with, which is its identity as JSON
+func (e Entity) key(identity ValueObject) (string, error) {
+	bytes, err := json.Marshal(ToInterface(identity))
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// Returns the document an instance is stored as
+func (e Entity) document(obj ValueObject) (resource.Document, error) {
+	bytes, err := json.Marshal(ToInterface(obj))
+	if err != nil {
+		return nil, err
+	}
+	var doc resource.Document
+	if err := json.Unmarshal(bytes, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Returns the instance a stored document represents
+func (e Entity) fromDocument(doc resource.Document) (EntityObject, error) {
+	obj, err := FromInterfaceAs(map[string]interface{}(doc), e)
+	if err != nil {
+		return EntityObject{}, fmt.Errorf("cannot read %s: %s", e.Name, err.Error())
+	}
+	return obj.(EntityObject), nil
+}
+
+// Checks that every property of a filter is a field of the entity that the
+// property can be converted to
+func (e Entity) validateFilter(class Class) error {
+	objectClass, ok := class.(ObjectClass)
+	if !ok || objectClass.Fields() == nil {
+		return fmt.Errorf("expected filter object, got %s", class.ClassName())
+	}
+	for key, propClass := range objectClass.Fields() {
+		fieldClass, ok := e.fields[key]
+		if !ok {
+			return fmt.Errorf("%s has no field %s", e.Name, key)
+		}
+		if err := ShouldConvert(fieldClass, propClass); err != nil {
+			return fmt.Errorf("%s: %s", key, err.Error())
+		}
+	}
+	return nil
+}
+
+// Returns the document that stored instances are matched against
+func (e Entity) filter(obj ValueObject) (resource.Document, error) {
+	doc := make(resource.Document)
+	for key := range obj.Class().(ObjectClass).Fields() {
+		prop, ok := obj.Get(key).(ValueObject)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected value", key)
+		}
+		value, err := Convert(e.fields[key], prop)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err.Error())
+		}
+		doc[key] = ToInterface(value)
+	}
+	return doc, nil
+}
+
+func (e Entity) find(site CallSite, filter resource.Document) (Iterable, error) {
+	driver, err := e.driver(site)
+	if err != nil {
+		return Iterable{}, err
+	}
+	records, err := driver.Find(e.collection, filter)
+	if err != nil {
+		return Iterable{}, err
+	}
+	out := NewIterable(e, 0)
+	for _, record := range records {
+		obj, err := e.fromDocument(record.Document)
+		if err != nil {
+			return Iterable{}, err
+		}
+		out.Items = append(out.Items, obj)
+	}
+	return out, nil
+}
+
+func (e Entity) Get(key string) Object {
+	methods := map[string]Object{
+		// stores a new instance, failing if its identity is already used
+		"create": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				e,
+			},
+			Returns: e,
+			Mutates: true,
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				if err := e.writable(site); err != nil {
+					return nil, err
+				}
+				driver, err := e.driver(site)
+				if err != nil {
+					return nil, err
+				}
+				obj := args[0].(EntityObject)
+				key, err := e.key(obj.Identity())
+				if err != nil {
+					return nil, err
+				}
+				doc, err := e.document(obj)
+				if err != nil {
+					return nil, err
+				}
+				if err := driver.Insert(e.collection, key, doc); err != nil {
+					if errors.Is(err, resource.ErrExists) {
+						return nil, fmt.Errorf("%s %s already exists", e.Name, key)
+					}
+					return nil, err
+				}
+				return obj, nil
+			},
+		}),
+		// returns the first instance that matches the filter, failing if there
+		// isn't one
+		"findOne": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("expected 1 argument, got %d", len(args))
+				}
+				if err := e.validateFilter(args[0]); err != nil {
+					return nil, err
+				}
+				return e, nil
+			},
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				filter, err := e.filter(args[0])
+				if err != nil {
+					return nil, err
+				}
+				// looking up by identity alone doesn't need a scan
+				if identity, ok := filter[e.Identity]; ok && len(filter) == 1 {
+					driver, err := e.driver(site)
+					if err != nil {
+						return nil, err
+					}
+					key, err := json.Marshal(identity)
+					if err != nil {
+						return nil, err
+					}
+					doc, err := driver.Get(e.collection, string(key))
+					if errors.Is(err, resource.ErrNotFound) {
+						return nil, fmt.Errorf("%s %s not found", e.Name, string(key))
+					} else if err != nil {
+						return nil, err
+					}
+					return e.fromDocument(doc)
+				}
+				found, err := e.find(site, filter)
+				if err != nil {
+					return nil, err
+				}
+				if len(found.Items) == 0 {
+					bytes, _ := json.Marshal(filter)
+					return nil, fmt.Errorf("no %s matches %s", e.Name, string(bytes))
+				}
+				return found.Items[0], nil
+			},
+		}),
+		"findMany": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("expected 1 argument, got %d", len(args))
+				}
+				if err := e.validateFilter(args[0]); err != nil {
+					return nil, err
+				}
+				return NewIterable(e, 0), nil
+			},
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				filter, err := e.filter(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return e.find(site, filter)
+			},
+		}),
+		// returns every instance
+		"query": NewFunction(FunctionOptions{
+			Returns: NewIterable(e, 0),
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return e.find(site, resource.Document{})
+			},
+		}),
+		// returns how many instances there are, or how many match a filter
+		"count": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if len(args) > 1 {
+					return nil, fmt.Errorf("expected at most 1 argument, got %d", len(args))
+				}
+				if len(args) == 1 {
+					if err := e.validateFilter(args[0]); err != nil {
+						return nil, err
+					}
+				}
+				return Integer{}, nil
+			},
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				driver, err := e.driver(site)
+				if err != nil {
+					return nil, err
+				}
+				filter := resource.Document{}
+				if len(args) == 1 {
+					filter, err = e.filter(args[0])
+					if err != nil {
+						return nil, err
+					}
+				}
+				count, err := driver.Count(e.collection, filter)
+				if err != nil {
+					return nil, err
+				}
+				return IntegerLiteral(count), nil
+			},
+		}),
+	}
+	if method, ok := methods[key]; ok {
+		return method
+	}
+	return nil
+}
+
+// Returns the methods available on an instance so they can be validated
+// without a value
+func (e Entity) InstanceGet(key string) Object {
+	return EntityObject{ParentEntity: e}.Get(key)
+}
+
+func (e Entity) ObjectClassFromNode(ctx *Context, node nodes.ContextObject) (Class, error) {
+	e.Name = node.Name
+	e.Private = node.Private
+	e.Comment = node.Comment
+	e.collection = ctx.Name + "." + node.Name
+	e.store = &entityStore{}
+
+	e.fields = make(map[string]Class)
+
+	if node.Extends != nil {
+		extendsType := nodes.TypeExpression{IsArray: false, IsOptional: false, Selector: *node.Extends}
+		class, err := ctx.EvaluateTypeExpression(extendsType)
+		if err != nil {
+			return nil, err
+		}
+		if objectClass, ok := class.(ObjectClass); ok {
+			if fields := objectClass.Fields(); fields != nil {
+				for k, v := range fields {
+					e.fields[k] = v
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("cannot extend %s", class.ClassName())
+		}
+	}
+	for _, item := range node.Fields {
+		if typeExpr, ok := item.Init.(nodes.TypeStatement); ok {
+			for _, method := range entityInstanceMethods {
+				if typeExpr.Name == method {
+					return nil, NodeError(item, "%s cannot have a field named %s", node.Name, method)
+				}
+			}
+			if typeExpr.Default != nil {
+				return nil, NodeError(item, "%s cannot have a default for %s", node.Name, typeExpr.Name)
+			}
+			obj, err := ctx.EvaluateTypeExpression(typeExpr.Init)
+			if err != nil {
+				return nil, err
+			}
+			if e.Identity == "" {
+				if _, ok := obj.(NilableObject); ok {
+					return nil, NodeError(item, "identity %s of %s cannot be optional", typeExpr.Name, node.Name)
+				}
+				e.Identity = typeExpr.Name
+			}
+			e.fields[typeExpr.Name] = obj
+		} else {
+			return nil, fmt.Errorf("expected type statement")
+		}
+	}
+	if e.Identity == "" {
+		return nil, NodeError(node, "%s needs a field to identify it by", node.Name)
+	}
+	if e.Projection {
+		e.projection = &projectionState{ctx: ctx, handlers: make(map[string]projectionHandler)}
+	}
+
+	return e, nil
+}
+
+// Connects the entity to the storage driver of the build context
+func (e Entity) Attach(ctx Context) error {
+	var driver resource.StorageDriver
+	if err := ctx.Resource(StorageResource, &driver); err != nil {
+		return fmt.Errorf("entity %s: %s", e.Name, err.Error())
+	}
+	e.store.driver = driver
+	if e.Projection {
+		return e.attachProjection(ctx)
+	}
+	return nil
+}
+func (e Entity) Detach() error {
+	e.store.driver = nil
+	if e.Projection {
+		return e.detachProjection()
+	}
+	return nil
+}
+
+// EntityObject represents an instance of an Entity
+type EntityObject struct {
+	ParentEntity Entity
+	fields       map[string]ValueObject
+}
+
+// Returns the value of the field the instance is identified by
+func (eo EntityObject) Identity() ValueObject {
+	return eo.fields[eo.ParentEntity.Identity]
+}
+
+func (eo EntityObject) Class() Class {
+	return eo.ParentEntity
+}
+func (eo EntityObject) Value() interface{} {
+	out := make(map[string]interface{})
+	for key, obj := range eo.fields {
+		out[key] = obj.Value()
+	}
+	return out
+}
+func (eo EntityObject) Set(key string, obj ValueObject) error {
+	eo.fields[key] = obj
+	return nil
+}
+func (eo EntityObject) Get(key string) Object {
+	switch key {
+	// replaces the stored instance with this one
+	case "save":
+		return NewFunction(FunctionOptions{
+			Mutates: true,
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				e := eo.ParentEntity
+				if err := e.writable(site); err != nil {
+					return nil, err
+				}
+				driver, err := e.driver(site)
+				if err != nil {
+					return nil, err
+				}
+				key, err := e.key(eo.Identity())
+				if err != nil {
+					return nil, err
+				}
+				doc, err := e.document(eo)
+				if err != nil {
+					return nil, err
+				}
+				return nil, driver.Put(e.collection, key, doc)
+			},
+		})
+	case "delete":
+		return NewFunction(FunctionOptions{
+			Mutates: true,
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				e := eo.ParentEntity
+				if err := e.writable(site); err != nil {
+					return nil, err
+				}
+				driver, err := e.driver(site)
+				if err != nil {
+					return nil, err
+				}
+				key, err := e.key(eo.Identity())
+				if err != nil {
+					return nil, err
+				}
+				if err := driver.Delete(e.collection, key); err != nil {
+					if errors.Is(err, resource.ErrNotFound) {
+						return nil, fmt.Errorf("%s %s not found", e.Name, key)
+					}
+					return nil, err
+				}
+				return nil, nil
+			},
+		})
+	// replaces the fields of the instance with the ones that are stored
+	case "reload":
+		return NewFunction(FunctionOptions{
+			SiteHandler: func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+				e := eo.ParentEntity
+				driver, err := e.driver(site)
+				if err != nil {
+					return nil, err
+				}
+				key, err := e.key(eo.Identity())
+				if err != nil {
+					return nil, err
+				}
+				doc, err := driver.Get(e.collection, key)
+				if errors.Is(err, resource.ErrNotFound) {
+					return nil, fmt.Errorf("%s %s not found", e.Name, key)
+				} else if err != nil {
+					return nil, err
+				}
+				stored, err := e.fromDocument(doc)
+				if err != nil {
+					return nil, err
+				}
+				for key := range eo.fields {
+					delete(eo.fields, key)
+				}
+				for key, value := range stored.fields {
+					eo.fields[key] = value
+				}
+				return nil, nil
+			},
+		})
+	}
+	return eo.fields[key]
+}
@@ -0,0 +1,105 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hntrl/lang/resource"
+)
+
+const catalogSource = `context acme.catalog {
+	entity Product {
+		sku String
+		name String
+		price Int
+	}
+
+	command Add(sku: String, name: String, price: Int) {
+		Product.create(Product{ sku: sku, name: name, price: price })
+	}
+	command AddPair(sku: String, other: String) {
+		Product.create(Product{ sku: sku, name: sku, price: 1 })
+		Product.create(Product{ sku: other, name: other, price: 1 })
+	}
+	command Reprice(sku: String, price: Int) {
+		product := Product.findOne({ sku: sku })
+		product.price = price
+		product.save()
+	}
+	command Remove(sku: String) {
+		Product.findOne({ sku: sku }).delete()
+	}
+	query Price(sku: String) Int {
+		return Product.findOne({ sku: sku }).price
+	}
+	query PricedAt(price: Int) Int {
+		return len(Product.findMany({ price: price }))
+	}
+	query Count() Int {
+		return Product.count()
+	}
+}`
+
+// CAN CREATE, FIND, SAVE AND DELETE INSTANCES OF AN ENTITY
+func TestEntity(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, catalogSource)
+
+	invoke(t, ctx, "Add", `{"sku": "a", "name": "Apple", "price": 3}`)
+	invoke(t, ctx, "Add", `{"sku": "b", "name": "Banana", "price": 3}`)
+	if out := invoke(t, ctx, "Count", `{}`); out != "2" {
+		t.Errorf("expected 2, got %s", out)
+	}
+	if out := invoke(t, ctx, "PricedAt", `{"price": 3}`); out != "2" {
+		t.Errorf("expected 2 products at 3, got %s", out)
+	}
+	invoke(t, ctx, "Reprice", `{"sku": "a", "price": 5}`)
+	if out := invoke(t, ctx, "Price", `{"sku": "a"}`); out != "5" {
+		t.Errorf("expected 5, got %s", out)
+	}
+	invoke(t, ctx, "Remove", `{"sku": "b"}`)
+	if out := invoke(t, ctx, "Count", `{}`); out != "1" {
+		t.Errorf("expected 1, got %s", out)
+	}
+
+	// instances are stored by their identity
+	doc, err := buildCtx.resources[StorageResource].(resource.StorageDriver).Get("acme.catalog.Product", `"a"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["name"] != "Apple" {
+		t.Errorf("expected the stored instance, got %v", doc)
+	}
+}
+
+// CANNOT CREATE AN INSTANCE WHOSE IDENTITY IS ALREADY USED OR FIND ONE THAT
+// DOESN'T EXIST
+func TestEntityErrors(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, catalogSource)
+
+	invoke(t, ctx, "Add", `{"sku": "a", "name": "Apple", "price": 3}`)
+	if _, err := ctx.Invoke("Add", []byte(`{"sku": "a", "name": "Apple", "price": 3}`)); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected the identity to be used, got %v", err)
+	}
+	if _, err := ctx.Invoke("Price", []byte(`{"sku": "z"}`)); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected the instance not to be found, got %v", err)
+	}
+	if _, err := ctx.Invoke("Remove", []byte(`{"sku": "z"}`)); err == nil {
+		t.Errorf("expected an error deleting an instance that doesn't exist")
+	}
+}
+
+// CAN FORGET EVERY CHANGE A COMMAND MADE WHEN IT FAILS
+func TestEntityUnitOfWork(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, catalogSource)
+
+	invoke(t, ctx, "Add", `{"sku": "b", "name": "Banana", "price": 3}`)
+	if _, err := ctx.Invoke("AddPair", []byte(`{"sku": "a", "other": "b"}`)); err == nil {
+		t.Fatalf("expected the second instance to exist")
+	}
+	if out := invoke(t, ctx, "Count", `{}`); out != "1" {
+		t.Errorf("expected the first instance to be forgotten, got %s", out)
+	}
+}
@@ -0,0 +1,117 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/language/tokens"
+)
+
+// Enum represents a class whose values can only be one of a fixed set of
+// members, each with the string it's represented as
+// i.e. enum Status { active "ACTIVE" }
+type Enum struct {
+	Name    string
+	Private bool
+	Comment string
+	members map[string]string
+}
+
+func (e Enum) ClassName() string {
+	return e.Name
+}
+func (e Enum) Constructors() ConstructorMap {
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(e, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddConstructor(String{}, func(obj ValueObject) (ValueObject, error) {
+		return e.Parse(string(obj.(StringLiteral)))
+	})
+	return csMap
+}
+
+func enumComparePredicate(cb func(a, b EnumLiteral) bool) OperatorFn {
+	return func(a, b ValueObject) (ValueObject, error) {
+		return BooleanLiteral(cb(a.(EnumLiteral), b.(EnumLiteral))), nil
+	}
+}
+func (e Enum) ComparableRules() ComparatorRules {
+	rules := NewComparatorRules()
+	rules.AddComparator(e, tokens.EQUALS, enumComparePredicate(func(a, b EnumLiteral) bool {
+		return a.Member == b.Member
+	}))
+	rules.AddComparator(e, tokens.NOT_EQUALS, enumComparePredicate(func(a, b EnumLiteral) bool {
+		return a.Member != b.Member
+	}))
+	return rules
+}
+
+func (e Enum) Get(key string) Object {
+	if _, ok := e.members[key]; ok {
+		return EnumLiteral{ParentEnum: e, Member: key}
+	}
+	return EnumLiteral{ParentEnum: e}.Get(key)
+}
+
+// Returns the member of the enum that's represented by the string
+func (e Enum) Parse(value string) (EnumLiteral, error) {
+	for member, str := range e.members {
+		if str == value {
+			return EnumLiteral{ParentEnum: e, Member: member}, nil
+		}
+	}
+	values := make([]string, 0, len(e.members))
+	for _, str := range e.members {
+		values = append(values, fmt.Sprintf("%q", str))
+	}
+	sort.Strings(values)
+	return EnumLiteral{}, fmt.Errorf("%q is not a member of %s, expected one of %v", value, e.Name, values)
+}
+
+func (e Enum) ObjectClassFromNode(ctx *Context, node nodes.ContextObject) (Class, error) {
+	e.Name = node.Name
+	e.Private = node.Private
+	e.Comment = node.Comment
+	e.members = make(map[string]string)
+
+	for _, item := range node.Fields {
+		if enumExpr, ok := item.Init.(nodes.EnumStatement); ok {
+			e.members[enumExpr.Name] = enumExpr.Init
+		} else {
+			return nil, fmt.Errorf("expected enum statement")
+		}
+	}
+	return e, nil
+}
+
+// EnumLiteral represents a single member of an Enum
+type EnumLiteral struct {
+	ParentEnum Enum
+	Member     string
+}
+
+func (el EnumLiteral) Class() Class {
+	return el.ParentEnum
+}
+func (el EnumLiteral) Value() interface{} {
+	return el.ParentEnum.members[el.Member]
+}
+func (el EnumLiteral) Set(key string, obj ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, el.ParentEnum.Name)
+}
+func (el EnumLiteral) Get(key string) Object {
+	switch key {
+	case "name":
+		return StringLiteral(el.Member)
+	case "value":
+		return StringLiteral(el.ParentEnum.members[el.Member])
+	}
+	return nil
+}
+
+func (el EnumLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(el.ParentEnum.members[el.Member])
+}
@@ -123,3 +123,7 @@ func InvalidValueExpressionError(node nodes.Node) ParserError {
 func CannotConstructError(className string, from string) error {
 	return fmt.Errorf("cannot construct %s from %s", className, from)
 }
+
+func NarrowingError(className string, from string) error {
+	return fmt.Errorf("cannot implicitly convert %s to %s", from, className)
+}
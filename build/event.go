@@ -0,0 +1,221 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/resource"
+)
+
+// Event represents something that happened in a context that other contexts
+// can react to
+// i.e. event ProductDeleted { productId String }
+type Event struct {
+	Name    string
+	Private bool
+	Comment string
+	fields  map[string]Class
+	// the subject the event is published to, which is qualified by the name of
+	// the context so events with the same name don't collide
+	subject string
+}
+
+func (ev Event) ClassName() string {
+	return ev.Name
+}
+func (ev Event) Fields() map[string]Class {
+	return ev.fields
+}
+func (ev Event) Constructors() ConstructorMap {
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(ev, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddGenericConstructor(ev, func(data map[string]ValueObject) (ValueObject, error) {
+		obj := EventObject{ParentEvent: ev, fields: data}
+		for key, class := range ev.fields {
+			if nilableClass, ok := class.(NilableObject); ok {
+				if _, isNilable := data[key].(NilableObject); !isNilable {
+					nilableClass.Object = data[key]
+					obj.fields[key] = nilableClass
+				}
+			}
+		}
+		return obj, nil
+	})
+	return csMap
+}
+func (ev Event) Get(key string) Object {
+	return nil
+}
+
+// The member an event that's been delivered has with the ID of the message it
+// was delivered in, unless the event has a field of the same name
+const eventIDMember = "eventId"
+
+func (ev Event) InstanceGet(key string) Object {
+	if _, ok := ev.fields[key]; key == eventIDMember && !ok {
+		return String{}
+	}
+	return nil
+}
+
+// Returns the subject the event is published to
+func (ev Event) Subject() string {
+	return ev.subject
+}
+
+func (ev Event) ObjectClassFromNode(ctx *Context, node nodes.ContextObject) (Class, error) {
+	ev.Name = node.Name
+	ev.Private = node.Private
+	ev.Comment = node.Comment
+	ev.subject = ctx.Name + "." + node.Name
+
+	ev.fields = make(map[string]Class)
+
+	if node.Extends != nil {
+		extendsType := nodes.TypeExpression{IsArray: false, IsOptional: false, Selector: *node.Extends}
+		class, err := ctx.EvaluateTypeExpression(extendsType)
+		if err != nil {
+			return nil, err
+		}
+		if objectClass, ok := class.(ObjectClass); ok {
+			if fields := objectClass.Fields(); fields != nil {
+				for k, v := range fields {
+					ev.fields[k] = v
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("cannot extend %s", class.ClassName())
+		}
+	}
+	for _, item := range node.Fields {
+		if typeExpr, ok := item.Init.(nodes.TypeStatement); ok {
+			if typeExpr.Default != nil {
+				return nil, NodeError(item, "%s cannot have a default for %s", node.Name, typeExpr.Name)
+			}
+			obj, err := ctx.EvaluateTypeExpression(typeExpr.Init)
+			if err != nil {
+				return nil, err
+			}
+			ev.fields[typeExpr.Name] = obj
+		} else {
+			return nil, fmt.Errorf("expected type statement")
+		}
+	}
+
+	return ev, nil
+}
+
+// EventObject represents an instance of an Event
+type EventObject struct {
+	ParentEvent Event
+	fields      map[string]ValueObject
+	// the ID of the message the event was delivered in, so that handlers can
+	// tell when it's been delivered more than once
+	id string
+}
+
+func (eo EventObject) Class() Class {
+	return eo.ParentEvent
+}
+func (eo EventObject) Value() interface{} {
+	out := make(map[string]interface{})
+	for key, obj := range eo.fields {
+		out[key] = obj.Value()
+	}
+	return out
+}
+func (eo EventObject) Set(key string, obj ValueObject) error {
+	eo.fields[key] = obj
+	return nil
+}
+func (eo EventObject) Get(key string) Object {
+	if _, ok := eo.fields[key]; key == eventIDMember && !ok {
+		return StringLiteral(eo.id)
+	}
+	return eo.fields[key]
+}
+
+// EventMessage is what's published to an event bus when an event is emitted
+type EventMessage struct {
+	// Identifies the event so that a consumer can tell when it's been delivered
+	// more than once
+	ID    string          `json:"id"`
+	Event string          `json:"event"`
+	Time  time.Time       `json:"time"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Returns a random version 4 UUID from the random source of the build context
+func (ctx *BuildContext) newEventID() string {
+	var id [16]byte
+	random := ctx.Random()
+	for idx := 0; idx < len(id); idx += 8 {
+		value := random.Uint64()
+		for offset := 0; offset < 8; offset++ {
+			id[idx+offset] = byte(value >> (8 * offset))
+		}
+	}
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// Returns the message an event is published as
+func (ctx *BuildContext) eventMessage(obj EventObject) (resource.Message, error) {
+	data, err := json.Marshal(ToInterface(obj))
+	if err != nil {
+		return resource.Message{}, err
+	}
+	ev := obj.ParentEvent
+	msg, err := json.Marshal(EventMessage{
+		ID:    ctx.newEventID(),
+		Event: ev.subject,
+		Time:  ctx.Now(),
+		Data:  data,
+	})
+	if err != nil {
+		return resource.Message{}, err
+	}
+	return resource.Message{Subject: ev.subject, Data: msg}, nil
+}
+
+// Sets the bus that emitted events are published to
+func (ctx *BuildContext) SetEventBus(bus resource.EventBus) {
+	ctx.bus = bus
+}
+
+// Returns the bus that emitted events are published to
+func (ctx *BuildContext) EventBus() resource.EventBus {
+	return ctx.bus
+}
+
+// Publishes an event to the event bus of the build context
+func (ctx *BuildContext) Emit(obj EventObject) error {
+	msg, err := ctx.eventMessage(obj)
+	if err != nil {
+		return err
+	}
+	return ctx.bus.Publish(msg)
+}
+
+// Returns the event that a message published to an event bus carries
+func (ev Event) Decode(msg resource.Message) (EventObject, EventMessage, error) {
+	var envelope EventMessage
+	if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+		return EventObject{}, EventMessage{}, fmt.Errorf("cannot read %s: %s", ev.Name, err.Error())
+	}
+	if envelope.Event != ev.subject {
+		return EventObject{}, EventMessage{}, fmt.Errorf("expected %s, got %s", ev.subject, envelope.Event)
+	}
+	obj, err := FromBytes(envelope.Data, ev)
+	if err != nil {
+		return EventObject{}, EventMessage{}, fmt.Errorf("cannot read %s: %s", ev.Name, err.Error())
+	}
+	event := obj.(EventObject)
+	event.id = envelope.ID
+	return event, envelope, nil
+}
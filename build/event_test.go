@@ -0,0 +1,82 @@
+package build
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hntrl/lang/resource"
+)
+
+const shopSource = `context acme.shop {
+	event ProductDeleted {
+		product_id String
+	}
+
+	entity Product {
+		product_id String
+	}
+
+	command Add(product_id: String) {
+		Product.create(Product{ product_id: product_id })
+	}
+	command Delete(product_id: String) {
+		emit(ProductDeleted{ product_id: product_id })
+		Product.findOne({ product_id: product_id }).delete()
+	}
+}`
+
+// Returns the messages published to a subject
+func published(t *testing.T, buildCtx *BuildContext, subject string) *[]EventMessage {
+	t.Helper()
+	msgs := []EventMessage{}
+	_, err := buildCtx.EventBus().Subscribe(subject, "", func(msg resource.Message) error {
+		var event EventMessage
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			return err
+		}
+		msgs = append(msgs, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &msgs
+}
+
+// CAN PUBLISH AN EVENT A COMMAND EMITS ONCE ITS CHANGES ARE KEPT
+func TestEmit(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, shopSource)
+	msgs := published(t, buildCtx, "acme.shop.ProductDeleted")
+
+	invoke(t, ctx, "Add", `{"product_id": "p1"}`)
+	invoke(t, ctx, "Delete", `{"product_id": "p1"}`)
+	if len(*msgs) != 1 {
+		t.Fatalf("expected one event, got %+v", *msgs)
+	}
+	msg := (*msgs)[0]
+	if msg.Event != "acme.shop.ProductDeleted" || !msg.Time.Equal(testEpoch) || msg.ID == "" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if string(msg.Data) != `{"product_id":"p1"}` {
+		t.Errorf("expected the fields of the event, got %s", msg.Data)
+	}
+}
+
+// CANNOT PUBLISH AN EVENT A COMMAND EMITS WHEN THE COMMAND FAILS
+func TestEmitInFailedCommand(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, shopSource)
+	msgs := published(t, buildCtx, "acme.shop.ProductDeleted")
+
+	if _, err := ctx.Invoke("Delete", []byte(`{"product_id": "p1"}`)); err == nil {
+		t.Fatalf("expected the product not to be found")
+	}
+	if len(*msgs) != 0 {
+		t.Errorf("expected no events, got %+v", *msgs)
+	}
+	count, err := buildCtx.resources[StorageResource].(resource.StorageDriver).Count(OutboxCollection, resource.Document{})
+	if err != nil || count != 0 {
+		t.Errorf("expected nothing to be left in the outbox, got %d (%v)", count, err)
+	}
+}
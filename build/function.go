@@ -2,24 +2,40 @@ package build
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/hntrl/lang/language/nodes"
 	"github.com/hntrl/lang/language/tokens"
 )
 
 type fnHandler func(args []ValueObject, proto ValueObject) (ValueObject, error)
+type fnSiteHandler func(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error)
 
 // Function represents a subroutine that can be executed in an expression
 type Function struct {
 	arguments []Class
 	returns   Class
 	handler   fnHandler
+	// used instead of handler when the function needs to know where it's called
+	// from
+	siteHandler fnSiteHandler
+	mutates     bool
 }
 
 func (f Function) Get(key string) Object {
 	return nil
 }
 
+func (fn Function) Class() Class {
+	return FunctionType{Arguments: fn.arguments, Returns: fn.returns}
+}
+func (fn Function) Value() interface{} {
+	return nil
+}
+func (fn Function) Set(key string, obj ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, fn.Class().ClassName())
+}
+
 func (fn Function) Arguments() []Class {
 	return fn.arguments
 }
@@ -27,6 +43,9 @@ func (fn Function) Returns() Class {
 	return fn.returns
 }
 func (fn Function) Call(args []ValueObject, proto ValueObject) (ValueObject, error) {
+	return fn.CallAt(CallSite{}, args, proto)
+}
+func (fn Function) CallAt(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
 	if len(args) != len(fn.arguments) {
 		return nil, fmt.Errorf("expected %d arguments, got %d", len(fn.arguments), len(args))
 	}
@@ -34,20 +53,124 @@ func (fn Function) Call(args []ValueObject, proto ValueObject) (ValueObject, err
 	if err != nil {
 		return nil, err
 	}
+	if fn.siteHandler != nil {
+		return fn.siteHandler(site, args, proto)
+	}
 	return fn.handler(args, proto)
 }
+func (fn Function) Mutates() bool {
+	return fn.mutates
+}
 
 type FunctionOptions struct {
-	Arguments []Class
-	Returns   Class
-	Handler   fnHandler
+	Arguments   []Class
+	Returns     Class
+	Handler     fnHandler
+	SiteHandler fnSiteHandler
+	// Whether calling the function makes changes
+	Mutates bool
 }
 
 func NewFunction(opts FunctionOptions) Function {
 	return Function{
-		arguments: opts.Arguments,
-		returns:   opts.Returns,
-		handler:   opts.Handler,
+		arguments:   opts.Arguments,
+		returns:     opts.Returns,
+		handler:     opts.Handler,
+		siteHandler: opts.SiteHandler,
+		mutates:     opts.Mutates,
+	}
+}
+
+// FunctionType represents the signature of a Function
+type FunctionType struct {
+	Arguments []Class
+	Returns   Class
+}
+
+func (ft FunctionType) ClassName() string {
+	args := make([]string, len(ft.Arguments))
+	for idx, arg := range ft.Arguments {
+		args[idx] = arg.ClassName()
+	}
+	name := fmt.Sprintf("func(%s)", strings.Join(args, ", "))
+	if ft.Returns != nil {
+		name += " " + ft.Returns.ClassName()
+	}
+	return name
+}
+func (ft FunctionType) Constructors() ConstructorMap {
+	return NewConstructorMap()
+}
+func (ft FunctionType) Get(key string) Object {
+	return nil
+}
+
+// GenericFunction represents a subroutine whose return type is determined by
+// the classes of the arguments it's called with
+type GenericFunction struct {
+	validator   func(args []Class) (Class, error)
+	handler     fnHandler
+	siteHandler fnSiteHandler
+	mutates     bool
+}
+
+func (fn GenericFunction) Get(key string) Object {
+	return nil
+}
+
+func (fn GenericFunction) ValidateArguments(args []Class) (Class, error) {
+	return fn.validator(args)
+}
+func (fn GenericFunction) Call(args []ValueObject, proto ValueObject) (ValueObject, error) {
+	return fn.CallAt(CallSite{}, args, proto)
+}
+func (fn GenericFunction) CallAt(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+	if fn.siteHandler != nil {
+		return fn.siteHandler(site, args, proto)
+	}
+	return fn.handler(args, proto)
+}
+func (fn GenericFunction) Mutates() bool {
+	return fn.mutates
+}
+
+type GenericFunctionOptions struct {
+	Validator   func(args []Class) (Class, error)
+	Handler     fnHandler
+	SiteHandler fnSiteHandler
+	Mutates     bool
+}
+
+func NewGenericFunction(opts GenericFunctionOptions) GenericFunction {
+	return GenericFunction{
+		validator:   opts.Validator,
+		handler:     opts.Handler,
+		siteHandler: opts.SiteHandler,
+		mutates:     opts.Mutates,
+	}
+}
+
+// ParameterizedFunction represents a subroutine whose signature is determined
+// by the classes it's given as type arguments
+type ParameterizedFunction struct {
+	instantiate func(types []Class) (Object, error)
+}
+
+func (fn ParameterizedFunction) Get(key string) Object {
+	return nil
+}
+
+func (fn ParameterizedFunction) WithTypeArguments(types []Class) (Object, error) {
+	return fn.instantiate(types)
+}
+
+type ParameterizedFunctionOptions struct {
+	Instantiate func(types []Class) (Object, error)
+}
+
+func NewParameterizedFunction(opts ParameterizedFunctionOptions) ParameterizedFunction {
+	return ParameterizedFunction{
+		instantiate: opts.Instantiate,
 	}
 }
 
@@ -84,25 +207,104 @@ func (st SymbolTable) ResolveFunctionBlock(node nodes.FunctionBlock, proto Value
 	}
 
 	fn.handler = func(args []ValueObject, proto ValueObject) (ValueObject, error) {
-		execTable := st.Clone()
-		err = execTable.ApplyArgumentList(node.Arguments, args)
+		return st.callFunctionBlock(node, fn.returns, args, proto)
+	}
+	return &fn, nil
+}
+
+// Evaluates the body of a function block that's already been validated
+func (st SymbolTable) callFunctionBlock(node nodes.FunctionBlock, returns Class, args []ValueObject, proto ValueObject) (ValueObject, error) {
+	execTable := st.Clone()
+	err := execTable.ApplyArgumentList(node.Arguments, args)
+	if err != nil {
+		return nil, err
+	}
+	if proto != nil {
+		execTable.immutable["self"] = proto
+	}
+	obj, err := execTable.ResolveBlock(node.Body)
+	if err != nil {
+		return nil, err
+	}
+	if returns != nil {
+		return Convert(returns, obj)
+	}
+	return nil, nil
+}
+
+// --
+// FUNCTION EXPRESSIONS
+// --
+
+// Function expressions are validated once when the enclosing block is
+// validated, so resolving one only binds the body to the current scope
+func (st SymbolTable) ResolveFunctionExpression(expr nodes.FunctionExpression) (*Function, error) {
+	scopeTable := st.Clone()
+	fn := Function{arguments: make([]Class, 0)}
+	if expr.Body.Arguments.Items != nil {
+		args, err := scopeTable.ResolveArgumentList(expr.Body.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		fn.arguments = args
+	}
+	if expr.Body.ReturnType != nil {
+		returns, err := scopeTable.ResolveTypeExpression(*expr.Body.ReturnType)
 		if err != nil {
 			return nil, err
 		}
-		if proto != nil {
-			execTable.immutable["self"] = proto
+		fn.returns = returns
+	}
+	fn.handler = func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+		execTable := st.Clone()
+		err := execTable.ApplyArgumentList(expr.Body.Arguments, args)
+		if err != nil {
+			return nil, err
 		}
-		obj, err := execTable.ResolveBlock(node.Body)
+		obj, err := execTable.ResolveBlock(expr.Body.Body)
 		if err != nil {
 			return nil, err
 		}
 		if fn.returns != nil {
-			return Construct(fn.returns, obj)
+			if obj == nil {
+				return nil, NodeError(expr, "expected return")
+			}
+			return Convert(fn.returns, obj)
 		}
 		return nil, nil
 	}
 	return &fn, nil
 }
+func (st SymbolTable) ValidateFunctionExpression(expr nodes.FunctionExpression) (Class, error) {
+	scopeTable := st.Clone()
+	fnType := FunctionType{Arguments: make([]Class, 0)}
+	if expr.Body.Arguments.Items != nil {
+		args, err := scopeTable.ResolveArgumentList(expr.Body.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		fnType.Arguments = args
+	}
+	err := scopeTable.ValidateBlock(expr.Body.Body)
+	if err != nil {
+		return nil, err
+	}
+	if expr.Body.ReturnType != nil {
+		returns, err := scopeTable.ResolveTypeExpression(*expr.Body.ReturnType)
+		if err != nil {
+			return nil, err
+		}
+		passes, err := scopeTable.ValidateBlockReturns(expr.Body.Body, returns)
+		if err != nil {
+			return nil, err
+		}
+		if !passes {
+			return nil, NodeError(expr, "expected return")
+		}
+		fnType.Returns = returns
+	}
+	return fnType, nil
+}
 
 // --
 // FUNCTION ARGUMENTS
@@ -144,7 +346,7 @@ func ResolveMethodArguments(method Method, args []ValueObject) ([]ValueObject, e
 	for idx, arg := range args {
 		argClass := methodArgs[idx]
 		if _, ok := argClass.(GenericObject); !ok {
-			args[idx], err = Construct(argClass, arg)
+			args[idx], err = Convert(argClass, arg)
 			if err != nil {
 				return nil, err
 			}
@@ -159,7 +361,7 @@ func ValidateMethodArguments(method Method, args []Class) error {
 	}
 	for idx, arg := range args {
 		if _, ok := methodArgs[idx].(GenericObject); !ok {
-			err := ShouldConstruct(methodArgs[idx], arg)
+			err := ShouldConvert(methodArgs[idx], arg)
 			if err != nil {
 				return err
 			}
@@ -235,11 +437,25 @@ func getEffectOperator(token tokens.Token) tokens.Token {
 	}
 	return effectOperator
 }
+
+// Returns the object an assignment changes a member of, which is either a local
+// variable or the receiver of a method
+func (st *SymbolTable) assignmentTarget(expr nodes.AssignmentExpression) (Object, error) {
+	name := expr.Name.Members[0]
+	if st.immutable[name] != nil {
+		if name == "self" && len(expr.Name.Members) > 1 {
+			return st.immutable[name], nil
+		}
+		return nil, NodeError(expr, "cannot reassign immutable variable %s", name)
+	}
+	return st.local[name], nil
+}
+
 func (st *SymbolTable) ResolveAssignmentExpression(expr nodes.AssignmentExpression) error {
-	if st.immutable[expr.Name.Members[0]] != nil {
-		return NodeError(expr, "cannot reassign immutable variable %s", expr.Name.Members[0])
+	parentObject, err := st.assignmentTarget(expr)
+	if err != nil {
+		return err
 	}
-	parentObject := st.local[expr.Name.Members[0]]
 	originalObject, err := st.ResolveSelector(expr.Name)
 	if err != nil {
 		return err
@@ -250,17 +466,25 @@ func (st *SymbolTable) ResolveAssignmentExpression(expr nodes.AssignmentExpressi
 			return err
 		}
 		if expr.Operator == tokens.ASSIGN {
-			newObject, err := Construct(object.Class(), operand)
+			newObject, err := Convert(object.Class(), operand)
 			if err != nil {
 				return err
 			}
 			object = newObject
 		} else {
-			object, err = Operate(getEffectOperator(expr.Operator), object, operand)
+			result, err := Operate(getEffectOperator(expr.Operator), object, operand)
+			if err != nil {
+				return NodeError(expr, "%s", err)
+			}
+			object, err = Convert(object.Class(), result)
 			if err != nil {
-				return NodeError(expr, err.Error())
+				return NodeError(expr, "%s", err)
 			}
 		}
+		if len(expr.Name.Members) == 1 {
+			st.local[expr.Name.Members[0]] = object
+			return nil
+		}
 		var eval func(Object, []string) error
 		eval = func(current Object, members []string) error {
 			if valueObj, ok := current.(ValueObject); ok {
@@ -276,17 +500,19 @@ func (st *SymbolTable) ResolveAssignmentExpression(expr nodes.AssignmentExpressi
 		if err != nil {
 			return err
 		}
-		st.local[expr.Name.Members[0]] = parentObject
+		if st.immutable[expr.Name.Members[0]] == nil {
+			st.local[expr.Name.Members[0]] = parentObject
+		}
 	} else {
 		return NodeError(expr, "cannot assign to non-value object")
 	}
 	return nil
 }
 func (st *SymbolTable) ValidateAssignmentExpression(expr nodes.AssignmentExpression) error {
-	if st.immutable[expr.Name.Members[0]] != nil {
-		return NodeError(expr, "cannot reassign immutable variable %s", expr.Name.Members[0])
+	currentObject, err := st.assignmentTarget(expr)
+	if err != nil {
+		return err
 	}
-	currentObject := st.local[expr.Name.Members[0]]
 	for _, member := range expr.Name.Members[1:] {
 		switch object := currentObject.(type) {
 		case ObjectClass:
@@ -316,15 +542,19 @@ func (st *SymbolTable) ValidateAssignmentExpression(expr nodes.AssignmentExpress
 			return err
 		}
 		if expr.Operator == tokens.ASSIGN {
-			err := ShouldConstruct(operand, class)
+			err := ShouldConvert(class, operand)
 			if err != nil {
-				return NodeError(expr, err.Error())
+				return NodeError(expr, "%s", err)
 			}
 			return nil
 		} else {
-			err = ShouldOperate(getEffectOperator(expr.Operator), class, operand)
+			result, err := OperatorReturns(getEffectOperator(expr.Operator), class, operand)
+			if err != nil {
+				return NodeError(expr, "%s", err)
+			}
+			err = ShouldConvert(class, result)
 			if err != nil {
-				return NodeError(expr, err.Error())
+				return NodeError(expr, "%s", err)
 			}
 		}
 	} else {
@@ -615,7 +845,7 @@ func (st *SymbolTable) ResolveSwitchBlock(expr nodes.SwitchBlock) (ValueObject,
 				}
 				evaluated, err := Operate(tokens.EQUALS, target, caseCondition)
 				if err != nil {
-					return nil, NodeError(caseBlock, err.Error())
+					return nil, NodeError(caseBlock, "%s", err)
 				}
 				if conditionResult, ok := evaluated.(BooleanLiteral); ok && bool(conditionResult) {
 					resolved = true
@@ -738,7 +968,7 @@ func (st *SymbolTable) ValidateGuardStatement(expr nodes.GuardStatement) error {
 	}
 	err = ValidateMethodArguments(guardFn, []Class{class})
 	if err != nil {
-		return NodeError(expr, err.Error())
+		return NodeError(expr, "%s", err)
 	}
 	return nil
 }
@@ -868,9 +1098,9 @@ func (st *SymbolTable) ValidateBlockReturns(expr nodes.Block, shouldReturn Class
 			if err != nil {
 				return false, err
 			}
-			err = ShouldConstruct(shouldReturn, returnClass)
+			err = ShouldConvert(shouldReturn, returnClass)
 			if err != nil {
-				return false, NodeError(expr, err.Error())
+				return false, NodeError(expr, "%s", err)
 			}
 			doesReturn = true
 		case nodes.ThrowStatement:
@@ -0,0 +1,254 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/resource"
+)
+
+// The collection the results of methods with an idempotency key are kept in
+const IdempotencyCollection = "idempotency"
+
+// How long the result of a method is kept for its idempotency key, unless it's
+// set with SetIdempotencyTTL or the method declares it
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// How long an idempotency key is reserved for while its method is running,
+// unless it's set with SetIdempotencyLease. If the host stops before the method
+// is done, the key can be used again once the lease is over
+const DefaultIdempotencyLease = time.Minute
+
+// Sets how long the result of a method is kept for its idempotency key
+func (ctx *BuildContext) SetIdempotencyTTL(ttl time.Duration) {
+	ctx.idempotencyTTL = ttl
+}
+
+// Sets how long an idempotency key is reserved for while its method is running
+func (ctx *BuildContext) SetIdempotencyLease(lease time.Duration) {
+	ctx.idempotencyLease = lease
+}
+
+// idempotency describes how a method recognizes that it's being called again
+// with something it's already handled, so that it returns what it returned
+// before instead of making the same changes twice
+// i.e. command(req.request_id, Duration("PT1H")) Pay(req: PayRequest) Receipt { ... }
+type idempotency struct {
+	// the expression the key is evaluated from, with the arguments of the
+	// method in scope
+	key  nodes.Expression
+	args nodes.ArgumentList
+	// how long results are kept, or zero for as long as the build context keeps
+	// them
+	ttl time.Duration
+}
+
+// Returns the idempotency a method declares with its options, which are an
+// expression for the key and optionally how long results are kept for it
+func idempotencyFromOptions(table SymbolTable, node nodes.ContextMethod) (*idempotency, error) {
+	if len(node.Options) == 0 {
+		return nil, nil
+	}
+	if len(node.Options) > 2 {
+		return nil, NodeError(node, "%s should have an idempotency key and optionally how long it's kept for, got %d options", node.Name, len(node.Options))
+	}
+	idem := &idempotency{key: node.Options[0], args: node.Block.Arguments}
+	// the key can't make changes since it's evaluated before the method decides
+	// whether to run
+	scope := table.Clone().ReadOnly()
+	if _, err := scope.ResolveArgumentList(node.Block.Arguments); err != nil {
+		return nil, err
+	}
+	class, err := scope.ValidateExpression(idem.key)
+	if err != nil {
+		return nil, err
+	}
+	if class == nil {
+		return nil, NodeError(node, "idempotency key of %s should be a value", node.Name)
+	}
+	if len(node.Options) == 2 {
+		class, err := table.ValidateExpression(node.Options[1])
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := class.(Duration); !ok {
+			return nil, NodeError(node, "idempotency key of %s should be kept for a Duration, got %s", node.Name, class.ClassName())
+		}
+		ttl, err := table.ResolveValueObject(node.Options[1])
+		if err != nil {
+			return nil, err
+		}
+		idem.ttl = time.Duration(ttl.(DurationLiteral))
+		if idem.ttl <= 0 {
+			return nil, NodeError(node, "idempotency key of %s should be kept for longer than %s", node.Name, idem.ttl)
+		}
+	}
+	return idem, nil
+}
+
+// Returns the key a call of a method is kept with, which is qualified by the
+// name of the method
+func (idem *idempotency) keyOf(table SymbolTable, name string, args []ValueObject) (string, error) {
+	scope := table.Clone().ReadOnly()
+	if err := scope.ApplyArgumentList(idem.args, args); err != nil {
+		return "", err
+	}
+	obj, err := scope.ResolveValueObject(idem.key)
+	if err != nil {
+		return "", err
+	}
+	bytes, err := json.Marshal(ToInterface(obj))
+	if err != nil {
+		return "", err
+	}
+	return name + ":" + string(bytes), nil
+}
+
+// What an idempotency key is kept with while the method is running, so that a
+// call with the same key at the same time doesn't run it as well
+const idempotencyPending = "pending"
+
+// Calls a method in a unit of work and keeps what it returns for its key,
+// unless it's already been called with the key and what it returned then
+// hasn't expired, in which case that's returned instead. The key is reserved
+// before the method runs and the result is kept in the unit of work, so that
+// it's only kept along with the changes the method makes
+func (idem *idempotency) call(table SymbolTable, name string, args []ValueObject, returns Class, call func(SymbolTable) (ValueObject, error)) (ValueObject, error) {
+	work := table.frame.Work
+	buildCtx := work.buildCtx
+	key, err := idem.keyOf(table, name, args)
+	if err != nil {
+		return nil, fmt.Errorf("cannot evaluate idempotency key: %s", err.Error())
+	}
+	driver, ok := buildCtx.resources[StorageResource].(resource.StorageDriver)
+	if !ok {
+		return nil, fmt.Errorf("%s has an idempotency key, but there isn't a storage resource to keep results in", name)
+	}
+	ttl := idem.ttl
+	if ttl == 0 {
+		ttl = buildCtx.idempotencyTTL
+	}
+	now := buildCtx.Now()
+	expires := now.Add(ttl).Format(time.RFC3339Nano)
+	reservation := resource.Document{
+		"status":  idempotencyPending,
+		"expires": now.Add(buildCtx.idempotencyLease).Format(time.RFC3339Nano),
+	}
+
+	doc, err := reserve(driver, key, now, reservation)
+	if errors.Is(err, resource.ErrExists) {
+		if doc["status"] == idempotencyPending {
+			return nil, fmt.Errorf("%s is already being called with the same idempotency key", name)
+		}
+		result, _ := doc["result"].(string)
+		if returns == nil {
+			return nil, nil
+		}
+		return FromBytes([]byte(result), returns)
+	} else if err != nil {
+		return nil, err
+	}
+	// the reservation is given up if the method fails or its changes aren't
+	// committed, so that the call can be made again. It's only removed if it's
+	// still ours, since another call can take it over once the lease is over
+	released := false
+	release := func() {
+		if released {
+			return
+		}
+		released = true
+		err := driver.Apply([]resource.Write{{Collection: IdempotencyCollection, Key: key, Match: reservation}})
+		if err != nil && !errors.Is(err, resource.ErrNotFound) && !errors.Is(err, resource.ErrConflict) {
+			buildCtx.Logger().Warn(fmt.Sprintf("cannot release idempotency key of %s: %s", name, err.Error()), "key", key)
+		}
+	}
+	work.OnDiscard(release)
+
+	obj, err := call(table)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	result, err := json.Marshal(ToInterface(obj))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// the result replaces the reservation only if it's still ours, so the unit
+	// of work fails to commit if another call has taken the key over
+	err = work.Storage(driver).Apply([]resource.Write{{
+		Collection: IdempotencyCollection,
+		Key:        key,
+		Document:   resource.Document{"result": string(result), "expires": expires},
+		Match:      reservation,
+	}})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Reserves an idempotency key, taking over a record that's expired. If the key
+// is already kept, the record it's kept with is returned along with ErrExists
+func reserve(driver resource.StorageDriver, key string, now time.Time, reservation resource.Document) (resource.Document, error) {
+	for {
+		err := driver.Insert(IdempotencyCollection, key, reservation)
+		if !errors.Is(err, resource.ErrExists) {
+			return nil, err
+		}
+		doc, err := driver.Get(IdempotencyCollection, key)
+		if errors.Is(err, resource.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !expired(doc, now) {
+			return doc, resource.ErrExists
+		}
+		// the expired record is only replaced if it's the one that was read, so
+		// that of the calls trying to take it over only one gets to
+		err = driver.Apply([]resource.Write{
+			{Collection: IdempotencyCollection, Key: key, Match: resource.Document{"expires": doc["expires"]}},
+			{Collection: IdempotencyCollection, Key: key, Document: reservation, Insert: true},
+		})
+		if err == nil {
+			return nil, nil
+		} else if !errors.Is(err, resource.ErrConflict) && !errors.Is(err, resource.ErrNotFound) {
+			return nil, err
+		}
+	}
+}
+
+// Returns whether a record of an idempotency key has expired
+func expired(doc resource.Document, now time.Time) bool {
+	expires, err := time.Parse(time.RFC3339Nano, fmt.Sprint(doc["expires"]))
+	return err != nil || !now.Before(expires)
+}
+
+// Removes the results that have expired from the storage resource
+func (ctx *BuildContext) purgeResults(driver resource.StorageDriver) error {
+	records, err := driver.Find(IdempotencyCollection, resource.Document{})
+	if err != nil {
+		return err
+	}
+	now := ctx.Now()
+	for _, record := range records {
+		if !expired(record.Document, now) {
+			continue
+		}
+		// a record that's been taken over since it was read is left alone
+		err := driver.Apply([]resource.Write{{
+			Collection: IdempotencyCollection,
+			Key:        record.Key,
+			Match:      resource.Document{"expires": record.Document["expires"]},
+		}})
+		if err != nil && !errors.Is(err, resource.ErrNotFound) && !errors.Is(err, resource.ErrConflict) {
+			return err
+		}
+	}
+	return nil
+}
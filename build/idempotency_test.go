@@ -0,0 +1,105 @@
+package build
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hntrl/lang/resource"
+)
+
+const paymentsSource = `context acme.payments {
+	entity Payment {
+		payment_id String
+		amount Int
+	}
+
+	command(request_id) Pay(request_id: String, amount: Int) Int {
+		Payment.create(Payment{ payment_id: request_id, amount: amount })
+		return Payment.count()
+	}
+}`
+
+// CAN RETURN WHAT A COMMAND RETURNED THE FIRST TIME IT'S CALLED WITH A KEY
+func TestIdempotentCommand(t *testing.T) {
+	buildCtx, clock := setupBuildContext(t)
+	buildCtx.SetIdempotencyTTL(time.Hour)
+	ctx := setupContext(t, buildCtx, paymentsSource)
+
+	if out := invoke(t, ctx, "Pay", `{"request_id": "a", "amount": 5}`); out != "1" {
+		t.Errorf("expected 1, got %s", out)
+	}
+	if out := invoke(t, ctx, "Pay", `{"request_id": "a", "amount": 5}`); out != "1" {
+		t.Errorf("expected the first result again, got %s", out)
+	}
+	if out := invoke(t, ctx, "Pay", `{"request_id": "b", "amount": 5}`); out != "2" {
+		t.Errorf("expected 2, got %s", out)
+	}
+	// once the result expires the command runs again, and fails since the
+	// payment already exists
+	clock.Advance(time.Hour)
+	if _, err := ctx.Invoke("Pay", []byte(`{"request_id": "a", "amount": 5}`)); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected the command to run again, got %v", err)
+	}
+	// the reservation is given up since the command failed
+	if _, err := buildCtx.resources[StorageResource].(resource.StorageDriver).Get(IdempotencyCollection, `acme.payments.Pay:"a"`); err == nil {
+		t.Errorf("expected the key to be released")
+	}
+}
+
+// CAN CALL A COMMAND AGAIN ONCE THE RESERVATION OF A CALL THAT NEVER FINISHED
+// IS OVER
+func TestIdempotencyLease(t *testing.T) {
+	buildCtx, clock := setupBuildContext(t)
+	buildCtx.SetIdempotencyLease(time.Minute)
+	ctx := setupContext(t, buildCtx, paymentsSource)
+
+	// a call that was reserved by a host that stopped before it finished
+	driver := buildCtx.resources[StorageResource].(resource.StorageDriver)
+	driver.Put(IdempotencyCollection, `acme.payments.Pay:"a"`, resource.Document{
+		"status":  idempotencyPending,
+		"expires": testEpoch.Add(time.Minute).Format(time.RFC3339Nano),
+	})
+	if _, err := ctx.Invoke("Pay", []byte(`{"request_id": "a", "amount": 5}`)); err == nil || !strings.Contains(err.Error(), "already being called") {
+		t.Errorf("expected the key to be reserved, got %v", err)
+	}
+	clock.Advance(time.Minute)
+	if out := invoke(t, ctx, "Pay", `{"request_id": "a", "amount": 5}`); out != "1" {
+		t.Errorf("expected 1, got %s", out)
+	}
+	doc, err := driver.Get(IdempotencyCollection, `acme.payments.Pay:"a"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["result"] != "1" || doc["expires"] != testEpoch.Add(time.Minute+DefaultIdempotencyTTL).Format(time.RFC3339Nano) {
+		t.Errorf("expected the result to be kept for as long as results are, got %v", doc)
+	}
+}
+
+// CANNOT TAKE OVER A KEY THAT ANOTHER CALL HAS ALREADY TAKEN OVER
+func TestIdempotencyTakeOver(t *testing.T) {
+	driver := resource.NewMemoryStore()
+	expired := resource.Document{"status": idempotencyPending, "expires": testEpoch.Format(time.RFC3339Nano)}
+	driver.Put(IdempotencyCollection, "k", expired)
+	now := testEpoch.Add(time.Second)
+
+	lease := now.Add(time.Minute).Format(time.RFC3339Nano)
+	first := resource.Document{"status": idempotencyPending, "expires": lease}
+	if _, err := reserve(driver, "k", now, first); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err := reserve(driver, "k", now, expired); err != resource.ErrExists || doc["expires"] != lease {
+		t.Errorf("expected the key to be reserved, got %v (%v)", doc, err)
+	}
+	// a call that read the expired record before it was taken over
+	err := driver.Apply([]resource.Write{
+		{Collection: IdempotencyCollection, Key: "k", Match: resource.Document{"expires": expired["expires"]}},
+		{Collection: IdempotencyCollection, Key: "k", Document: expired, Insert: true},
+	})
+	if err == nil {
+		t.Errorf("expected the record to be taken over once")
+	}
+	if doc, _ := driver.Get(IdempotencyCollection, "k"); doc["expires"] != lease {
+		t.Errorf("expected the first reservation to be kept, got %v", doc)
+	}
+}
@@ -23,6 +23,14 @@ type ObjectClass interface {
 	Fields() map[string]Class
 }
 
+// InstanceClass represents a class whose instances have members that the class
+// itself doesn't, i.e. the save method of an entity. They can be validated on a
+// value of the class, but not on the class
+type InstanceClass interface {
+	Class
+	InstanceGet(string) Object
+}
+
 // ComparableClass represents anything that can be compared to another object
 // i.e. a == b
 type ComparableClass interface {
@@ -37,6 +45,13 @@ type OperableClass interface {
 	OperatorRules() OperatorRules
 }
 
+// StringableClass represents a class that can be made into a String but can't
+// add a constructor to the String class itself, i.e. a class from a package
+type StringableClass interface {
+	Class
+	ToString(ValueObject) (string, error)
+}
+
 // Method represents anything that can be called with arguments
 type Method interface {
 	Arguments() []Class
@@ -44,6 +59,31 @@ type Method interface {
 	Call([]ValueObject, ValueObject) (ValueObject, error)
 }
 
+// GenericMethod represents anything that can be called with arguments whose
+// return type depends on the classes of the arguments it's called with
+type GenericMethod interface {
+	ValidateArguments([]Class) (Class, error)
+	Call([]ValueObject, ValueObject) (ValueObject, error)
+}
+
+// ParameterizedMethod represents anything that has to be given classes as type
+// arguments before it can be called i.e. json.parse<Order>(str)
+type ParameterizedMethod interface {
+	WithTypeArguments([]Class) (Object, error)
+}
+
+// CallSiteMethod represents a method that needs to know where it's called from
+// i.e. to attach the source position to a log entry
+type CallSiteMethod interface {
+	CallAt(CallSite, []ValueObject, ValueObject) (ValueObject, error)
+}
+
+// MutatingMethod represents a method that can make changes when it's called,
+// which isn't allowed from a query
+type MutatingMethod interface {
+	Mutates() bool
+}
+
 // ObjectInterface represents an interface that can make classes from a ContextObject
 type ObjectInterface interface {
 	ObjectClassFromNode(*Context, nodes.ContextObject) (Class, error)
@@ -82,6 +122,12 @@ type Indexable[T ValueObject] interface {
 	Len() int
 }
 
+// IndexableClass represents a class whose values can be accessed using indices
+type IndexableClass interface {
+	Class
+	ElementClass() Class
+}
+
 // Exportable represents the object a class assumes when being imported from an
 // outside context
 type Exportable interface {
@@ -0,0 +1,473 @@
+package build
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/hntrl/lang/language/tokens"
+)
+
+// Returns the signature of a callback argument if it can be called with the
+// leading classes in params. Callbacks can choose to accept fewer arguments
+// than are supplied (i.e. map accepts both func(item) and func(item, index))
+func validateCallback(arg Class, params ...Class) (FunctionType, error) {
+	fnType, ok := arg.(FunctionType)
+	if !ok {
+		return FunctionType{}, fmt.Errorf("expected function, got %s", arg.ClassName())
+	}
+	if len(fnType.Arguments) == 0 || len(fnType.Arguments) > len(params) {
+		return FunctionType{}, fmt.Errorf("%s cannot be used as a callback with %d arguments", fnType.ClassName(), len(params))
+	}
+	for idx, argClass := range fnType.Arguments {
+		if err := ShouldConstruct(argClass, params[idx]); err != nil {
+			return FunctionType{}, err
+		}
+	}
+	return fnType, nil
+}
+
+// Same as validateCallback, but also checks that the callback returns a Boolean
+func validatePredicate(arg Class, params ...Class) error {
+	fnType, err := validateCallback(arg, params...)
+	if err != nil {
+		return err
+	}
+	if fnType.Returns == nil || ShouldConstruct(Boolean{}, fnType.Returns) != nil {
+		return fmt.Errorf("%s must return Boolean", fnType.ClassName())
+	}
+	return nil
+}
+
+func validateArgumentCount(args []Class, count int) error {
+	if len(args) != count {
+		return fmt.Errorf("expected %d arguments, got %d", count, len(args))
+	}
+	return nil
+}
+
+// Calls a callback with as many of the supplied arguments as it accepts
+func callCallback(callback ValueObject, args ...ValueObject) (ValueObject, error) {
+	method, ok := callback.(Method)
+	if !ok {
+		return nil, fmt.Errorf("%s is not callable", callback.Class().ClassName())
+	}
+	return method.Call(args[:len(method.Arguments())], nil)
+}
+
+func callPredicate(callback ValueObject, args ...ValueObject) (bool, error) {
+	obj, err := callCallback(callback, args...)
+	if err != nil {
+		return false, err
+	}
+	result, err := Construct(Boolean{}, obj)
+	if err != nil {
+		return false, err
+	}
+	return bool(result.(BooleanLiteral)), nil
+}
+
+// Returns true if two values are equal. Classes that define an equality
+// comparator use it, otherwise the underlying values are compared
+func valueEquals(a, b ValueObject) (bool, error) {
+	if fn, err := getOperatorFn(tokens.EQUALS, a.Class(), b.Class()); err == nil {
+		result, err := fn(a, b)
+		if err != nil {
+			return false, err
+		}
+		return bool(result.(BooleanLiteral)), nil
+	}
+	return ClassEquals(a.Class(), b.Class()) && reflect.DeepEqual(a.Value(), b.Value()), nil
+}
+
+// Returns the class used for the items yielded by groupBy
+func groupClass(key Class, item Class) Type {
+	return Type{
+		Name: "Group",
+		fields: map[string]Class{
+			"key":   key,
+			"items": NewIterable(item, 0),
+		},
+	}
+}
+
+func (it Iterable) indexOf(obj ValueObject) (int, error) {
+	for idx, item := range it.Items {
+		equal, err := valueEquals(item, obj)
+		if err != nil {
+			return -1, err
+		}
+		if equal {
+			return idx, nil
+		}
+	}
+	return -1, nil
+}
+
+func (it Iterable) methods() map[string]Object {
+	class := Iterable{ParentType: it.ParentType}
+	return map[string]Object{
+		"map": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				fnType, err := validateCallback(args[0], it.ParentType, Integer{})
+				if err != nil {
+					return nil, err
+				}
+				if fnType.Returns == nil {
+					return nil, fmt.Errorf("%s must return a value", fnType.ClassName())
+				}
+				return NewIterable(fnType.Returns, 0), nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				fnType := args[0].Class().(FunctionType)
+				out := NewIterable(fnType.Returns, len(it.Items))
+				for idx, item := range it.Items {
+					obj, err := callCallback(args[0], item, IntegerLiteral(idx))
+					if err != nil {
+						return nil, err
+					}
+					out.Items[idx] = obj
+				}
+				return out, nil
+			},
+		}),
+		"filter": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				if err := validatePredicate(args[0], it.ParentType, Integer{}); err != nil {
+					return nil, err
+				}
+				return class, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				out := NewIterable(it.ParentType, 0)
+				for idx, item := range it.Items {
+					ok, err := callPredicate(args[0], item, IntegerLiteral(idx))
+					if err != nil {
+						return nil, err
+					}
+					if ok {
+						out.Items = append(out.Items, item)
+					}
+				}
+				return out, nil
+			},
+		}),
+		"reduce": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 2); err != nil {
+					return nil, err
+				}
+				fnType, err := validateCallback(args[0], args[1], it.ParentType, Integer{})
+				if err != nil {
+					return nil, err
+				}
+				if len(fnType.Arguments) < 2 {
+					return nil, fmt.Errorf("%s must accept an accumulator and an item", fnType.ClassName())
+				}
+				if fnType.Returns == nil {
+					return nil, fmt.Errorf("%s must return a value", fnType.ClassName())
+				}
+				if err := ShouldConstruct(fnType.Arguments[0], fnType.Returns); err != nil {
+					return nil, err
+				}
+				return fnType.Returns, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				acc := args[1]
+				for idx, item := range it.Items {
+					obj, err := callCallback(args[0], acc, item, IntegerLiteral(idx))
+					if err != nil {
+						return nil, err
+					}
+					acc = obj
+				}
+				return acc, nil
+			},
+		}),
+		"find": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				if err := validatePredicate(args[0], it.ParentType, Integer{}); err != nil {
+					return nil, err
+				}
+				return NewOptionalClass(it.ParentType), nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				for idx, item := range it.Items {
+					ok, err := callPredicate(args[0], item, IntegerLiteral(idx))
+					if err != nil {
+						return nil, err
+					}
+					if ok {
+						return NilableObject{it.ParentType, item}, nil
+					}
+				}
+				return NilableObject{it.ParentType, nil}, nil
+			},
+		}),
+		"some": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				if err := validatePredicate(args[0], it.ParentType, Integer{}); err != nil {
+					return nil, err
+				}
+				return Boolean{}, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				for idx, item := range it.Items {
+					ok, err := callPredicate(args[0], item, IntegerLiteral(idx))
+					if err != nil {
+						return nil, err
+					}
+					if ok {
+						return BooleanLiteral(true), nil
+					}
+				}
+				return BooleanLiteral(false), nil
+			},
+		}),
+		"every": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				if err := validatePredicate(args[0], it.ParentType, Integer{}); err != nil {
+					return nil, err
+				}
+				return Boolean{}, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				for idx, item := range it.Items {
+					ok, err := callPredicate(args[0], item, IntegerLiteral(idx))
+					if err != nil {
+						return nil, err
+					}
+					if !ok {
+						return BooleanLiteral(false), nil
+					}
+				}
+				return BooleanLiteral(true), nil
+			},
+		}),
+		"sortBy": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				fnType, err := validateCallback(args[0], it.ParentType)
+				if err != nil {
+					return nil, err
+				}
+				if fnType.Returns == nil {
+					return nil, fmt.Errorf("%s must return a value", fnType.ClassName())
+				}
+				if err := ShouldOperate(tokens.LESS, fnType.Returns, fnType.Returns); err != nil {
+					return nil, err
+				}
+				return class, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				keys := make([]ValueObject, len(it.Items))
+				for idx, item := range it.Items {
+					key, err := callCallback(args[0], item)
+					if err != nil {
+						return nil, err
+					}
+					keys[idx] = key
+				}
+				order := make([]int, len(it.Items))
+				for idx := range order {
+					order[idx] = idx
+				}
+				var sortErr error
+				sort.SliceStable(order, func(i, j int) bool {
+					result, err := Operate(tokens.LESS, keys[order[i]], keys[order[j]])
+					if err != nil {
+						sortErr = err
+						return false
+					}
+					return bool(result.(BooleanLiteral))
+				})
+				if sortErr != nil {
+					return nil, sortErr
+				}
+				out := NewIterable(it.ParentType, len(it.Items))
+				for idx, itemIdx := range order {
+					out.Items[idx] = it.Items[itemIdx]
+				}
+				return out, nil
+			},
+		}),
+		"groupBy": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				fnType, err := validateCallback(args[0], it.ParentType)
+				if err != nil {
+					return nil, err
+				}
+				if fnType.Returns == nil {
+					return nil, fmt.Errorf("%s must return a value", fnType.ClassName())
+				}
+				return NewIterable(groupClass(fnType.Returns, it.ParentType), 0), nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				fnType := args[0].Class().(FunctionType)
+				group := groupClass(fnType.Returns, it.ParentType)
+				keys := NewIterable(fnType.Returns, 0)
+				groups := make([]Iterable, 0)
+				for _, item := range it.Items {
+					key, err := callCallback(args[0], item)
+					if err != nil {
+						return nil, err
+					}
+					idx, err := keys.indexOf(key)
+					if err != nil {
+						return nil, err
+					}
+					if idx == -1 {
+						keys.Items = append(keys.Items, key)
+						groups = append(groups, NewIterable(it.ParentType, 0))
+						idx = len(groups) - 1
+					}
+					groups[idx].Items = append(groups[idx].Items, item)
+				}
+				out := NewIterable(group, len(groups))
+				for idx, items := range groups {
+					out.Items[idx] = TypeObject{group, map[string]ValueObject{
+						"key":   keys.Items[idx],
+						"items": items,
+					}}
+				}
+				return out, nil
+			},
+		}),
+		"push": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				for _, arg := range args {
+					if err := ShouldConstruct(it.ParentType, arg); err != nil {
+						return nil, err
+					}
+				}
+				return class, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				out := NewIterable(it.ParentType, len(it.Items))
+				copy(out.Items, it.Items)
+				for _, arg := range args {
+					item, err := Construct(it.ParentType, arg)
+					if err != nil {
+						return nil, err
+					}
+					out.Items = append(out.Items, item)
+				}
+				return out, nil
+			},
+		}),
+		"concat": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				if err := ShouldConstruct(class, args[0]); err != nil {
+					return nil, err
+				}
+				return class, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				other, err := Construct(class, args[0])
+				if err != nil {
+					return nil, err
+				}
+				otherItems := other.(Iterable).Items
+				out := NewIterable(it.ParentType, len(it.Items))
+				copy(out.Items, it.Items)
+				out.Items = append(out.Items, otherItems...)
+				return out, nil
+			},
+		}),
+		"includes": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				if err := ShouldConstruct(it.ParentType, args[0]); err != nil {
+					return nil, err
+				}
+				return Boolean{}, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				idx, err := it.indexOf(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return BooleanLiteral(idx != -1), nil
+			},
+		}),
+		"indexOf": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 1); err != nil {
+					return nil, err
+				}
+				if err := ShouldConstruct(it.ParentType, args[0]); err != nil {
+					return nil, err
+				}
+				return Integer{}, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				idx, err := it.indexOf(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return IntegerLiteral(idx), nil
+			},
+		}),
+		"reverse": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 0); err != nil {
+					return nil, err
+				}
+				return class, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				out := NewIterable(it.ParentType, len(it.Items))
+				for idx, item := range it.Items {
+					out.Items[len(it.Items)-1-idx] = item
+				}
+				return out, nil
+			},
+		}),
+		"unique": NewGenericFunction(GenericFunctionOptions{
+			Validator: func(args []Class) (Class, error) {
+				if err := validateArgumentCount(args, 0); err != nil {
+					return nil, err
+				}
+				return class, nil
+			},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				out := NewIterable(it.ParentType, 0)
+				for _, item := range it.Items {
+					idx, err := out.indexOf(item)
+					if err != nil {
+						return nil, err
+					}
+					if idx == -1 {
+						out.Items = append(out.Items, item)
+					}
+				}
+				return out, nil
+			},
+		}),
+	}
+}
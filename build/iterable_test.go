@@ -0,0 +1,121 @@
+package build
+
+import (
+	"strings"
+	"testing"
+)
+
+type iterableFixture struct {
+	Block    string
+	Returns  string
+	Expected string
+}
+
+// Evaluates each fixture, failing the test when the result isn't expected
+func checkIterableFixtures(t *testing.T, fixtures []iterableFixture) {
+	t.Helper()
+	for _, fixture := range fixtures {
+		out, err := evaluate(t, fixture.Returns, fixture.Block)
+		if err != nil {
+			t.Errorf("cannot evaluate %s: %s", fixture.Block, err)
+		} else if out != fixture.Expected {
+			t.Errorf("expected %s to be %s, got %s", fixture.Block, fixture.Expected, out)
+		}
+	}
+}
+
+// CAN TRANSFORM, SEARCH AND ORDER ARRAYS WITH CALLBACKS
+func TestIterableMethods(t *testing.T) {
+	checkIterableFixtures(t, []iterableFixture{
+		{`items := []Int{1, 2, 3}
+		return items.map(func(n: Int) Int { return n * 2 })`, "[]Int", "[2,4,6]"},
+		{`items := []Int{1, 2, 3, 4}
+		return items.filter(func(n: Int, idx: Int) Bool { return idx % 2 == 0 })`, "[]Int", "[1,3]"},
+		{`items := []Int{1, 2, 3}
+		return items.reduce(func(acc: Int, n: Int) Int { return acc + n }, 10)`, "Int", "16"},
+		{`items := []String{"a", "bb", "ccc"}
+		return items.find(func(s: String) Bool { return len(s) == 2 })`, "String?", `"bb"`},
+		{`items := []Int{1, 2}
+		return items.find(func(n: Int) Bool { return n > 2 })`, "Int?", "null"},
+		{`items := []Int{1, 2}
+		return items.some(func(n: Int) Bool { return n > 1 })`, "Bool", "true"},
+		{`items := []Int{1, 2}
+		return items.every(func(n: Int) Bool { return n > 1 })`, "Bool", "false"},
+		{`items := []String{"pear", "fig", "apple"}
+		return items.sortBy(func(s: String) Int { return len(s) })`, "[]String", `["fig","pear","apple"]`},
+		{`items := []Int{1, 2, 3, 4}
+		return len(items.groupBy(func(n: Int) Int { return n % 2 }))`, "Int", "2"},
+		{`items := []Int{1, 2}
+		return items.push(3).concat([]Int{4})`, "[]Int", "[1,2,3,4]"},
+		{`items := []Int{3, 1, 3, 2, 1}
+		return items.unique().reverse()`, "[]Int", "[2,1,3]"},
+		{`items := []Int{1, 2, 3}
+		return items.indexOf(3)`, "Int", "2"},
+		{`items := []Int{1, 2, 3}
+		return items.includes(4)`, "Bool", "false"},
+	})
+}
+
+// CAN SET AN INDEX OF AN ARRAY WITHOUT CHANGING THE ARRAY IT WAS COPIED FROM
+func TestIterableSetIndex(t *testing.T) {
+	items := Iterable{Integer{}, []ValueObject{IntegerLiteral(1), IntegerLiteral(2), IntegerLiteral(3)}}
+	updated, err := items.SetIndex(0, IntegerLiteral(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items.Items[0] != IntegerLiteral(1) || updated.(Iterable).Items[0] != IntegerLiteral(9) {
+		t.Errorf("expected only the copy to change, got %v and %v", items.Items, updated.(Iterable).Items)
+	}
+	window, _ := items.Range(0, 2)
+	if _, err := window.SetIndex(1, IntegerLiteral(9)); err != nil {
+		t.Fatal(err)
+	}
+	if items.Items[1] != IntegerLiteral(2) {
+		t.Errorf("expected setting an index of a slice to leave the array, got %v", items.Items)
+	}
+	if _, err := items.SetIndex(3, IntegerLiteral(9)); err == nil {
+		t.Errorf("expected an index out of range to fail")
+	}
+}
+
+// CAN CALL THE METHODS OF AN EMPTY ARRAY
+func TestEmptyIterable(t *testing.T) {
+	checkIterableFixtures(t, []iterableFixture{
+		{`items := []Int{}
+		return len(items.map(func(n: Int) Int { return n * 2 }))`, "Int", "0"},
+		{`items := []Int{}
+		return items.reduce(func(acc: Int, n: Int) Int { return acc + n }, 7)`, "Int", "7"},
+		{`items := []Int{}
+		return items.some(func(n: Int) Bool { return true })`, "Bool", "false"},
+		{`items := []Int{}
+		return items.every(func(n: Int) Bool { return false })`, "Bool", "true"},
+		{`items := []Int{}
+		return items.find(func(n: Int) Bool { return true })`, "Int?", "null"},
+		{`items := []Int{}
+		return items.sortBy(func(n: Int) Int { return n }).concat([]Int{})`, "[]Int", "[]"},
+		{`items := []Int{}
+		return len(items.groupBy(func(n: Int) Int { return n }))`, "Int", "0"},
+		{`items := []Int{}
+		return items.indexOf(1)`, "Int", "-1"},
+	})
+}
+
+// CANNOT CALL THE METHODS OF AN ARRAY WITH CALLBACKS THAT DON'T FIT, OR
+// REDUCE IT WITHOUT AN INITIAL VALUE
+func TestIterableErrors(t *testing.T) {
+	fixtures := map[string]string{
+		`return items.reduce(func(acc: Int, n: Int) Int { return acc + n })`: "expected 2 arguments, got 1",
+		`return items.reduce(func(n: Int) Int { return n }, 0)`:              "must accept an accumulator and an item",
+		`return items.map(func(n: Int) {})`:                                  "must return a value",
+		`return items.filter(func(n: Int) Int { return n })`:                 "must return Boolean",
+		`return items.map(func(d: Date) Int { return 1 })`:                   "cannot construct Date from Integer",
+		`return items.map(func(a: Int, b: Int, c: Int) Int { return a })`:    "cannot be used as a callback with 2 arguments",
+		`return items.map(1)`: "expected function, got Integer",
+	}
+	for block, message := range fixtures {
+		_, err := evaluate(t, "Int", "items := []Int{1}\n"+block)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %s to fail with %q, got %v", block, message, err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/hntrl/lang/language/tokens"
 )
@@ -91,10 +92,63 @@ func (str String) Constructors() ConstructorMap {
 	csMap.AddConstructor(Integer{}, numericConstructor)
 	csMap.AddConstructor(Float{}, numericConstructor)
 	csMap.AddConstructor(Boolean{}, numericConstructor)
+	csMap.AddConstructor(Date{}, func(obj ValueObject) (ValueObject, error) {
+		return StringLiteral(obj.(DateLiteral).String()), nil
+	})
+	csMap.AddConstructor(DateTime{}, func(obj ValueObject) (ValueObject, error) {
+		return StringLiteral(obj.(DateTimeLiteral).String()), nil
+	})
+	csMap.AddConstructor(Duration{}, func(obj ValueObject) (ValueObject, error) {
+		return StringLiteral(obj.(DurationLiteral).String()), nil
+	})
+	csMap.AddConstructor(Decimal{}, func(obj ValueObject) (ValueObject, error) {
+		return StringLiteral(obj.(DecimalLiteral).String()), nil
+	})
 	return csMap
 }
+func (str String) ComparableRules() ComparatorRules {
+	rules := NewComparatorRules()
+	stringComparePredicate := func(cb func(a, b string) bool) OperatorFn {
+		return func(a, b ValueObject) (ValueObject, error) {
+			return BooleanLiteral(cb(string(a.(StringLiteral)), string(b.(StringLiteral)))), nil
+		}
+	}
+	rules.AddComparator(String{}, tokens.EQUALS, stringComparePredicate(func(a, b string) bool {
+		return a == b
+	}))
+	rules.AddComparator(String{}, tokens.NOT_EQUALS, stringComparePredicate(func(a, b string) bool {
+		return a != b
+	}))
+	rules.AddComparator(String{}, tokens.LESS, stringComparePredicate(func(a, b string) bool {
+		return a < b
+	}))
+	rules.AddComparator(String{}, tokens.GREATER, stringComparePredicate(func(a, b string) bool {
+		return a > b
+	}))
+	rules.AddComparator(String{}, tokens.LESS_EQUAL, stringComparePredicate(func(a, b string) bool {
+		return a <= b
+	}))
+	rules.AddComparator(String{}, tokens.GREATER_EQUAL, stringComparePredicate(func(a, b string) bool {
+		return a >= b
+	}))
+	return rules
+}
+func (str String) OperatorRules() OperatorRules {
+	rules := NewOperatorRules()
+	rules.AddOperator(String{}, tokens.ADD, func(a, b ValueObject) (ValueObject, error) {
+		return a.(StringLiteral) + b.(StringLiteral), nil
+	})
+	return rules
+}
+
+func (str String) ElementClass() Class {
+	return String{}
+}
+
+// Methods on String are the same ones that are available on StringLiteral so
+// they can be validated without a value
 func (str String) Get(key string) Object {
-	return nil
+	return StringLiteral("").Get(key)
 }
 
 type StringLiteral string
@@ -109,7 +163,7 @@ func (sl StringLiteral) Set(key string, obj ValueObject) error {
 	return nil
 }
 func (sl StringLiteral) Get(key string) Object {
-	methods := map[string]Function{
+	methods := map[string]Object{
 		"lower": NewFunction(FunctionOptions{
 			Returns: String{},
 			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
@@ -122,10 +176,167 @@ func (sl StringLiteral) Get(key string) Object {
 				return StringLiteral(strings.ToUpper(string(sl))), nil
 			},
 		}),
+		"trim": NewFunction(FunctionOptions{
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return StringLiteral(strings.TrimSpace(string(sl))), nil
+			},
+		}),
+		"split": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				String{},
+			},
+			Returns: NewIterable(String{}, 0),
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				parts := strings.Split(string(sl), string(args[0].(StringLiteral)))
+				out := NewIterable(String{}, len(parts))
+				for idx, part := range parts {
+					out.Items[idx] = StringLiteral(part)
+				}
+				return out, nil
+			},
+		}),
+		"replace": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				String{},
+				String{},
+			},
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				old := string(args[0].(StringLiteral))
+				new := string(args[1].(StringLiteral))
+				return StringLiteral(strings.ReplaceAll(string(sl), old, new)), nil
+			},
+		}),
+		"contains": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				String{},
+			},
+			Returns: Boolean{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return BooleanLiteral(strings.Contains(string(sl), string(args[0].(StringLiteral)))), nil
+			},
+		}),
+		"startsWith": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				String{},
+			},
+			Returns: Boolean{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return BooleanLiteral(strings.HasPrefix(string(sl), string(args[0].(StringLiteral)))), nil
+			},
+		}),
+		"endsWith": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				String{},
+			},
+			Returns: Boolean{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return BooleanLiteral(strings.HasSuffix(string(sl), string(args[0].(StringLiteral)))), nil
+			},
+		}),
+		"indexOf": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				String{},
+			},
+			Returns: Integer{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				idx := strings.Index(string(sl), string(args[0].(StringLiteral)))
+				if idx == -1 {
+					return IntegerLiteral(-1), nil
+				}
+				return IntegerLiteral(utf8.RuneCountInString(string(sl)[:idx])), nil
+			},
+		}),
+		"repeat": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				Integer{},
+			},
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				count := int(args[0].(IntegerLiteral))
+				if count < 0 {
+					return nil, fmt.Errorf("negative repeat count %d", count)
+				}
+				return StringLiteral(strings.Repeat(string(sl), count)), nil
+			},
+		}),
+		"padStart": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				Integer{},
+				String{},
+			},
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				padding := sl.padding(int(args[0].(IntegerLiteral)), string(args[1].(StringLiteral)))
+				return StringLiteral(padding) + sl, nil
+			},
+		}),
+		"padEnd": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				Integer{},
+				String{},
+			},
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				padding := sl.padding(int(args[0].(IntegerLiteral)), string(args[1].(StringLiteral)))
+				return sl + StringLiteral(padding), nil
+			},
+		}),
+		"substring": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				Integer{},
+				Integer{},
+			},
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return sl.Range(int(args[0].(IntegerLiteral)), int(args[1].(IntegerLiteral)))
+			},
+		}),
 	}
 	return methods[key]
 }
 
+// Returns the padding needed to bring the string up to length runes by
+// repeating pad
+func (sl StringLiteral) padding(length int, pad string) string {
+	missing := length - utf8.RuneCountInString(string(sl))
+	if missing <= 0 || pad == "" {
+		return ""
+	}
+	padRunes := []rune(strings.Repeat(pad, missing/utf8.RuneCountInString(pad)+1))
+	return string(padRunes[:missing])
+}
+
+func (sl StringLiteral) GetIndex(index int) (ValueObject, error) {
+	runes := []rune(string(sl))
+	if index < 0 || index >= len(runes) {
+		return nil, fmt.Errorf("index %d out of range with length %d", index, len(runes))
+	}
+	return StringLiteral(runes[index]), nil
+}
+func (sl StringLiteral) SetIndex(index int, obj ValueObject) (Indexable[ValueObject], error) {
+	runes := []rune(string(sl))
+	if index < 0 || index >= len(runes) {
+		return nil, fmt.Errorf("index %d out of range with length %d", index, len(runes))
+	}
+	str, ok := obj.(StringLiteral)
+	if !ok {
+		return nil, fmt.Errorf("cannot assign %s to String index", obj.Class().ClassName())
+	}
+	return StringLiteral(string(runes[:index]) + string(str) + string(runes[index+1:])), nil
+}
+func (sl StringLiteral) Range(a, b int) (Indexable[ValueObject], error) {
+	runes := []rune(string(sl))
+	if a < 0 || b > len(runes) || a > b {
+		return nil, fmt.Errorf("slice bounds [%d:%d] out of range with length %d", a, b, len(runes))
+	}
+	return StringLiteral(runes[a:b]), nil
+}
+func (sl StringLiteral) Len() int {
+	return utf8.RuneCountInString(string(sl))
+}
+
 type Number struct{}
 
 func (num Number) ClassName() string {
@@ -155,9 +366,75 @@ func (num Number) Get(key string) Object {
 	return nil
 }
 
+// Returns the position of a class in the numeric tower. Values can be
+// implicitly widened to a class further up the tower (Int -> Float -> Double),
+// but narrowing them requires an explicit conversion. Number is the untyped
+// catch-all for numbers that come from outside the language, so it sits at the
+// top. Returns -1 if the class isn't numeric
+func numericRank(class Class) int {
+	switch class.(type) {
+	case Integer:
+		return 0
+	case Float:
+		return 1
+	case Double:
+		return 2
+	case Number:
+		return 3
+	}
+	return -1
+}
+
+// Returns true if the class is on the numeric tower or is an exact decimal
+func isNumeric(class Class) bool {
+	_, ok := class.(Decimal)
+	return ok || numericRank(class) != -1
+}
+
+// Returns the class both numeric classes can be widened to
+func widerNumericClass(a, b Class) Class {
+	if numericRank(b) > numericRank(a) {
+		return b
+	}
+	return a
+}
+
+// Returns the narrowest class on the numeric tower that every class can be
+// widened to
+func CommonNumericClass(classes ...Class) (Class, error) {
+	var common Class
+	for _, class := range classes {
+		if numericRank(class) == -1 {
+			return nil, fmt.Errorf("expected numeric class, got %s", class.ClassName())
+		}
+		if common == nil {
+			common = class
+		} else {
+			common = widerNumericClass(common, class)
+		}
+	}
+	if common == nil {
+		return nil, fmt.Errorf("expected at least one numeric class")
+	}
+	return common, nil
+}
+
 func numComparePredicate(cb func(float64, float64) bool) func(ValueObject, ValueObject) (ValueObject, error) {
 	numConstructor := (Number{}).Constructors().Get(Number{})
 	return func(a, b ValueObject) (ValueObject, error) {
+		// compare integers directly so they don't lose precision as a float
+		if ia, ok := a.(IntegerLiteral); ok {
+			if ib, ok := b.(IntegerLiteral); ok {
+				switch {
+				case ia < ib:
+					return BooleanLiteral(cb(0, 1)), nil
+				case ia > ib:
+					return BooleanLiteral(cb(1, 0)), nil
+				default:
+					return BooleanLiteral(cb(0, 0)), nil
+				}
+			}
+		}
 		na, err := numConstructor(a)
 		if err != nil {
 			return nil, err
@@ -213,41 +490,152 @@ func numOperatorPredicate(cb func(float64, float64) (ValueObject, error)) Operat
 		return cb(float64(na.(NumberLiteral)), float64(nb.(NumberLiteral)))
 	}
 }
-func addNumOperatorMap(rules OperatorRules, class Class, fn ConstructorFn) {
-	rules.AddOperator(class, tokens.ADD, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
+func addNumOperatorMap(rules OperatorRules, class Class, returns Class) {
+	fn := returns.Constructors().Get(Number{})
+	rules.AddOperatorReturning(class, tokens.ADD, returns, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
 		return fn(NumberLiteral(a + b))
 	}))
-	rules.AddOperator(class, tokens.SUB, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
+	rules.AddOperatorReturning(class, tokens.SUB, returns, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
 		return fn(NumberLiteral(a - b))
 	}))
-	rules.AddOperator(class, tokens.MUL, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
+	rules.AddOperatorReturning(class, tokens.MUL, returns, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
 		return fn(NumberLiteral(a * b))
 	}))
-	rules.AddOperator(class, tokens.PWR, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
+	rules.AddOperatorReturning(class, tokens.PWR, returns, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
 		return fn(NumberLiteral(math.Pow(a, b)))
 	}))
-	rules.AddOperator(class, tokens.QUO, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
+	rules.AddOperatorReturning(class, tokens.QUO, returns, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
 		return fn(NumberLiteral(a / b))
 	}))
-	rules.AddOperator(class, tokens.REM, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
+	rules.AddOperatorReturning(class, tokens.REM, returns, numOperatorPredicate(func(a, b float64) (ValueObject, error) {
 		return fn(NumberLiteral(math.Mod(a, b)))
 	}))
 }
-func numOperatorMap(fn ConstructorFn) OperatorRules {
+
+var errIntegerOverflow = fmt.Errorf("integer overflow")
+var errIntegerDivisionByZero = fmt.Errorf("integer division by zero")
+
+func intOperatorPredicate(cb func(int64, int64) (int64, error)) OperatorFn {
+	return func(a, b ValueObject) (ValueObject, error) {
+		result, err := cb(int64(a.(IntegerLiteral)), int64(b.(IntegerLiteral)))
+		if err != nil {
+			return nil, err
+		}
+		return IntegerLiteral(result), nil
+	}
+}
+
+// Operations between two integers are done on int64 so they stay exact, and
+// overflow or division by zero is an error rather than a wrapped result
+func addIntOperatorMap(rules OperatorRules) {
+	rules.AddOperator(Integer{}, tokens.ADD, intOperatorPredicate(func(a, b int64) (int64, error) {
+		if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+			return 0, errIntegerOverflow
+		}
+		return a + b, nil
+	}))
+	rules.AddOperator(Integer{}, tokens.SUB, intOperatorPredicate(func(a, b int64) (int64, error) {
+		if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+			return 0, errIntegerOverflow
+		}
+		return a - b, nil
+	}))
+	rules.AddOperator(Integer{}, tokens.MUL, intOperatorPredicate(mulInt))
+	rules.AddOperator(Integer{}, tokens.PWR, intOperatorPredicate(powInt))
+	rules.AddOperator(Integer{}, tokens.QUO, intOperatorPredicate(func(a, b int64) (int64, error) {
+		if b == 0 {
+			return 0, errIntegerDivisionByZero
+		}
+		if a == math.MinInt64 && b == -1 {
+			return 0, errIntegerOverflow
+		}
+		return a / b, nil
+	}))
+	rules.AddOperator(Integer{}, tokens.REM, intOperatorPredicate(func(a, b int64) (int64, error) {
+		if b == 0 {
+			return 0, errIntegerDivisionByZero
+		}
+		if b == -1 {
+			return 0, nil
+		}
+		return a % b, nil
+	}))
+}
+func mulInt(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	result := a * b
+	if result/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, errIntegerOverflow
+	}
+	return result, nil
+}
+
+// Raises an integer to a power by squaring, so it takes as many steps as the
+// exponent has bits. Only bases of 0, 1 and -1 can have exponents too large to
+// overflow, so they're answered without any steps at all
+func powInt(a, b int64) (int64, error) {
+	if b < 0 {
+		return 0, fmt.Errorf("negative integer exponent %d", b)
+	}
+	switch {
+	case b == 0 || a == 1:
+		return 1, nil
+	case a == 0:
+		return 0, nil
+	case a == -1:
+		if b%2 == 0 {
+			return 1, nil
+		}
+		return -1, nil
+	}
+	result := int64(1)
+	for {
+		var err error
+		if b&1 == 1 {
+			if result, err = mulInt(result, a); err != nil {
+				return 0, err
+			}
+		}
+		b >>= 1
+		if b == 0 {
+			return result, nil
+		}
+		if a, err = mulInt(a, a); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Returns the operators of a numeric class with every other numeric class,
+// where the result is widened to whichever class is further up the tower
+func numOperatorMap(class Class) OperatorRules {
 	rules := NewOperatorRules()
-	addNumOperatorMap(rules, Number{}, fn)
-	addNumOperatorMap(rules, Double{}, fn)
-	addNumOperatorMap(rules, Integer{}, fn)
-	addNumOperatorMap(rules, Float{}, fn)
+	for _, other := range []Class{Number{}, Double{}, Integer{}, Float{}} {
+		returns := widerNumericClass(class, other)
+		if _, ok := returns.(Integer); ok {
+			addIntOperatorMap(rules)
+		} else {
+			addNumOperatorMap(rules, other, returns)
+		}
+	}
 	return rules
 }
 
+// Converts a float to an integer, returning an error if it doesn't fit
+func intFromFloat(f float64) (ValueObject, error) {
+	if math.IsNaN(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return nil, fmt.Errorf("%v overflows Integer", f)
+	}
+	return IntegerLiteral(f), nil
+}
+
 func (num Number) ComparableRules() ComparatorRules {
 	return numCompareMap()
 }
 func (num Number) OperatorRules() OperatorRules {
-	fn := num.Constructors().Get(Number{})
-	return numOperatorMap(fn)
+	return numOperatorMap(Number{})
 }
 
 type NumberLiteral float64
@@ -277,8 +665,13 @@ func (db Double) Constructors() ConstructorMap {
 	}
 	csMap.AddConstructor(Number{}, numericConstructor)
 	csMap.AddConstructor(Double{}, numericConstructor)
-	csMap.AddConstructor(Integer{}, numericConstructor)
+	csMap.AddConstructor(Integer{}, func(obj ValueObject) (ValueObject, error) {
+		return numericConstructor(FloatLiteral(obj.(IntegerLiteral)))
+	})
 	csMap.AddConstructor(Float{}, numericConstructor)
+	csMap.AddConstructor(Decimal{}, func(obj ValueObject) (ValueObject, error) {
+		return numericConstructor(FloatLiteral(obj.(DecimalLiteral).Float64()))
+	})
 	return csMap
 }
 
@@ -286,8 +679,7 @@ func (db Double) ComparableRules() ComparatorRules {
 	return numCompareMap()
 }
 func (db Double) OperatorRules() OperatorRules {
-	fn := db.Constructors().Get(Number{})
-	return numOperatorMap(fn)
+	return numOperatorMap(Double{})
 }
 
 func (db Double) Get(key string) Object {
@@ -328,6 +720,9 @@ func (f Float) Constructors() ConstructorMap {
 	csMap.AddConstructor(Float{}, func(obj ValueObject) (ValueObject, error) {
 		return FloatLiteral(obj.(FloatLiteral)), nil
 	})
+	csMap.AddConstructor(Decimal{}, func(obj ValueObject) (ValueObject, error) {
+		return FloatLiteral(obj.(DecimalLiteral).Float64()), nil
+	})
 	return csMap
 }
 
@@ -335,8 +730,7 @@ func (f Float) ComparableRules() ComparatorRules {
 	return numCompareMap()
 }
 func (f Float) OperatorRules() OperatorRules {
-	fn := f.Constructors().Get(Number{})
-	return numOperatorMap(fn)
+	return numOperatorMap(Float{})
 }
 
 func (f Float) Get(key string) Object {
@@ -365,18 +759,15 @@ func (i Integer) ClassName() string {
 }
 func (i Integer) Constructors() ConstructorMap {
 	csMap := NewConstructorMap()
-	csMap.AddConstructor(Number{}, func(obj ValueObject) (ValueObject, error) {
-		return IntegerLiteral(obj.Value().(float64)), nil
-	})
-	csMap.AddConstructor(Double{}, func(obj ValueObject) (ValueObject, error) {
-		return IntegerLiteral(obj.(DoubleLiteral)), nil
-	})
+	floatConstructor := func(obj ValueObject) (ValueObject, error) {
+		return intFromFloat(obj.Value().(float64))
+	}
+	csMap.AddConstructor(Number{}, floatConstructor)
+	csMap.AddConstructor(Double{}, floatConstructor)
 	csMap.AddConstructor(Integer{}, func(obj ValueObject) (ValueObject, error) {
 		return IntegerLiteral(obj.(IntegerLiteral)), nil
 	})
-	csMap.AddConstructor(Float{}, func(obj ValueObject) (ValueObject, error) {
-		return IntegerLiteral(obj.(FloatLiteral)), nil
-	})
+	csMap.AddConstructor(Float{}, floatConstructor)
 	return csMap
 }
 
@@ -384,8 +775,7 @@ func (i Integer) ComparableRules() ComparatorRules {
 	return numCompareMap()
 }
 func (i Integer) OperatorRules() OperatorRules {
-	fn := i.Constructors().Get(Number{})
-	return numOperatorMap(fn)
+	return numOperatorMap(Integer{})
 }
 
 func (i Integer) Get(key string) Object {
@@ -406,77 +796,3 @@ func (il IntegerLiteral) Set(key string, obj ValueObject) error {
 func (il IntegerLiteral) Get(key string) Object {
 	return nil
 }
-
-type Date struct{}
-
-func (d Date) ClassName() string {
-	return "Date"
-}
-func (d Date) Constructors() ConstructorMap {
-	return NewConstructorMap()
-}
-
-func (d Date) Get(key string) Object {
-	switch key {
-	case "now":
-		return NewFunction(FunctionOptions{
-			Returns: Date{},
-			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
-				return DateLiteral{}, nil
-			},
-		})
-	}
-	return nil
-}
-
-type DateLiteral struct{}
-
-func (dl DateLiteral) Class() Class {
-	return Date{}
-}
-func (dl DateLiteral) Value() interface{} {
-	return nil
-}
-func (dl DateLiteral) Set(key string, obj ValueObject) error {
-	return nil
-}
-func (dl DateLiteral) Get(key string) Object {
-	return nil
-}
-
-type DateTime struct{}
-
-func (d DateTime) ClassName() string {
-	return "DateTime"
-}
-func (d DateTime) Constructors() ConstructorMap {
-	return NewConstructorMap()
-}
-
-func (d DateTime) Get(key string) Object {
-	switch key {
-	case "now":
-		return NewFunction(FunctionOptions{
-			Returns: DateTime{},
-			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
-				return DateTimeLiteral{}, nil
-			},
-		})
-	}
-	return nil
-}
-
-type DateTimeLiteral struct{}
-
-func (dl DateTimeLiteral) Class() Class {
-	return DateTime{}
-}
-func (dl DateTimeLiteral) Value() interface{} {
-	return nil
-}
-func (dl DateTimeLiteral) Set(key string, obj ValueObject) error {
-	return nil
-}
-func (dl DateTimeLiteral) Get(key string) Object {
-	return nil
-}
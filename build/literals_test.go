@@ -0,0 +1,117 @@
+package build
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// CAN RAISE INTEGERS TO LARGE POWERS WITHOUT STEPPING THROUGH THE EXPONENT
+func TestPowInt(t *testing.T) {
+	cases := []struct {
+		a, b   int64
+		result int64
+		err    error
+	}{
+		{2, 10, 1024, nil},
+		{-3, 3, -27, nil},
+		{7, 0, 1, nil},
+		{0, 0, 1, nil},
+		{1, math.MaxInt64, 1, nil},
+		{0, math.MaxInt64, 0, nil},
+		{-1, math.MaxInt64, -1, nil},
+		{-1, math.MaxInt64 - 1, 1, nil},
+		{-2, 63, math.MinInt64, nil},
+		{2, 62, 1 << 62, nil},
+		{2, 63, 0, errIntegerOverflow},
+		{3, math.MaxInt64, 0, errIntegerOverflow},
+	}
+	for _, c := range cases {
+		result, err := powInt(c.a, c.b)
+		if err != c.err || result != c.result {
+			t.Errorf("%d ** %d: expected %d (%v), got %d (%v)", c.a, c.b, c.result, c.err, result, err)
+		}
+	}
+	if _, err := powInt(2, -1); err == nil {
+		t.Errorf("expected an error for a negative exponent")
+	}
+}
+
+// CAN CALL THE METHODS OF A STRING, WHICH COUNT IN RUNES RATHER THAN BYTES
+func TestStringMethods(t *testing.T) {
+	fixtures := map[string]string{
+		`s := "Crème Brûlée"
+		return s.lower()`: `"crème brûlée"`,
+		`s := "smörgåsbord"
+		return s.upper()`: `"SMÖRGÅSBORD"`,
+		`s := "  padded \n"
+		return s.trim()`: `"padded"`,
+		`s := "a,b,,c"
+		return s.split(",")[2]`: `""`,
+		`s := "a-b-c"
+		return s.replace("-", "+")`: `"a+b+c"`,
+		`s := "naïve café"
+		return s.indexOf("café")`: "6",
+		`s := "naïve"
+		return s.indexOf("x")`: "-1",
+		`s := "ab"
+		return s.repeat(3)`: `"ababab"`,
+		`s := "7"
+		return s.padStart(3, "0")`: `"007"`,
+		`s := "€"
+		return s.padEnd(4, "ab")`: `"€aba"`,
+		`s := "long"
+		return s.padStart(2, "0")`: `"long"`,
+		`s := "日本語テキスト"
+		return s.substring(2, 4)`: `"語テ"`,
+		`s := "日本語"
+		return s[1]`: `"本"`,
+		`s := "日本語"
+		return s[1:]`: `"本語"`,
+		`s := "héllo"
+		return s[:2]`: `"hé"`,
+		`s := "日本語"
+		return len(s)`: "3",
+		`s := "naïve"
+		return s.contains("ïv")`: "true",
+		`s := "naïve"
+		return s.startsWith("na")`: "true",
+		`s := "naïve"
+		return s.endsWith("ve!")`: "false",
+	}
+	for expr, expected := range fixtures {
+		returns := "String"
+		if expected == "true" || expected == "false" {
+			returns = "Bool"
+		} else if expected[0] != '"' {
+			returns = "Int"
+		}
+		out, err := evaluate(t, returns, expr)
+		if err != nil {
+			t.Errorf("cannot evaluate %s: %s", expr, err)
+		} else if out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
+
+// CANNOT INDEX OR SLICE A STRING PAST ITS LAST RUNE OR REPEAT IT A NEGATIVE
+// NUMBER OF TIMES
+func TestStringErrors(t *testing.T) {
+	fixtures := map[string]string{
+		`s := "日本語"
+		return s[3]`: "index 3 out of range with length 3",
+		`s := "日本語"
+		return s[2:4]`: "slice bounds [2:4] out of range with length 3",
+		`s := "日本語"
+		return s.substring(2, 1)`: "slice bounds [2:1] out of range with length 3",
+		`s := "ab"
+		return s.repeat(-1)`: "negative repeat count -1",
+	}
+	for expr, message := range fixtures {
+		_, err := evaluate(t, "String", expr)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %s to fail with %q, got %v", expr, message, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/hntrl/lang/language/tokens"
+)
+
+// Frame describes what a symbol table is evaluating
+type Frame struct {
+	Context string
+	File    string
+	// The name of the method whose body is being evaluated, if there is one
+	Method string
+	// Whether the frame can't make changes, i.e. it's part of a query
+	ReadOnly bool
+	// The unit of work changes are made in, if the frame is part of a command
+	Work *UnitOfWork
+	// Where emitted events go instead of the event bus, if the frame is part of
+	// a method of an aggregate
+	Emitter func(EventObject) error
+	// The collection of the projection whose handler is being evaluated, which
+	// is the only thing that can change the projection
+	Projection string
+}
+
+// CallSite describes where a method is being called from
+type CallSite struct {
+	Frame
+	Position tokens.Position
+}
+
+// Returns the attributes that describe the call site in a log entry
+func (site CallSite) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("context", site.Context),
+	}
+	if site.Method != "" {
+		attrs = append(attrs, slog.String("method", site.Method))
+	}
+	source := site.Position.String()
+	if site.File != "" {
+		source = site.File + ":" + source
+	}
+	return append(attrs, slog.String("source", source))
+}
+
+// Sets the handler every log entry made in the build context is written to
+func (ctx *BuildContext) SetLogHandler(handler slog.Handler) {
+	ctx.logger = slog.New(handler)
+}
+
+// Returns the logger of the build context. If there isn't one the default
+// logger is used
+func (ctx *BuildContext) Logger() *slog.Logger {
+	if ctx == nil || ctx.logger == nil {
+		return slog.Default()
+	}
+	return ctx.logger
+}
+
+// Sets where print writes to
+func (ctx *BuildContext) SetOutput(w io.Writer) {
+	ctx.output = w
+}
+
+// Returns where print writes to, which is stdout unless it's been changed
+func (ctx *BuildContext) Output() io.Writer {
+	if ctx == nil || ctx.output == nil {
+		return os.Stdout
+	}
+	return ctx.output
+}
+
+// Returns how a value is shown when it's printed
+func printable(obj ValueObject) string {
+	switch obj := obj.(type) {
+	case nil:
+		return "nil"
+	case NilableObject:
+		if obj.Object == nil {
+			return "nil"
+		}
+		return printable(obj.Object)
+	case StringLiteral:
+		return string(obj)
+	case fmt.Stringer:
+		return obj.String()
+	}
+	return fmt.Sprint(obj.Value())
+}
@@ -0,0 +1,231 @@
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hntrl/lang/language/nodes"
+)
+
+const (
+	QueryOperation   = "query"
+	CommandOperation = "command"
+)
+
+// Operation represents a method that a host can invoke by name. Queries only
+// read, so they can't call anything that makes changes, and commands make their
+// changes in a unit of work that's only kept if they succeed
+// i.e. query FetchProducts() []Product { return Product.query() }
+type Operation struct {
+	Kind      string
+	Name      string
+	Private   bool
+	Comment   string
+	arguments []Class
+	returns   Class
+	// the arguments by the names they're given in a payload, which for an
+	// argument that's an object are the names of its properties
+	payload Type
+	// how a command recognizes that it's called again with something it's
+	// already done, if it has an idempotency key
+	idempotency *idempotency
+	node        nodes.FunctionBlock
+	ctx         *Context
+}
+
+func (op Operation) ClassName() string {
+	return op.Name
+}
+func (op Operation) Constructors() ConstructorMap {
+	return NewConstructorMap()
+}
+func (op Operation) Get(key string) Object {
+	return nil
+}
+
+// An operation can be passed around as a value, i.e. to invoke a command later
+func (op Operation) Class() Class {
+	return op
+}
+func (op Operation) Value() interface{} {
+	return op.qualifiedName()
+}
+func (op Operation) Set(key string, obj ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, op.Name)
+}
+
+// Returns the name the operation is known by outside of its context
+func (op Operation) qualifiedName() string {
+	return op.ctx.Name + "." + op.Name
+}
+
+func (op Operation) Arguments() []Class {
+	return op.arguments
+}
+func (op Operation) Returns() Class {
+	return op.returns
+}
+
+// Returns the class of the payload the operation is invoked with
+func (op Operation) Payload() ObjectClass {
+	return op.payload
+}
+
+func (op Operation) Mutates() bool {
+	return op.Kind == CommandOperation
+}
+
+func (op Operation) Call(args []ValueObject, proto ValueObject) (ValueObject, error) {
+	return op.CallAt(CallSite{}, args, proto)
+}
+func (op Operation) CallAt(site CallSite, args []ValueObject, proto ValueObject) (ValueObject, error) {
+	if len(args) != len(op.arguments) {
+		return nil, fmt.Errorf("expected %d arguments, got %d", len(op.arguments), len(args))
+	}
+	args, err := ResolveMethodArguments(op, args)
+	if err != nil {
+		return nil, err
+	}
+	// the body is evaluated with the symbols the context has now so it can use
+	// objects that were defined after the operation
+	table := op.ctx.Symbols().WithMethod(op.Name)
+	if op.Kind == QueryOperation {
+		// a query called from a command reads the changes the command has made
+		return table.ReadOnly().WithWork(site.Work).callFunctionBlock(op.node, op.returns, args, nil)
+	}
+	call := func(table SymbolTable) (ValueObject, error) {
+		return table.callFunctionBlock(op.node, op.returns, args, nil)
+	}
+	// the result of a command with an idempotency key is kept along with the
+	// changes it makes, so a repeated call returns it without making them again
+	if op.idempotency != nil {
+		body := call
+		call = func(table SymbolTable) (ValueObject, error) {
+			return op.idempotency.call(table, op.qualifiedName(), args, op.returns, body)
+		}
+	}
+	// a command called from another command makes its changes in the same unit
+	// of work, so they're kept or forgotten together
+	if site.Work != nil {
+		return call(table.WithWork(site.Work))
+	}
+	return op.ctx.buildCtx.evaluateInWork(table, call)
+}
+
+// Calls the operation with a payload of its arguments as a JSON object,
+// returning what it returns as JSON
+func (op Operation) Invoke(payload []byte) ([]byte, error) {
+	args, err := op.decodePayload(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", op.Name, err.Error())
+	}
+	result, err := op.Call(args, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", op.Name, err.Error())
+	}
+	return json.Marshal(ToInterface(result))
+}
+
+// Returns the arguments a payload of the operation is for
+func (op Operation) decodePayload(payload []byte) ([]ValueObject, error) {
+	if len(bytes.TrimSpace(payload)) == 0 {
+		payload = []byte("{}")
+	}
+	obj, err := FromBytes(payload, op.payload)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]ValueObject, len(op.arguments))
+	for idx, item := range op.node.Arguments.Items {
+		switch argNode := item.(type) {
+		case nodes.ArgumentItem:
+			args[idx], _ = obj.Get(argNode.Key).(ValueObject)
+		case nodes.ArgumentObject:
+			fields := make(map[string]ValueObject)
+			for _, item := range argNode.Items {
+				fields[item.Key], _ = obj.Get(item.Key).(ValueObject)
+			}
+			args[idx] = TypeObject{op.arguments[idx].(Type), fields}
+		}
+	}
+	return args, nil
+}
+
+func (op Operation) MethodClassFromNode(ctx *Context, node nodes.ContextMethod) (Class, error) {
+	op.Name = node.Name
+	op.Private = node.Private
+	op.Comment = node.Comment
+	op.node = node.Block
+	op.ctx = ctx
+
+	// queries don't make changes, so there's nothing for an idempotency key to
+	// keep them from doing twice
+	if op.Kind == QueryOperation && len(node.Options) > 0 {
+		return nil, NodeError(node, "query %s cannot have options", node.Name)
+	}
+	table := ctx.Symbols().WithMethod(node.Name)
+	if op.Kind == QueryOperation {
+		table = table.ReadOnly()
+	}
+	fn, err := table.ResolveFunctionBlock(node.Block, nil)
+	if err != nil {
+		return nil, err
+	}
+	op.idempotency, err = idempotencyFromOptions(table, node)
+	if err != nil {
+		return nil, err
+	}
+	op.arguments = fn.arguments
+	op.returns = fn.returns
+
+	op.payload = Type{Name: node.Name, fields: make(map[string]Class)}
+	addField := func(key string, class Class) error {
+		if _, ok := op.payload.fields[key]; ok {
+			return NodeError(node, "%s has more than one argument named %s", node.Name, key)
+		}
+		op.payload.fields[key] = class
+		return nil
+	}
+	for idx, item := range node.Block.Arguments.Items {
+		switch argNode := item.(type) {
+		case nodes.ArgumentItem:
+			if err := addField(argNode.Key, op.arguments[idx]); err != nil {
+				return nil, err
+			}
+		case nodes.ArgumentObject:
+			for _, item := range argNode.Items {
+				if err := addField(item.Key, op.arguments[idx].(Type).fields[item.Key]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return op, nil
+}
+
+// Returns the queries and commands of the context that a host can invoke,
+// ordered by name
+func (ctx *Context) Operations() []Operation {
+	out := []Operation{}
+	for _, obj := range ctx.objects {
+		if op, ok := obj.(Operation); ok && !op.Private {
+			out = append(out, op)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Invokes a query or command of the context by name with a payload of its
+// arguments as a JSON object, returning what it returns as JSON
+func (ctx *Context) Invoke(name string, payload []byte) ([]byte, error) {
+	op, ok := ctx.objects[name].(Operation)
+	if !ok || op.Private {
+		return nil, fmt.Errorf("%s has no operation %s", ctx.Name, name)
+	}
+	return op.Invoke(payload)
+}
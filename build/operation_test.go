@@ -0,0 +1,77 @@
+package build
+
+import (
+	"strings"
+	"testing"
+)
+
+const inventorySource = `context acme.inventory {
+	entity Item {
+		sku String
+		stock Int
+	}
+
+	query Stock(sku: String) Int {
+		return Item.findOne({ sku: sku }).stock
+	}
+	command Receive(sku: String, stock: Int) Int {
+		Item.create(Item{ sku: sku, stock: stock })
+		return Stock(sku)
+	}
+	private query Audit() Int {
+		return Item.count()
+	}
+}`
+
+// CAN LIST AND INVOKE THE OPERATIONS OF A CONTEXT THAT AREN'T PRIVATE
+func TestOperations(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, inventorySource)
+
+	ops := ctx.Operations()
+	if len(ops) != 2 || ops[0].Name != "Receive" || ops[1].Name != "Stock" {
+		t.Fatalf("expected Receive and Stock, got %v", ops)
+	}
+	if !ops[0].Mutates() || ops[1].Mutates() {
+		t.Errorf("expected only the command to make changes")
+	}
+	if _, err := ctx.Invoke("Audit", nil); err == nil {
+		t.Errorf("expected a private query not to be invoked")
+	}
+	if _, err := ctx.Invoke("Missing", nil); err == nil {
+		t.Errorf("expected an operation that doesn't exist not to be invoked")
+	}
+	if _, err := ctx.Invoke("Stock", []byte(`{"sku": 1}`)); err == nil {
+		t.Errorf("expected a payload of the wrong type to be refused")
+	}
+}
+
+// CAN READ THE CHANGES A COMMAND HAS MADE FROM A QUERY IT CALLS
+func TestCommandCallsQuery(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, inventorySource)
+
+	if out := invoke(t, ctx, "Receive", `{"sku": "a", "stock": 4}`); out != "4" {
+		t.Errorf("expected 4, got %s", out)
+	}
+	if out := invoke(t, ctx, "Stock", `{"sku": "a"}`); out != "4" {
+		t.Errorf("expected 4, got %s", out)
+	}
+}
+
+// CANNOT MAKE CHANGES FROM A QUERY
+func TestQueryIsReadOnly(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	_, err := newContext(buildCtx, `context acme.inventory {
+	entity Item {
+		sku String
+	}
+
+	query Receive(sku: String) {
+		Item.create(Item{ sku: sku })
+	}
+}`)
+	if err == nil || !strings.Contains(err.Error(), "makes changes") {
+		t.Errorf("expected a query that makes changes not to build, got %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/resource"
+)
+
+// The collection the position of every projection is stored in
+const checkpointCollection = "projections"
+
+// How many events a projection reads from the event store at a time
+const projectionBatchSize = 100
+
+type projectionState struct {
+	ctx *Context
+	// the handlers of the projection by the subject of the event they handle
+	handlers map[string]projectionHandler
+	events   resource.EventStore
+	subs     []resource.Subscription
+	// held while the projection is catching up so events are handled in order
+	mu sync.Mutex
+	// set when there might be events the projection hasn't caught up with, so
+	// that whoever is catching up goes on to them
+	pending atomic.Bool
+}
+
+type projectionHandler struct {
+	node  nodes.FunctionBlock
+	event Event
+}
+
+// Adds a handler to a projection, which is a method named on that's called
+// with an event to change the projection
+// i.e. func (OrderSummary) on(e: OrderPlaced) { ... }
+func (e Entity) AddMethod(ctx *Context, node nodes.ContextObjectMethod) error {
+	if !e.Projection {
+		return NodeError(node, "cannot add methods to entity %s", e.Name)
+	}
+	if node.Name != "on" {
+		return NodeError(node, "%s can only have handlers named on, got %s", e.Name, node.Name)
+	}
+	fn, err := ctx.Symbols().WithMethod(e.Name+".on").ResolveFunctionBlock(node.Block, nil)
+	if err != nil {
+		return err
+	}
+	if len(fn.arguments) != 1 {
+		return NodeError(node, "on should have one argument for the event, got %d", len(fn.arguments))
+	}
+	event, ok := fn.arguments[0].(Event)
+	if !ok {
+		return NodeError(node, "%s can only handle events, got %s", e.Name, fn.arguments[0].ClassName())
+	}
+	if fn.returns != nil {
+		return NodeError(node, "on cannot return a value")
+	}
+	if _, ok := e.projection.handlers[event.subject]; ok {
+		return NodeError(node, "%s already handles %s", e.Name, event.Name)
+	}
+	e.projection.handlers[event.subject] = projectionHandler{node: node.Block, event: event}
+	return nil
+}
+
+// Connects the projection to the event store, catches it up with the events
+// that have been stored since its checkpoint and keeps it caught up as events
+// are published
+func (e Entity) attachProjection(ctx Context) error {
+	var store resource.EventStore
+	if err := ctx.Resource(EventStoreResource, &store); err != nil {
+		return fmt.Errorf("projection %s: %s", e.Name, err.Error())
+	}
+	e.projection.events = store
+	if err := e.catchUp(); err != nil {
+		return fmt.Errorf("projection %s: %s", e.Name, err.Error())
+	}
+	subjects := make([]string, 0, len(e.projection.handlers))
+	for subject := range e.projection.handlers {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	for _, subject := range subjects {
+		// the message only says that there's something new in the event store,
+		// which is read from the checkpoint so nothing is missed or handled twice
+		sub, err := ctx.buildCtx.EventBus().Subscribe(subject, e.collection, func(resource.Message) error {
+			// the projection is behind until the next event if it can't catch up,
+			// which isn't a reason to fail publishing this one
+			if err := e.catchUp(); err != nil {
+				ctx.buildCtx.Logger().Error(fmt.Sprintf("projection %s cannot catch up: %s", e.Name, err.Error()))
+			}
+			return nil
+		})
+		if err != nil {
+			e.detachProjection()
+			return fmt.Errorf("projection %s: %s", e.Name, err.Error())
+		}
+		e.projection.subs = append(e.projection.subs, sub)
+	}
+	return nil
+}
+func (e Entity) detachProjection() error {
+	errs := []error{}
+	for _, sub := range e.projection.subs {
+		if err := sub.Unsubscribe(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	e.projection.subs = nil
+	e.projection.events = nil
+	return errors.Join(errs...)
+}
+
+// Returns the position of the last event the projection has handled
+func (e Entity) checkpoint(driver resource.StorageDriver) (int64, error) {
+	doc, err := driver.Get(checkpointCollection, e.collection)
+	if errors.Is(err, resource.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	// drivers decode numbers differently
+	switch position := doc["position"].(type) {
+	case json.Number:
+		return position.Int64()
+	case int:
+		return int64(position), nil
+	case int32:
+		return int64(position), nil
+	case int64:
+		return position, nil
+	case float64:
+		return int64(position), nil
+	}
+	return 0, fmt.Errorf("cannot read checkpoint of %s", e.Name)
+}
+
+// Handles every event that's been stored since the checkpoint. If the
+// projection is already catching up, i.e. because a handler emitted an event it
+// handles, it's left to go on to the new events instead of waiting for it
+func (e Entity) catchUp() error {
+	e.projection.pending.Store(true)
+	for e.projection.pending.Load() {
+		if !e.projection.mu.TryLock() {
+			return nil
+		}
+		e.projection.pending.Store(false)
+		err := e.handleSinceCheckpoint()
+		e.projection.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e Entity) handleSinceCheckpoint() error {
+	store := e.projection.events
+	if store == nil || e.store.driver == nil {
+		return fmt.Errorf("projection %s is not attached", e.Name)
+	}
+	position, err := e.checkpoint(e.store.driver)
+	if err != nil {
+		return err
+	}
+	for {
+		events, err := store.ReadAll(position, projectionBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		for _, stored := range events {
+			if unhandled, err := e.handle(stored); err != nil {
+				if !unhandled {
+					// the changes can't be kept, so the projection stays at its
+					// checkpoint and the event is handled again next time
+					return err
+				}
+				if err := e.skip(stored, err); err != nil {
+					return fmt.Errorf("cannot skip event %d: %s", stored.Position, err.Error())
+				}
+			}
+			position = stored.Position
+		}
+	}
+}
+
+// Publishes an event the projection can't handle to its dead letter subject
+// and moves the checkpoint past it, so that it doesn't keep the projection
+// from handling the events after it
+func (e Entity) skip(stored resource.StoredEvent, reason error) error {
+	buildCtx := e.projection.ctx.buildCtx
+	subject := "dead." + e.collection
+	var msg EventMessage
+	json.Unmarshal(stored.Data, &msg)
+	buildCtx.Logger().Error(
+		fmt.Sprintf("projection %s cannot handle event %d: %s", e.Name, stored.Position, reason.Error()),
+		"event", msg.Event,
+		"deadLetter", subject,
+	)
+	data, err := json.Marshal(DeadLetter{
+		Subscriber: e.collection,
+		Subject:    msg.Event,
+		Attempts:   1,
+		Error:      reason.Error(),
+		Data:       stored.Data,
+	})
+	if err != nil {
+		return err
+	}
+	if err := buildCtx.EventBus().Publish(resource.Message{Subject: subject, Data: data}); err != nil {
+		return err
+	}
+	return e.store.driver.Put(checkpointCollection, e.collection, resource.Document{"position": stored.Position})
+}
+
+// Handles an event, moving the checkpoint past it in the same unit of work as
+// the changes the handler makes. Returns whether the error is the event's
+// fault, i.e. because it can't be decoded or the handler fails, so that it can
+// be skipped
+func (e Entity) handle(stored resource.StoredEvent) (bool, error) {
+	var msg EventMessage
+	if err := json.Unmarshal(stored.Data, &msg); err != nil {
+		return true, err
+	}
+	table := e.projection.ctx.Symbols().WithMethod(e.Name + ".on")
+	table.frame.Projection = e.collection
+	var unhandled error
+	_, err := e.projection.ctx.buildCtx.evaluateInWork(table, func(table SymbolTable) (ValueObject, error) {
+		if handler, ok := e.projection.handlers[msg.Event]; ok {
+			ev, _, err := handler.event.Decode(resource.Message{Subject: msg.Event, Data: stored.Data})
+			if err != nil {
+				unhandled = err
+				return nil, err
+			}
+			_, err = table.callFunctionBlock(handler.node, nil, []ValueObject{ev}, nil)
+			if err != nil {
+				unhandled = err
+				return nil, err
+			}
+		}
+		driver := table.frame.Work.Storage(e.store.driver)
+		return nil, driver.Put(checkpointCollection, e.collection, resource.Document{"position": stored.Position})
+	})
+	return unhandled != nil, err
+}
+
+// Removes every instance of the projection and handles every event again
+func (e Entity) rebuild() error {
+	if err := e.reset(); err != nil {
+		return err
+	}
+	return e.catchUp()
+}
+
+// Removes every instance of the projection and moves its checkpoint back to
+// the start of the event store
+func (e Entity) reset() error {
+	e.projection.mu.Lock()
+	defer e.projection.mu.Unlock()
+	if e.store.driver == nil {
+		return fmt.Errorf("projection %s is not attached", e.Name)
+	}
+	work := NewUnitOfWork(e.projection.ctx.buildCtx)
+	driver := work.Storage(e.store.driver)
+	records, err := driver.Find(e.collection, resource.Document{})
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := driver.Delete(e.collection, record.Key); err != nil {
+			return err
+		}
+	}
+	if err := driver.Put(checkpointCollection, e.collection, resource.Document{"position": 0}); err != nil {
+		return err
+	}
+	return work.Commit()
+}
+
+// ProjectionStatus describes how far a projection has caught up with the
+// event store
+type ProjectionStatus struct {
+	Name string
+	// The position of the last event the projection has handled
+	Position int64
+	// The position of the last event in the event store
+	Head int64
+	// How many events the projection has yet to handle
+	Lag int64
+}
+
+func (e Entity) status() (ProjectionStatus, error) {
+	if e.projection.events == nil || e.store.driver == nil {
+		return ProjectionStatus{}, fmt.Errorf("projection %s is not attached", e.Name)
+	}
+	position, err := e.checkpoint(e.store.driver)
+	if err != nil {
+		return ProjectionStatus{}, err
+	}
+	head, err := e.projection.events.Head()
+	if err != nil {
+		return ProjectionStatus{}, err
+	}
+	return ProjectionStatus{Name: e.Name, Position: position, Head: head, Lag: head - position}, nil
+}
+
+// Returns how far each projection of the context has caught up with the event
+// store, ordered by name
+func (ctx *Context) Projections() ([]ProjectionStatus, error) {
+	out := []ProjectionStatus{}
+	for _, obj := range ctx.objects {
+		if e, ok := obj.(Entity); ok && e.Projection {
+			status, err := e.status()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, status)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// Rebuilds a projection of the context from the start of the event store
+func (ctx *Context) RebuildProjection(name string) error {
+	e, ok := ctx.objects[name].(Entity)
+	if !ok || !e.Projection {
+		return fmt.Errorf("%s has no projection %s", ctx.Name, name)
+	}
+	return e.rebuild()
+}
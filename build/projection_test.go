@@ -0,0 +1,136 @@
+package build
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hntrl/lang/resource"
+)
+
+const summariesSource = `import "flaky"
+
+context acme.summaries {
+	event OrderPlaced {
+		order_id String
+	}
+
+	aggregate Order {
+		order_id String
+	}
+	func (Order) apply(e: OrderPlaced) {}
+	func (Order) place() {
+		emit(OrderPlaced{ order_id: self.order_id })
+	}
+
+	command Place(order_id: String) {
+		order := Order.new(order_id)
+		order.place()
+	}
+
+	projection Summary {
+		order_id String
+	}
+	func (Summary) on(e: OrderPlaced) {
+		flaky.handle(e.order_id)
+		Summary.create(Summary{ order_id: e.order_id })
+	}
+	query Summaries() Int {
+		return Summary.count()
+	}
+}`
+
+// checkpointOutage is a storage driver that can't keep the changes of a
+// projection while it's down
+type checkpointOutage struct {
+	resource.MemoryStore
+	down *bool
+}
+
+func (store checkpointOutage) Attach() (resource.Resource, error) {
+	return store, nil
+}
+func (store checkpointOutage) Apply(writes []resource.Write) error {
+	for _, write := range writes {
+		if *store.down && write.Collection == checkpointCollection {
+			return errors.New("storage is unavailable")
+		}
+	}
+	return store.MemoryStore.Apply(writes)
+}
+
+// Sets up a context with a projection whose handler fails a number of times
+func setupSummaries(t *testing.T, failures int, driver resource.StorageDriver) (*BuildContext, *Context, *[]DeadLetter) {
+	t.Helper()
+	buildCtx, _ := setupBuildContext(t)
+	if driver != nil {
+		buildCtx.RegisterResource(StorageResource, driver)
+	}
+	calls, handled := 0, []string{}
+	buildCtx.RegisterPackage("flaky", flakyPackage{failures: failures, calls: &calls, handled: &handled})
+	letters := deadLetters(t, buildCtx, "dead.acme.summaries.Summary")
+	return buildCtx, setupContext(t, buildCtx, summariesSource), letters
+}
+
+// CAN KEEP A PROJECTION CAUGHT UP WITH THE EVENT STORE
+func TestProjection(t *testing.T) {
+	_, ctx, letters := setupSummaries(t, 0, nil)
+
+	invoke(t, ctx, "Place", `{"order_id": "o1"}`)
+	invoke(t, ctx, "Place", `{"order_id": "o2"}`)
+	if out := invoke(t, ctx, "Summaries", `{}`); out != "2" {
+		t.Errorf("expected 2, got %s", out)
+	}
+	statuses, err := ctx.Projections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Position != 2 || statuses[0].Lag != 0 {
+		t.Errorf("expected the projection to be caught up, got %+v", statuses)
+	}
+	if len(*letters) != 0 {
+		t.Errorf("expected no dead letters, got %+v", *letters)
+	}
+
+	if err := ctx.RebuildProjection("Summary"); err != nil {
+		t.Fatal(err)
+	}
+	if out := invoke(t, ctx, "Summaries", `{}`); out != "2" {
+		t.Errorf("expected 2 after rebuilding, got %s", out)
+	}
+}
+
+// CAN SKIP AN EVENT THE HANDLER OF A PROJECTION FAILS ON
+func TestProjectionSkipsUnhandledEvents(t *testing.T) {
+	_, ctx, letters := setupSummaries(t, 1, nil)
+
+	invoke(t, ctx, "Place", `{"order_id": "o1"}`)
+	invoke(t, ctx, "Place", `{"order_id": "o2"}`)
+	if out := invoke(t, ctx, "Summaries", `{}`); out != "1" {
+		t.Errorf("expected the first event to be skipped, got %s", out)
+	}
+	if len(*letters) != 1 || (*letters)[0].Subject != "acme.summaries.OrderPlaced" {
+		t.Errorf("expected a dead letter for the first event, got %+v", *letters)
+	}
+	if statuses, _ := ctx.Projections(); len(statuses) != 1 || statuses[0].Lag != 0 {
+		t.Errorf("expected the projection to move past the event, got %+v", statuses)
+	}
+}
+
+// CAN KEEP A PROJECTION AT ITS CHECKPOINT WHEN ITS CHANGES CAN'T BE KEPT
+func TestProjectionStaysAtCheckpoint(t *testing.T) {
+	down := true
+	_, ctx, letters := setupSummaries(t, 0, checkpointOutage{resource.NewMemoryStore(), &down})
+
+	invoke(t, ctx, "Place", `{"order_id": "o1"}`)
+	if statuses, _ := ctx.Projections(); len(statuses) != 1 || statuses[0].Position != 0 || statuses[0].Lag != 1 {
+		t.Errorf("expected the projection to stay at its checkpoint, got %+v", statuses)
+	}
+	down = false
+	invoke(t, ctx, "Place", `{"order_id": "o2"}`)
+	if out := invoke(t, ctx, "Summaries", `{}`); out != "2" {
+		t.Errorf("expected both events to be handled, got %s", out)
+	}
+	if len(*letters) != 0 {
+		t.Errorf("expected no dead letters, got %+v", *letters)
+	}
+}
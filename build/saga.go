@@ -0,0 +1,887 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/resource"
+)
+
+const (
+	SagaRunning   = "running"
+	SagaCompleted = "completed"
+	SagaFailed    = "failed"
+)
+
+// The methods every saga instance has, which fields and methods can't be named
+// after
+var sagaInstanceMethods = []string{"timeout", "cancelTimeout", "compensate", "complete", "fail"}
+
+// How many of the latest steps an instance keeps in its history, and how many
+// of the latest events it's handled it remembers so that it doesn't handle one
+// that's delivered again
+const (
+	sagaHistorySize   = 100
+	sagaProcessedSize = 1000
+)
+
+// Saga represents a workflow that spans contexts. Each instance is correlated
+// by its first field, which every event it handles has a field of the same
+// name for. Its handlers react to events by dispatching commands, and it keeps
+// the steps that undo what it's done in case it fails
+// i.e. saga Checkout { order_id String  status String = "new" }
+//
+//	func (Checkout) start(e: OrderPlaced) { self.compensate("refund") }
+//	func (Checkout) on(e: PaymentFailed) { self.fail(e.reason) }
+//	func (Checkout) timeout(name: String) { self.fail(name + " timed out") }
+type Saga struct {
+	Name     string
+	Private  bool
+	Comment  string
+	Identity string
+	fields   map[string]Class
+	// the values fields have when an instance is started
+	defaults map[string]ValueObject
+	// the handlers that start an instance and the ones that react to events of
+	// an instance that's running, by the subject of the event they handle
+	starters map[string]sagaHandler
+	handlers map[string]sagaHandler
+	// the methods that aren't event handlers by name, which are the one named
+	// timeout that's called with the name of a timeout once it's due and the
+	// ones that can be used as compensations
+	methods map[string]sagaHandler
+	// the collection instances are stored in, which is qualified by the name of
+	// the context so sagas with the same name don't collide
+	collection string
+	ctx        *Context
+	state      *sagaState
+}
+
+type sagaHandler struct {
+	node      nodes.FunctionBlock
+	arguments []Class
+	event     Event
+}
+
+type sagaState struct {
+	driver resource.StorageDriver
+	subs   []resource.Subscription
+	// held while an instance is changed so every change is made in order
+	mu sync.Mutex
+}
+
+// SagaInstance describes the state of an instance of a saga, which is what's
+// stored for it
+type SagaInstance struct {
+	Saga string `json:"saga"`
+	// The correlation key of the instance as JSON
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	// Why the instance failed, if it has
+	Reason string `json:"reason,omitempty"`
+	// The fields of the instance
+	State json.RawMessage `json:"state"`
+	// The timeouts that have yet to fire, ordered by when they're due
+	Timeouts []SagaTimeout `json:"timeouts"`
+	// The methods that are called if the instance fails, in the order they were
+	// added. They're called in reverse, and the ones that are left after it's
+	// failed are tried again whenever timeouts are fired
+	Compensations []string `json:"compensations"`
+	// The latest steps of the instance
+	History []SagaStep `json:"history"`
+	// The IDs of the latest events the instance has handled
+	Processed []string `json:"processed"`
+}
+
+type SagaTimeout struct {
+	Name string    `json:"name"`
+	Due  time.Time `json:"due"`
+}
+
+// SagaStep describes something that an instance of a saga handled
+type SagaStep struct {
+	Time time.Time `json:"time"`
+	// Either "event" or "timeout"
+	Trigger string `json:"trigger"`
+	// The subject of the event or the name of the timeout
+	Name string `json:"name"`
+	// The ID of the event
+	ID string `json:"id,omitempty"`
+	// The status of the instance after the step
+	Status string `json:"status"`
+}
+
+func (s Saga) ClassName() string {
+	return s.Name
+}
+func (s Saga) Fields() map[string]Class {
+	return s.fields
+}
+func (s Saga) Constructors() ConstructorMap {
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(s, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddGenericConstructor(s, func(data map[string]ValueObject) (ValueObject, error) {
+		obj := SagaObject{s, data, &SagaInstance{}}
+		for key, class := range s.fields {
+			if nilableClass, ok := class.(NilableObject); ok {
+				if _, isNilable := data[key].(NilableObject); !isNilable {
+					nilableClass.Object = data[key]
+					obj.fields[key] = nilableClass
+				}
+			}
+		}
+		return obj, nil
+	})
+	return csMap
+}
+func (s Saga) Get(key string) Object {
+	return nil
+}
+
+// Returns the methods available on an instance so they can be validated
+// without a value
+func (s Saga) InstanceGet(key string) Object {
+	for _, method := range sagaInstanceMethods {
+		if key == method {
+			return SagaObject{ParentSaga: s}.Get(key)
+		}
+	}
+	return nil
+}
+
+// Returns the name the saga is known by outside of its context
+func (s Saga) qualifiedName() string {
+	return s.ctx.Name + "." + s.Name
+}
+
+func (s Saga) ObjectClassFromNode(ctx *Context, node nodes.ContextObject) (Class, error) {
+	s.Name = node.Name
+	s.Private = node.Private
+	s.Comment = node.Comment
+	s.collection = ctx.Name + "." + node.Name
+	s.ctx = ctx
+	s.state = &sagaState{}
+
+	s.fields = make(map[string]Class)
+	s.defaults = make(map[string]ValueObject)
+	s.starters = make(map[string]sagaHandler)
+	s.handlers = make(map[string]sagaHandler)
+	s.methods = make(map[string]sagaHandler)
+
+	if node.Extends != nil {
+		return nil, NodeError(node, "saga %s cannot extend %s", node.Name, node.Extends.Members[len(node.Extends.Members)-1])
+	}
+	symbols := ctx.Symbols()
+	for _, item := range node.Fields {
+		typeExpr, ok := item.Init.(nodes.TypeStatement)
+		if !ok {
+			return nil, fmt.Errorf("expected type statement")
+		}
+		for _, method := range sagaInstanceMethods {
+			if typeExpr.Name == method {
+				return nil, NodeError(item, "%s cannot have a field named %s", node.Name, method)
+			}
+		}
+		class, err := ctx.EvaluateTypeExpression(typeExpr.Init)
+		if err != nil {
+			return nil, err
+		}
+		s.fields[typeExpr.Name] = class
+		if s.Identity == "" {
+			if _, ok := class.(NilableObject); ok {
+				return nil, NodeError(item, "correlation key %s of %s cannot be optional", typeExpr.Name, node.Name)
+			}
+			s.Identity = typeExpr.Name
+			continue
+		}
+		if typeExpr.Default == nil {
+			if _, ok := class.(NilableObject); !ok {
+				return nil, NodeError(item, "%s needs a default since %s starts out with only its correlation key", typeExpr.Name, node.Name)
+			}
+			continue
+		}
+		defaultClass, err := symbols.ValidateExpression(*typeExpr.Default)
+		if err != nil {
+			return nil, err
+		}
+		if err := ShouldConvert(class, defaultClass); err != nil {
+			return nil, NodeError(typeExpr, "invalid default for %s: %s", typeExpr.Name, err.Error())
+		}
+		value, err := symbols.ResolveValueObject(*typeExpr.Default)
+		if err != nil {
+			return nil, err
+		}
+		value, err = Convert(class, value)
+		if err != nil {
+			return nil, NodeError(typeExpr, "invalid default for %s: %s", typeExpr.Name, err.Error())
+		}
+		s.defaults[typeExpr.Name] = value
+	}
+	if s.Identity == "" {
+		return nil, NodeError(node, "%s needs a field to correlate it by", node.Name)
+	}
+
+	return s, nil
+}
+
+// Adds a handler to the saga. Handlers named start begin an instance, ones
+// named on react to events of an instance that's running and the one named
+// timeout is called with the name of a timeout once it's due. Any other
+// method can be used as a compensation
+func (s Saga) AddMethod(ctx *Context, node nodes.ContextObjectMethod) error {
+	if _, ok := s.fields[node.Name]; ok {
+		return NodeError(node, "%s already has a field named %s", s.Name, node.Name)
+	}
+	for _, method := range sagaInstanceMethods {
+		if node.Name == method && node.Name != "timeout" {
+			return NodeError(node, "%s cannot have a method named %s", s.Name, node.Name)
+		}
+	}
+	table := ctx.Symbols().WithMethod(s.Name + "." + node.Name)
+	table.immutable["self"] = s
+	fn, err := table.ResolveFunctionBlock(node.Block, nil)
+	if err != nil {
+		return err
+	}
+	if fn.returns != nil {
+		return NodeError(node, "%s cannot return a value", node.Name)
+	}
+	handler := sagaHandler{node: node.Block, arguments: fn.arguments}
+
+	switch node.Name {
+	case "start", "on":
+		if len(fn.arguments) != 1 {
+			return NodeError(node, "%s should have one argument for the event, got %d", node.Name, len(fn.arguments))
+		}
+		event, ok := fn.arguments[0].(Event)
+		if !ok {
+			return NodeError(node, "%s can only handle events, got %s", s.Name, fn.arguments[0].ClassName())
+		}
+		keyClass, ok := event.fields[s.Identity]
+		if !ok {
+			return NodeError(node, "%s has no field %s to correlate %s by", event.Name, s.Identity, s.Name)
+		}
+		if err := ShouldConvert(s.fields[s.Identity], keyClass); err != nil {
+			return NodeError(node, "cannot correlate %s by %s.%s: %s", s.Name, event.Name, s.Identity, err.Error())
+		}
+		_, starts := s.starters[event.subject]
+		_, handles := s.handlers[event.subject]
+		if starts || handles {
+			return NodeError(node, "%s already handles %s", s.Name, event.Name)
+		}
+		handler.event = event
+		if node.Name == "start" {
+			s.starters[event.subject] = handler
+		} else {
+			s.handlers[event.subject] = handler
+		}
+	case "timeout":
+		if len(fn.arguments) != 1 {
+			return NodeError(node, "timeout should have one argument for the name of the timeout, got %d", len(fn.arguments))
+		}
+		if err := ShouldConvert(String{}, fn.arguments[0]); err != nil {
+			return NodeError(node, "timeout should be called with a String, got %s", fn.arguments[0].ClassName())
+		}
+		fallthrough
+	default:
+		if _, ok := s.methods[node.Name]; ok {
+			return NodeError(node, "%s already has a method named %s", s.Name, node.Name)
+		}
+		s.methods[node.Name] = handler
+	}
+	return nil
+}
+
+// Connects the saga to the storage driver of the build context and subscribes
+// to the events it handles
+func (s Saga) Attach(ctx Context) error {
+	var driver resource.StorageDriver
+	if err := ctx.Resource(StorageResource, &driver); err != nil {
+		return fmt.Errorf("saga %s: %s", s.Name, err.Error())
+	}
+	s.state.driver = driver
+
+	subjects := make([]string, 0, len(s.starters)+len(s.handlers))
+	for subject := range s.starters {
+		subjects = append(subjects, subject)
+	}
+	for subject := range s.handlers {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	for _, subject := range subjects {
+		sub, err := ctx.buildCtx.EventBus().Subscribe(subject, s.qualifiedName(), s.handle)
+		if err != nil {
+			s.Detach()
+			return fmt.Errorf("saga %s: %s", s.Name, err.Error())
+		}
+		s.state.subs = append(s.state.subs, sub)
+	}
+	return nil
+}
+func (s Saga) Detach() error {
+	errs := []error{}
+	for _, sub := range s.state.subs {
+		if err := sub.Unsubscribe(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.state.subs = nil
+	s.state.driver = nil
+	return errors.Join(errs...)
+}
+
+// Returns the key an instance is stored with, which is its correlation key as
+// JSON
+func (s Saga) key(identity ValueObject) (string, error) {
+	identity, err := Convert(s.fields[s.Identity], identity)
+	if err != nil {
+		return "", err
+	}
+	bytes, err := json.Marshal(ToInterface(identity))
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// Returns the stored instance with a key, if there is one
+func (s Saga) read(driver resource.StorageDriver, key string) (*SagaInstance, error) {
+	doc, err := driver.Get(s.collection, key)
+	if errors.Is(err, resource.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	bytes, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var instance SagaInstance
+	if err := json.Unmarshal(bytes, &instance); err != nil {
+		return nil, fmt.Errorf("cannot read %s %s: %s", s.Name, key, err.Error())
+	}
+	return &instance, nil
+}
+
+// Returns the document an instance is stored as
+func (s Saga) document(instance *SagaInstance) (resource.Document, error) {
+	bytes, err := json.Marshal(instance)
+	if err != nil {
+		return nil, err
+	}
+	var doc resource.Document
+	if err := json.Unmarshal(bytes, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Returns the object that handlers of an instance are called on
+func (s Saga) object(identity ValueObject, instance *SagaInstance) (SagaObject, error) {
+	if instance.State == nil {
+		data := map[string]ValueObject{s.Identity: identity}
+		for key, value := range s.defaults {
+			data[key] = value
+		}
+		obj, err := Construct(s, GenericObject{data: data})
+		if err != nil {
+			return SagaObject{}, err
+		}
+		return SagaObject{s, obj.(SagaObject).fields, instance}, nil
+	}
+	obj, err := FromBytes(instance.State, s)
+	if err != nil {
+		return SagaObject{}, fmt.Errorf("cannot read %s %s: %s", s.Name, instance.Key, err.Error())
+	}
+	return SagaObject{s, obj.(SagaObject).fields, instance}, nil
+}
+
+// Handles an event that's published for the saga
+func (s Saga) handle(msg resource.Message) error {
+	if handler, ok := s.starters[msg.Subject]; ok {
+		return s.handleEvent(handler, msg, true)
+	}
+	if handler, ok := s.handlers[msg.Subject]; ok {
+		return s.handleEvent(handler, msg, false)
+	}
+	return nil
+}
+
+func (s Saga) handleEvent(handler sagaHandler, msg resource.Message, starts bool) error {
+	ev, envelope, err := handler.event.Decode(msg)
+	if err != nil {
+		return fmt.Errorf("%s: %s", s.qualifiedName(), err.Error())
+	}
+	identity, ok := ev.Get(s.Identity).(ValueObject)
+	if !ok {
+		return fmt.Errorf("%s: %s has no %s", s.qualifiedName(), handler.event.Name, s.Identity)
+	}
+	key, err := s.key(identity)
+	if err != nil {
+		return fmt.Errorf("%s: %s", s.qualifiedName(), err.Error())
+	}
+	step := SagaStep{Trigger: "event", Name: msg.Subject, ID: envelope.ID}
+	err = s.step(key, identity, step, starts, func(table SymbolTable, obj SagaObject) error {
+		_, err := table.WithMethod(s.Name+".on").callFunctionBlock(handler.node, nil, []ValueObject{ev}, obj)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %s", s.qualifiedName(), err.Error())
+	}
+	return nil
+}
+
+// Changes an instance in a unit of work, so that the commands its handler
+// dispatches are only kept along with the state of the instance. If the
+// handler fails the instance fails with the state it had before the step, and
+// its compensations are called to undo what it's done
+func (s Saga) step(key string, identity ValueObject, step SagaStep, starts bool, call func(SymbolTable, SagaObject) error) error {
+	var publish []func()
+	s.state.mu.Lock()
+	defer func() {
+		s.state.mu.Unlock()
+		s.publish(publish)
+	}()
+	driver := s.state.driver
+	if driver == nil {
+		return fmt.Errorf("saga %s is not attached to storage", s.Name)
+	}
+	instance, err := s.read(driver, key)
+	if err != nil {
+		return err
+	}
+	if instance == nil {
+		// events for an instance that hasn't started are only handled if they
+		// start one
+		if !starts {
+			return nil
+		}
+		instance = &SagaInstance{Saga: s.qualifiedName(), Key: key, Status: SagaRunning}
+	} else if starts || instance.Status != SagaRunning {
+		return nil
+	}
+	if step.ID != "" && instance.processed(step.ID) {
+		return nil
+	}
+	obj, err := s.object(identity, instance)
+	if err != nil {
+		return err
+	}
+
+	buildCtx := s.ctx.buildCtx
+	table := s.ctx.Symbols().WithMethod(s.Name)
+	err = s.evaluate(table, &publish, func(table SymbolTable) error {
+		if err := call(table, obj); err != nil {
+			return err
+		}
+		return s.save(table, obj, step)
+	})
+	if errors.Is(err, errSagaTimeoutCancelled) {
+		return err
+	} else if err != nil {
+		buildCtx.Logger().Error(
+			fmt.Sprintf("%s %s failed: %s", s.qualifiedName(), key, err.Error()),
+			"trigger", step.Trigger,
+			"name", step.Name,
+		)
+		reason := err.Error()
+		instance, err = s.read(driver, key)
+		if err != nil {
+			return err
+		}
+		if instance == nil {
+			instance = &SagaInstance{Saga: s.qualifiedName(), Key: key, Status: SagaRunning}
+		}
+		instance.fail(reason)
+		obj, err = s.object(identity, instance)
+		if err != nil {
+			return err
+		}
+		err = s.evaluate(table, &publish, func(table SymbolTable) error {
+			return s.save(table, obj, step)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.compensate(obj, &publish)
+	return nil
+}
+
+// Evaluates something in a unit of work of its own. The events it emits are
+// only published once the instance is unlocked, since one of them could be
+// handled by the saga again before publishing returns
+func (s Saga) evaluate(table SymbolTable, publish *[]func(), eval func(SymbolTable) error) error {
+	_, fn, err := s.ctx.buildCtx.evaluateInWorkUnpublished(table, func(table SymbolTable) (ValueObject, error) {
+		return nil, eval(table)
+	})
+	if err != nil {
+		return err
+	}
+	*publish = append(*publish, fn)
+	return nil
+}
+func (s Saga) publish(publish []func()) {
+	for _, fn := range publish {
+		fn()
+	}
+}
+
+// Stores an instance after a step, in the unit of work of the step
+func (s Saga) save(table SymbolTable, obj SagaObject, step SagaStep) error {
+	instance := obj.instance
+	// a finished instance has nothing left to wait for, and one that's completed
+	// has nothing left to undo
+	if instance.Status != SagaRunning {
+		instance.Timeouts = []SagaTimeout{}
+	}
+	if instance.Status == SagaCompleted {
+		instance.Compensations = []string{}
+	}
+	step.Time = s.ctx.buildCtx.Now()
+	step.Status = instance.Status
+	instance.History = latest(append(instance.History, step), sagaHistorySize)
+	if step.ID != "" {
+		instance.Processed = latest(append(instance.Processed, step.ID), sagaProcessedSize)
+	}
+	return s.store(table, obj)
+}
+
+// Stores the state of an instance in the unit of work of a symbol table
+func (s Saga) store(table SymbolTable, obj SagaObject) error {
+	state, err := json.Marshal(ToInterface(obj))
+	if err != nil {
+		return err
+	}
+	obj.instance.State = state
+	doc, err := s.document(obj.instance)
+	if err != nil {
+		return err
+	}
+	return table.frame.Work.Storage(s.state.driver).Put(s.collection, obj.instance.Key, doc)
+}
+
+// Returns the last of a list of values, up to a limit
+func latest[T any](values []T, limit int) []T {
+	if len(values) <= limit {
+		return values
+	}
+	return append([]T{}, values[len(values)-limit:]...)
+}
+
+// Calls the compensations of an instance that's failed, starting with the one
+// that was added last. Each is called in a unit of work of its own that
+// removes it from the instance, so that if one fails it's left to be tried
+// again along with the ones before it
+func (s Saga) compensate(obj SagaObject, publish *[]func()) {
+	instance := obj.instance
+	buildCtx := s.ctx.buildCtx
+	for instance.Status == SagaFailed && len(instance.Compensations) > 0 {
+		compensations := instance.Compensations
+		name := compensations[len(compensations)-1]
+		table := s.ctx.Symbols().WithMethod(s.Name + "." + name)
+		err := s.evaluate(table, publish, func(table SymbolTable) error {
+			method, ok := s.methods[name]
+			if !ok {
+				return fmt.Errorf("%s has no method %s to compensate with", s.Name, name)
+			}
+			if _, err := table.callFunctionBlock(method.node, nil, nil, obj); err != nil {
+				return err
+			}
+			instance.Compensations = compensations[:len(compensations)-1]
+			return s.store(table, obj)
+		})
+		if err != nil {
+			instance.Compensations = compensations
+			buildCtx.Logger().Warn(
+				fmt.Sprintf("%s %s cannot compensate with %s, trying again later: %s", s.qualifiedName(), instance.Key, name, err.Error()),
+			)
+			return
+		}
+	}
+}
+
+// Calls the compensations that are left of every instance that's failed
+func (s Saga) retryCompensations() error {
+	instances, err := s.instances()
+	if err != nil {
+		return err
+	}
+	for _, stored := range instances {
+		if stored.Status != SagaFailed || len(stored.Compensations) == 0 {
+			continue
+		}
+		if err := s.retryCompensation(stored.Key); err != nil {
+			return fmt.Errorf("%s %s: %s", s.qualifiedName(), stored.Key, err.Error())
+		}
+	}
+	return nil
+}
+func (s Saga) retryCompensation(key string) error {
+	var publish []func()
+	s.state.mu.Lock()
+	defer func() {
+		s.state.mu.Unlock()
+		s.publish(publish)
+	}()
+	instance, err := s.read(s.state.driver, key)
+	if err != nil || instance == nil {
+		return err
+	}
+	identity, err := FromBytes([]byte(key), s.fields[s.Identity])
+	if err != nil {
+		return err
+	}
+	obj, err := s.object(identity, instance)
+	if err != nil {
+		return err
+	}
+	s.compensate(obj, &publish)
+	return nil
+}
+
+// Returns every stored instance of the saga, ordered by key
+func (s Saga) instances() ([]SagaInstance, error) {
+	driver := s.state.driver
+	if driver == nil {
+		return nil, fmt.Errorf("saga %s is not attached to storage", s.Name)
+	}
+	records, err := driver.Find(s.collection, resource.Document{})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Key < records[j].Key
+	})
+	out := make([]SagaInstance, 0, len(records))
+	for _, record := range records {
+		instance, err := s.read(driver, record.Key)
+		if err != nil {
+			return nil, err
+		}
+		if instance != nil {
+			out = append(out, *instance)
+		}
+	}
+	return out, nil
+}
+
+// Fires every timeout that's due according to the clock of the build context,
+// in the order they're due
+func (s Saga) fireTimeouts() error {
+	now := s.ctx.buildCtx.Now()
+	instances, err := s.instances()
+	if err != nil {
+		return err
+	}
+	type due struct {
+		key     string
+		timeout SagaTimeout
+	}
+	pending := []due{}
+	for _, instance := range instances {
+		if instance.Status != SagaRunning {
+			continue
+		}
+		for _, timeout := range instance.Timeouts {
+			if !timeout.Due.After(now) {
+				pending = append(pending, due{instance.Key, timeout})
+			}
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].timeout.Due.Before(pending[j].timeout.Due)
+	})
+	errs := []error{}
+	for _, item := range pending {
+		identity, err := FromBytes([]byte(item.key), s.fields[s.Identity])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		step := SagaStep{Trigger: "timeout", Name: item.timeout.Name}
+		err = s.step(item.key, identity, step, false, func(table SymbolTable, obj SagaObject) error {
+			// the timeout could've been cancelled by a timeout that fired before it
+			if !obj.instance.removeTimeout(item.timeout.Name) {
+				return errSagaTimeoutCancelled
+			}
+			handler, ok := s.methods["timeout"]
+			if !ok {
+				obj.instance.fail(item.timeout.Name + " timed out")
+				return nil
+			}
+			_, err := table.WithMethod(s.Name+".timeout").callFunctionBlock(handler.node, nil, []ValueObject{StringLiteral(item.timeout.Name)}, obj)
+			return err
+		})
+		if err != nil && !errors.Is(err, errSagaTimeoutCancelled) {
+			errs = append(errs, fmt.Errorf("%s %s: %s", s.qualifiedName(), item.key, err.Error()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var errSagaTimeoutCancelled = errors.New("timeout was cancelled")
+
+// Returns every instance of a saga of the context, ordered by key
+func (ctx *Context) Sagas(name string) ([]SagaInstance, error) {
+	s, ok := ctx.objects[name].(Saga)
+	if !ok {
+		return nil, fmt.Errorf("%s has no saga %s", ctx.Name, name)
+	}
+	return s.instances()
+}
+
+// Fires the timeouts of every saga of the context that are due according to
+// the clock of the build context and tries the compensations of failed
+// instances again, which the scheduler does whenever it runs the jobs that are
+// due
+func (ctx *Context) FireTimeouts() error {
+	names := []string{}
+	for name, obj := range ctx.objects {
+		if _, ok := obj.(Saga); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	errs := []error{}
+	for _, name := range names {
+		s := ctx.objects[name].(Saga)
+		if err := s.fireTimeouts(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := s.retryCompensations(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (instance *SagaInstance) processed(id string) bool {
+	for _, processed := range instance.Processed {
+		if processed == id {
+			return true
+		}
+	}
+	return false
+}
+func (instance *SagaInstance) removeTimeout(name string) bool {
+	for idx, timeout := range instance.Timeouts {
+		if timeout.Name == name {
+			instance.Timeouts = append(instance.Timeouts[:idx], instance.Timeouts[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+func (instance *SagaInstance) fail(reason string) {
+	if instance.Status == SagaRunning {
+		instance.Status = SagaFailed
+		instance.Reason = reason
+	}
+}
+
+// SagaObject represents an instance of a Saga
+type SagaObject struct {
+	ParentSaga Saga
+	fields     map[string]ValueObject
+	instance   *SagaInstance
+}
+
+// Returns the value of the field the instance is correlated by
+func (so SagaObject) Identity() ValueObject {
+	return so.fields[so.ParentSaga.Identity]
+}
+
+func (so SagaObject) Class() Class {
+	return so.ParentSaga
+}
+func (so SagaObject) Value() interface{} {
+	out := make(map[string]interface{})
+	for key, obj := range so.fields {
+		out[key] = obj.Value()
+	}
+	return out
+}
+func (so SagaObject) Set(key string, obj ValueObject) error {
+	so.fields[key] = obj
+	return nil
+}
+func (so SagaObject) Get(key string) Object {
+	s := so.ParentSaga
+	switch key {
+	// fires the timeout method with a name once a duration has passed, replacing
+	// a timeout with the same name
+	case "timeout":
+		return NewFunction(FunctionOptions{
+			Arguments: []Class{String{}, Duration{}},
+			Mutates:   true,
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				name := string(args[0].(StringLiteral))
+				due := s.ctx.buildCtx.Now().Add(time.Duration(args[1].(DurationLiteral)))
+				so.instance.removeTimeout(name)
+				so.instance.Timeouts = append(so.instance.Timeouts, SagaTimeout{Name: name, Due: due})
+				sort.SliceStable(so.instance.Timeouts, func(i, j int) bool {
+					return so.instance.Timeouts[i].Due.Before(so.instance.Timeouts[j].Due)
+				})
+				return nil, nil
+			},
+		})
+	case "cancelTimeout":
+		return NewFunction(FunctionOptions{
+			Arguments: []Class{String{}},
+			Mutates:   true,
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				so.instance.removeTimeout(string(args[0].(StringLiteral)))
+				return nil, nil
+			},
+		})
+	// adds a method that's called to undo what the instance has done if it fails
+	case "compensate":
+		return NewFunction(FunctionOptions{
+			Arguments: []Class{String{}},
+			Mutates:   true,
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				name := string(args[0].(StringLiteral))
+				method, ok := s.methods[name]
+				if !ok {
+					return nil, fmt.Errorf("%s has no method %s to compensate with", s.Name, name)
+				}
+				if len(method.arguments) != 0 {
+					return nil, fmt.Errorf("cannot compensate with %s since it has arguments", name)
+				}
+				so.instance.Compensations = append(so.instance.Compensations, name)
+				return nil, nil
+			},
+		})
+	// finishes the instance, so it doesn't handle anything else
+	case "complete":
+		return NewFunction(FunctionOptions{
+			Mutates: true,
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				if so.instance.Status == SagaRunning {
+					so.instance.Status = SagaCompleted
+				}
+				return nil, nil
+			},
+		})
+	// finishes the instance, calling its compensations once the handler is done
+	case "fail":
+		return NewFunction(FunctionOptions{
+			Arguments: []Class{String{}},
+			Mutates:   true,
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				so.instance.fail(string(args[0].(StringLiteral)))
+				return nil, nil
+			},
+		})
+	}
+	return so.fields[key]
+}
@@ -0,0 +1,125 @@
+package build
+
+import (
+	"testing"
+	"time"
+)
+
+const checkoutSource = `context acme.checkout {
+	event OrderPlaced {
+		order_id String
+	}
+	event PaymentTaken {
+		order_id String
+	}
+	event PaymentFailed {
+		order_id String
+		reason String
+	}
+	event Refunded {
+		order_id String
+	}
+
+	command Place(order_id: String) {
+		emit(OrderPlaced{ order_id: order_id })
+	}
+	command Charge(order_id: String) {
+		if (order_id != "stuck") {
+			emit(PaymentTaken{ order_id: order_id })
+		}
+	}
+	command Decline(order_id: String) {
+		emit(PaymentFailed{ order_id: order_id, reason: "declined" })
+	}
+	command Refund(order_id: String) {
+		emit(Refunded{ order_id: order_id })
+	}
+
+	saga Checkout {
+		order_id String
+		status String = "new"
+		refunds Int = 0
+	}
+	func (Checkout) start(e: OrderPlaced) {
+		self.status = "charging"
+		self.compensate("refund")
+		self.timeout("payment", Duration("PT10M"))
+		Charge(e.order_id)
+	}
+	func (Checkout) on(e: PaymentTaken) {
+		self.status = "paid"
+		self.cancelTimeout("payment")
+		self.complete()
+	}
+	func (Checkout) refund() {
+		self.refunds = self.refunds + 1
+		Refund(self.order_id)
+	}
+}`
+
+func checkout(t *testing.T, ctx *Context, key string) SagaInstance {
+	t.Helper()
+	instances, err := ctx.Sagas("Checkout")
+	if err != nil {
+		t.Fatalf("cannot list instances: %s", err)
+	}
+	for _, instance := range instances {
+		if instance.Key == key {
+			return instance
+		}
+	}
+	t.Fatalf("no instance %s", key)
+	return SagaInstance{}
+}
+
+// CAN HANDLE AN EVENT EMITTED BY A COMMAND THE SAGA DISPATCHED
+func TestSagaHandlesEventsOfItsCommands(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, checkoutSource)
+
+	var err error
+	withTimeout(t, func() {
+		_, err = ctx.Invoke("Place", []byte(`{"order_id": "o1"}`))
+	})
+	if err != nil {
+		t.Fatalf("cannot place order: %s", err)
+	}
+	instance := checkout(t, ctx, `"o1"`)
+	if instance.Status != SagaCompleted {
+		t.Errorf("expected %s, got %s (%s)", SagaCompleted, instance.Status, instance.Reason)
+	}
+	if len(instance.Timeouts) != 0 || len(instance.Compensations) != 0 {
+		t.Errorf("expected nothing left to do, got %+v", instance)
+	}
+	if len(instance.History) != 2 || instance.History[0].Name != "acme.checkout.OrderPlaced" {
+		t.Errorf("unexpected history %+v", instance.History)
+	}
+}
+
+// CAN FAIL AN INSTANCE ONCE A TIMEOUT IS DUE AND CALL ITS COMPENSATIONS
+func TestSagaTimeout(t *testing.T) {
+	buildCtx, clock := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, checkoutSource)
+
+	invoke(t, ctx, "Place", `{"order_id": "stuck"}`)
+	clock.Advance(5 * time.Minute)
+	if err := ctx.FireTimeouts(); err != nil {
+		t.Fatal(err)
+	}
+	if instance := checkout(t, ctx, `"stuck"`); instance.Status != SagaRunning {
+		t.Fatalf("expected %s before the timeout is due, got %s", SagaRunning, instance.Status)
+	}
+	clock.Advance(5 * time.Minute)
+	withTimeout(t, func() {
+		if err := ctx.FireTimeouts(); err != nil {
+			t.Error(err)
+		}
+	})
+	instance := checkout(t, ctx, `"stuck"`)
+	if instance.Status != SagaFailed || instance.Reason != "payment timed out" {
+		t.Errorf("expected instance to fail, got %s (%s)", instance.Status, instance.Reason)
+	}
+	if len(instance.Compensations) != 0 {
+		t.Errorf("expected compensations to be called, got %v", instance.Compensations)
+	}
+}
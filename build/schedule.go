@@ -0,0 +1,95 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/hntrl/lang/language/nodes"
+)
+
+// Schedule represents a method that's run whenever its cron expression matches
+// the clock of the build context, which makes its changes in a unit of work
+// the same as a command
+// i.e. schedule("0 3 * * *") NightlyCleanup() { ... }
+type Schedule struct {
+	Name    string
+	Private bool
+	Comment string
+	// The cron expression of when the schedule runs
+	Cron string
+	cron cronSchedule
+	node nodes.FunctionBlock
+	ctx  *Context
+}
+
+func (s Schedule) ClassName() string {
+	return s.Name
+}
+func (s Schedule) Constructors() ConstructorMap {
+	return NewConstructorMap()
+}
+func (s Schedule) Get(key string) Object {
+	return nil
+}
+
+// Returns the name the schedule is known by outside of its context
+func (s Schedule) qualifiedName() string {
+	return s.ctx.Name + "." + s.Name
+}
+
+func (s Schedule) MethodClassFromNode(ctx *Context, node nodes.ContextMethod) (Class, error) {
+	s.Name = node.Name
+	s.Private = node.Private
+	s.Comment = node.Comment
+	s.node = node.Block
+	s.ctx = ctx
+
+	if len(node.Options) != 1 {
+		return nil, NodeError(node, "%s should have a cron expression", node.Name)
+	}
+	table := ctx.Symbols().WithMethod(node.Name)
+	class, err := table.ValidateExpression(node.Options[0])
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := class.(String); !ok {
+		return nil, NodeError(node, "cron expression of %s should be a String, got %s", node.Name, class.ClassName())
+	}
+	expr, err := table.ResolveValueObject(node.Options[0])
+	if err != nil {
+		return nil, err
+	}
+	s.Cron = string(expr.(StringLiteral))
+	s.cron, err = parseCron(s.Cron)
+	if err != nil {
+		return nil, NodeError(node, "%s", err)
+	}
+
+	fn, err := table.ResolveFunctionBlock(node.Block, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(fn.arguments) != 0 {
+		return nil, NodeError(node, "%s cannot have arguments", node.Name)
+	}
+	if fn.returns != nil {
+		return nil, NodeError(node, "%s cannot return a value", node.Name)
+	}
+	return s, nil
+}
+
+// Keeps the job of the schedule with the scheduler of the build context
+func (s Schedule) Attach(ctx Context) error {
+	if err := ctx.buildCtx.scheduler.schedule(s.qualifiedName(), s.cron, s.Cron); err != nil {
+		return fmt.Errorf("schedule %s: %s", s.Name, err.Error())
+	}
+	return nil
+}
+func (s Schedule) Detach() error {
+	return nil
+}
+
+func (s Schedule) run(work *UnitOfWork) error {
+	table := s.ctx.Symbols().WithMethod(s.Name).WithWork(work)
+	_, err := table.callFunctionBlock(s.node, nil, nil, nil)
+	return err
+}
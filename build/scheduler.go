@@ -0,0 +1,445 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hntrl/lang/resource"
+)
+
+// The collection jobs are kept in until they're run
+const JobCollection = "jobs"
+
+// How often the scheduler looks for jobs that are due when the build context
+// uses the clock of the host. A manual clock only runs jobs when RunDue is
+// called
+const schedulerInterval = time.Second
+
+// Job is something the scheduler runs once it's due
+type Job struct {
+	ID string `json:"id"`
+	// The schedule, command or subscriber that's run, qualified by the name of
+	// its context
+	Target string `json:"target"`
+	// The arguments a command is invoked with as a JSON object, or the message
+	// a subscriber is called with again
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Due     time.Time       `json:"due"`
+	// The cron expression of a job that repeats
+	Cron string `json:"cron,omitempty"`
+	// How many times the job has failed since it last ran
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// Scheduler runs jobs once they're due according to the clock of the build
+// context. Jobs are kept in the storage resource so they survive restarts, and
+// a job that fails is tried again as the delivery policy allows. Without a
+// storage resource only the retries of subscribers can be scheduled, which are
+// kept in memory instead
+type Scheduler struct {
+	buildCtx *BuildContext
+	driver   resource.StorageDriver
+	memory   resource.StorageDriver
+	// the number of contexts that are attached, the scheduler is connected
+	// while there's at least one
+	contexts int
+	stop     chan struct{}
+	// held while jobs are run so that a job isn't run twice at once
+	mu sync.Mutex
+}
+
+// Returns the scheduler of the build context
+func (ctx *BuildContext) Scheduler() *Scheduler {
+	return ctx.scheduler
+}
+
+func newScheduler(buildCtx *BuildContext) *Scheduler {
+	return &Scheduler{buildCtx: buildCtx, memory: resource.NewMemoryStore()}
+}
+
+// Connects the scheduler to the storage driver of the build context if there
+// is one when the first context is attached, and removes the jobs of
+// schedules the context doesn't have anymore
+func (s *Scheduler) Attach(ctx Context) error {
+	if s.contexts == 0 {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+	s.contexts++
+	if s.driver == nil {
+		return nil
+	}
+	if err := s.prune(ctx); err != nil {
+		s.Detach()
+		return err
+	}
+	return nil
+}
+func (s *Scheduler) connect(ctx Context) error {
+	if ctx.buildCtx.resources[StorageResource] != nil {
+		var driver resource.StorageDriver
+		if err := ctx.Resource(StorageResource, &driver); err != nil {
+			return fmt.Errorf("scheduler: %s", err.Error())
+		}
+		s.driver = driver
+	}
+
+	// with a manual clock jobs only run when they're asked to, so that they
+	// happen at the same point every time
+	if _, ok := s.buildCtx.clock.(*ManualClock); !ok {
+		stop := make(chan struct{})
+		s.stop = stop
+		go func() {
+			ticker := time.NewTicker(schedulerInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					if err := s.RunDue(); err != nil {
+						s.buildCtx.Logger().Error(fmt.Sprintf("scheduler: %s", err.Error()))
+					}
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// Disconnects the scheduler once every context that was attached is detached
+func (s *Scheduler) Detach() error {
+	if s.contexts > 1 {
+		s.contexts--
+		return nil
+	}
+	s.contexts = 0
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	s.driver = nil
+	return nil
+}
+
+// Removes the jobs of the schedules of a context that have been removed or
+// renamed since they were kept
+func (s *Scheduler) prune(ctx Context) error {
+	jobs, err := s.Jobs()
+	if err != nil {
+		return err
+	}
+	prefix := "schedule:" + ctx.Name + "."
+	for _, job := range jobs {
+		name, ok := strings.CutPrefix(job.ID, prefix)
+		if !ok || strings.Contains(name, ".") {
+			continue
+		}
+		if _, ok := ctx.objects[name].(Schedule); ok {
+			continue
+		}
+		s.buildCtx.Logger().Warn(fmt.Sprintf("removing the job of %s, which isn't a schedule anymore", job.Target), "job", job.ID)
+		if err := s.driver.Delete(JobCollection, job.ID); err != nil && !errors.Is(err, resource.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) storage() (resource.StorageDriver, error) {
+	if s.driver == nil {
+		return nil, fmt.Errorf("scheduler is not attached to storage")
+	}
+	return s.driver, nil
+}
+
+// Returns the driver jobs are kept in, which is the memory of the scheduler if
+// there isn't a storage resource. Only retries are kept there, since they
+// don't need to survive restarts the way jobs that were asked for do
+func (s *Scheduler) jobStorage() resource.StorageDriver {
+	if s.driver == nil {
+		return s.memory
+	}
+	return s.driver
+}
+
+func jobDocument(job Job) (resource.Document, error) {
+	bytes, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	var doc resource.Document
+	if err := json.Unmarshal(bytes, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Returns every job that hasn't run yet, in the order they're due
+func (s *Scheduler) Jobs() ([]Job, error) {
+	records, err := s.jobStorage().Find(JobCollection, resource.Document{})
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]Job, 0, len(records))
+	for _, record := range records {
+		bytes, err := json.Marshal(record.Document)
+		if err != nil {
+			return nil, err
+		}
+		var job Job
+		if err := json.Unmarshal(bytes, &job); err != nil {
+			return nil, fmt.Errorf("cannot read job %s: %s", record.Key, err.Error())
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Due.Equal(jobs[j].Due) {
+			return jobs[i].ID < jobs[j].ID
+		}
+		return jobs[i].Due.Before(jobs[j].Due)
+	})
+	return jobs, nil
+}
+
+// Keeps the job of a schedule, unless it's already kept with the same cron
+// expression so that a run that was missed while stopped still happens
+func (s *Scheduler) schedule(target string, cron cronSchedule, expr string) error {
+	driver, err := s.storage()
+	if err != nil {
+		return err
+	}
+	id := "schedule:" + target
+	doc, err := driver.Get(JobCollection, id)
+	if err == nil && doc["cron"] == expr {
+		return nil
+	} else if err != nil && !errors.Is(err, resource.ErrNotFound) {
+		return err
+	}
+	job := Job{ID: id, Target: target, Due: cron.next(s.buildCtx.Now()), Cron: expr}
+	doc, err = jobDocument(job)
+	if err != nil {
+		return err
+	}
+	return driver.Put(JobCollection, id, doc)
+}
+
+// Keeps a job that invokes a command once a duration has passed, in the unit
+// of work of the call site if there is one so that it's only kept along with
+// the changes the call site makes
+func (s *Scheduler) later(site CallSite, d time.Duration, op Operation, payload ValueObject) (string, error) {
+	driver, err := s.storage()
+	if err != nil {
+		return "", err
+	}
+	payload, err = Convert(op.payload, payload)
+	if err != nil {
+		return "", fmt.Errorf("invalid payload for %s: %s", op.Name, err.Error())
+	}
+	data, err := json.Marshal(ToInterface(payload))
+	if err != nil {
+		return "", err
+	}
+	job := Job{
+		ID:      s.buildCtx.newEventID(),
+		Target:  op.qualifiedName(),
+		Payload: data,
+		Due:     s.buildCtx.Now().Add(d),
+	}
+	doc, err := jobDocument(job)
+	if err != nil {
+		return "", err
+	}
+	if site.Work != nil {
+		driver = site.Work.Storage(driver)
+	}
+	if err := driver.Insert(JobCollection, job.ID, doc); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// Keeps a job that calls a subscriber with a message again once a backoff has
+// passed, after it's failed once
+func (s *Scheduler) retry(sub Subscriber, msg resource.Message, backoff time.Duration) error {
+	job := Job{
+		ID:       s.buildCtx.newEventID(),
+		Target:   sub.qualifiedName(),
+		Payload:  json.RawMessage(msg.Data),
+		Due:      s.buildCtx.Now().Add(backoff),
+		Attempts: 1,
+	}
+	doc, err := jobDocument(job)
+	if err != nil {
+		return err
+	}
+	return s.jobStorage().Insert(JobCollection, job.ID, doc)
+}
+
+// Runs every job that's due according to the clock of the build context in the
+// order they're due, fires the timeouts of sagas that are due and removes the
+// results of idempotency keys that have expired
+func (s *Scheduler) RunDue() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs, err := s.Jobs()
+	if err != nil {
+		return err
+	}
+	now := s.buildCtx.Now()
+	errs := []error{}
+	for _, job := range jobs {
+		if job.Due.After(now) {
+			break
+		}
+		if err := s.run(job); err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %s", job.ID, err.Error()))
+		}
+	}
+	for _, ctx := range s.buildCtx.contexts() {
+		if err := ctx.FireTimeouts(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.driver != nil {
+		if err := s.buildCtx.purgeResults(s.driver); err != nil {
+			errs = append(errs, fmt.Errorf("cannot remove expired results: %s", err.Error()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Runs a job in a unit of work along with removing it, or moving it to when
+// it's next due if it repeats. A job that fails is tried again after a backoff
+// until there aren't any retries left
+func (s *Scheduler) run(job Job) error {
+	buildCtx := s.buildCtx
+	driver := s.jobStorage()
+	work := NewUnitOfWork(buildCtx)
+	var target Object
+	err := func() error {
+		var err error
+		target, err = buildCtx.lookup(job.Target)
+		if err != nil {
+			return err
+		}
+		switch target := target.(type) {
+		case Schedule:
+			return target.run(work)
+		case Operation:
+			args, err := target.decodePayload(job.Payload)
+			if err != nil {
+				return err
+			}
+			_, err = target.CallAt(CallSite{Frame: Frame{Work: work}}, args, nil)
+			return err
+		case Subscriber:
+			obj, _, err := target.event.Decode(target.message(job.Payload))
+			if err != nil {
+				return err
+			}
+			return target.run(work, obj)
+		}
+		return fmt.Errorf("cannot run %s", job.Target)
+	}()
+	if err == nil {
+		job.Attempts = 0
+		if err := s.reschedule(work.Storage(driver), job); err != nil {
+			work.Discard()
+			return err
+		}
+		return work.Commit()
+	}
+	work.Discard()
+
+	policy := buildCtx.delivery
+	job.Attempts++
+	if job.Attempts <= policy.Retries {
+		buildCtx.Logger().Warn(
+			fmt.Sprintf("%s failed, retrying: %s", job.Target, err.Error()),
+			"job", job.ID,
+			"attempt", job.Attempts,
+		)
+		job.Due = buildCtx.Now().Add(policy.backoff(job.Attempts))
+		doc, err := jobDocument(job)
+		if err != nil {
+			return err
+		}
+		return driver.Put(JobCollection, job.ID, doc)
+	}
+	attempts := job.Attempts
+	job.Attempts = 0
+	if rescheduleErr := s.reschedule(driver, job); rescheduleErr != nil {
+		return errors.Join(err, rescheduleErr)
+	}
+	// what a subscriber gives up on goes to its dead letter subject instead
+	if sub, ok := target.(Subscriber); ok {
+		return sub.deadLetter(sub.message(job.Payload), attempts, err)
+	}
+	buildCtx.Logger().Error(
+		fmt.Sprintf("%s gave up: %s", job.Target, err.Error()),
+		"job", job.ID,
+		"attempts", attempts,
+	)
+	return err
+}
+
+// Removes a job that's run, or moves one that repeats to when it's next due
+func (s *Scheduler) reschedule(driver resource.StorageDriver, job Job) error {
+	if job.Cron == "" {
+		err := driver.Delete(JobCollection, job.ID)
+		if errors.Is(err, resource.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	cron, err := parseCron(job.Cron)
+	if err != nil {
+		return err
+	}
+	job.Due = cron.next(s.buildCtx.Now())
+	doc, err := jobDocument(job)
+	if err != nil {
+		return err
+	}
+	return driver.Put(JobCollection, job.ID, doc)
+}
+
+// Returns every context that's been built in the build context, ordered by
+// name
+func (ctx *BuildContext) contexts() []*Context {
+	out := []*Context{}
+	seen := make(map[*Context]bool)
+	for _, c := range ctx.imports {
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Returns an object by the name it's known by outside of its context
+func (ctx *BuildContext) lookup(name string) (Object, error) {
+	idx := strings.LastIndex(name, ".")
+	if idx < 0 {
+		return nil, fmt.Errorf("unknown %s", name)
+	}
+	for _, c := range ctx.contexts() {
+		if c.Name == name[:idx] {
+			if obj, ok := c.objects[name[idx+1:]]; ok {
+				return obj, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown %s", name)
+}
@@ -0,0 +1,195 @@
+package build
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hntrl/lang/resource"
+)
+
+const remindersSource = `context acme.reminders {
+	entity Counter {
+		name String
+		runs Int
+	}
+
+	command Setup() {
+		Counter.create(Counter{ name: "nightly", runs: 0 })
+	}
+	schedule("0 3 * * *") Nightly() {
+		counter := Counter.findOne({ name: "nightly" })
+		counter.runs = counter.runs + 1
+		counter.save()
+	}
+	command Note(note: String) {
+		Counter.create(Counter{ name: note, runs: 1 })
+	}
+	command Remind(note: String) String {
+		return later(Duration("PT1H"), Note, { note: note })
+	}
+	query Runs(name: String) Int {
+		return Counter.findOne({ name: name }).runs
+	}
+}`
+
+// CAN RUN A SCHEDULE WHENEVER ITS CRON EXPRESSION MATCHES THE CLOCK
+func TestSchedule(t *testing.T) {
+	buildCtx, clock := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, remindersSource)
+	invoke(t, ctx, "Setup", `{}`)
+
+	jobs, err := buildCtx.Scheduler().Jobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Target != "acme.reminders.Nightly" || !jobs[0].Due.Equal(testEpoch.Add(3*time.Hour)) {
+		t.Fatalf("expected the schedule to be due at 3am, got %+v", jobs)
+	}
+	clock.Advance(2 * time.Hour)
+	if err := buildCtx.Scheduler().RunDue(); err != nil {
+		t.Fatal(err)
+	}
+	if out := invoke(t, ctx, "Runs", `{"name": "nightly"}`); out != "0" {
+		t.Errorf("expected the schedule to wait, got %s runs", out)
+	}
+	for i := 0; i < 2; i++ {
+		clock.Advance(time.Hour)
+		if err := buildCtx.Scheduler().RunDue(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out := invoke(t, ctx, "Runs", `{"name": "nightly"}`); out != "1" {
+		t.Errorf("expected the schedule to run once, got %s runs", out)
+	}
+	jobs, _ = buildCtx.Scheduler().Jobs()
+	if len(jobs) != 1 || !jobs[0].Due.Equal(testEpoch.Add(27*time.Hour)) {
+		t.Errorf("expected the schedule to be due again the next day, got %+v", jobs)
+	}
+	clock.Advance(24 * time.Hour)
+	buildCtx.Scheduler().RunDue()
+	if out := invoke(t, ctx, "Runs", `{"name": "nightly"}`); out != "2" {
+		t.Errorf("expected the schedule to run again, got %s runs", out)
+	}
+}
+
+// CAN INVOKE A COMMAND ONCE A DURATION HAS PASSED
+func TestLater(t *testing.T) {
+	buildCtx, clock := setupBuildContext(t)
+	ctx := setupContext(t, buildCtx, remindersSource)
+
+	id := strings.Trim(invoke(t, ctx, "Remind", `{"note": "call"}`), `"`)
+	jobs, err := buildCtx.Scheduler().Jobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job *Job
+	for idx := range jobs {
+		if jobs[idx].ID == id {
+			job = &jobs[idx]
+		}
+	}
+	if job == nil || job.Target != "acme.reminders.Note" || !job.Due.Equal(testEpoch.Add(time.Hour)) {
+		t.Fatalf("expected a job in an hour, got %+v", jobs)
+	}
+	clock.Advance(30 * time.Minute)
+	buildCtx.Scheduler().RunDue()
+	if _, err := ctx.Invoke("Runs", []byte(`{"name": "call"}`)); err == nil {
+		t.Errorf("expected the command to wait")
+	}
+	clock.Advance(30 * time.Minute)
+	if err := buildCtx.Scheduler().RunDue(); err != nil {
+		t.Fatal(err)
+	}
+	if out := invoke(t, ctx, "Runs", `{"name": "call"}`); out != "1" {
+		t.Errorf("expected the command to be invoked, got %s", out)
+	}
+	jobs, _ = buildCtx.Scheduler().Jobs()
+	for _, job := range jobs {
+		if job.ID == id {
+			t.Errorf("expected the job to be removed once it's run")
+		}
+	}
+}
+
+// CAN KEEP THE SCHEDULER CONNECTED UNTIL EVERY CONTEXT THAT SHARES IT IS
+// DETACHED
+func TestSchedulerIsShared(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	reminders, err := newContext(buildCtx, remindersSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newContext(buildCtx, `context acme.other {
+		command Ping() {}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ctx := range []*Context{reminders, other} {
+		if err := ctx.Attach(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other.Detach()
+	if buildCtx.Scheduler().driver == nil {
+		t.Fatalf("expected the scheduler to stay connected while a context is attached")
+	}
+	reminders.Detach()
+	if buildCtx.Scheduler().driver != nil {
+		t.Errorf("expected the scheduler to disconnect once every context is detached")
+	}
+}
+
+// unavailableJobs is a storage driver that can't keep jobs
+type unavailableJobs struct {
+	resource.MemoryStore
+}
+
+func (store unavailableJobs) Attach() (resource.Resource, error) {
+	return store, nil
+}
+func (store unavailableJobs) Put(collection, key string, doc resource.Document) error {
+	if collection == JobCollection {
+		return errors.New("storage is unavailable")
+	}
+	return store.MemoryStore.Put(collection, key, doc)
+}
+
+// CANNOT ATTACH A CONTEXT WHOSE SCHEDULES CAN'T BE KEPT, WHICH LEAVES THE
+// SCHEDULER DISCONNECTED
+func TestAttachFailure(t *testing.T) {
+	buildCtx, _ := setupBuildContext(t)
+	buildCtx.RegisterResource(StorageResource, unavailableJobs{resource.NewMemoryStore()})
+	ctx, err := newContext(buildCtx, remindersSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctx.Attach()
+	if err == nil || !strings.Contains(err.Error(), "schedule Nightly") {
+		t.Fatalf("expected the schedule to fail to attach, got %v", err)
+	}
+	if scheduler := buildCtx.Scheduler(); scheduler.driver != nil || scheduler.contexts != 0 {
+		t.Errorf("expected the scheduler to be disconnected, got %d contexts", scheduler.contexts)
+	}
+}
+
+// CAN DOUBLE THE BACKOFF OF A JOB FOR EVERY ATTEMPT WITHOUT IT GROWING PAST
+// THE MAXIMUM
+func TestRetryBackoff(t *testing.T) {
+	policy := DeliveryPolicy{Retries: 1000, Backoff: 100 * time.Millisecond}
+	fixtures := map[int]time.Duration{
+		1:    100 * time.Millisecond,
+		2:    200 * time.Millisecond,
+		4:    800 * time.Millisecond,
+		21:   maxBackoff,
+		64:   maxBackoff,
+		1000: maxBackoff,
+	}
+	for attempt, expected := range fixtures {
+		if backoff := policy.backoff(attempt); backoff != expected {
+			t.Errorf("expected attempt %d to wait %s, got %s", attempt, expected, backoff)
+		}
+	}
+}
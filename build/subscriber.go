@@ -0,0 +1,229 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/resource"
+)
+
+// DeliveryPolicy describes how events are delivered to subscribers
+type DeliveryPolicy struct {
+	// The queue group every subscriber joins. If it's empty each subscriber has
+	// a group of its own, so that instances of a context take turns handling an
+	// event instead of all of them handling it
+	Queue string
+	// How many more times handling an event is tried after it fails
+	Retries int
+	// How long to wait before the first retry, which doubles for every retry
+	// after it up to maxBackoff
+	Backoff time.Duration
+	// The subject events are published to once there aren't any retries left.
+	// If it's empty it's the name of the subscriber prefixed with "dead."
+	DeadLetter string
+}
+
+var DefaultDeliveryPolicy = DeliveryPolicy{
+	Retries: 3,
+	Backoff: 100 * time.Millisecond,
+}
+
+// The longest a retry waits, however many attempts came before it
+const maxBackoff = 24 * time.Hour
+
+// Sets how events are delivered to subscribers that are attached after
+func (ctx *BuildContext) SetDeliveryPolicy(policy DeliveryPolicy) {
+	ctx.delivery = policy
+}
+
+// Returns how long to wait before trying again after the given attempt failed
+func (policy DeliveryPolicy) backoff(attempt int) time.Duration {
+	backoff := policy.Backoff
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff <<= 1
+	}
+	return min(backoff, maxBackoff)
+}
+
+// DeadLetter is what's published to the dead letter subject of a subscriber
+// when it can't handle a message
+type DeadLetter struct {
+	Subscriber string `json:"subscriber"`
+	Subject    string `json:"subject"`
+	Attempts   int    `json:"attempts"`
+	Error      string `json:"error"`
+	Data       []byte `json:"data"`
+}
+
+// Subscriber represents a method that's called with every event of a class
+// that's emitted, from any context
+// i.e. on ProductDeleted(e: acme.product.ProductDeleted) { ... }
+type Subscriber struct {
+	Name    string
+	Private bool
+	Comment string
+	event   Event
+	// how the subscriber recognizes an event it's already handled, i.e. one
+	// that's delivered again, if it has an idempotency key
+	idempotency *idempotency
+	node        nodes.FunctionBlock
+	ctx         *Context
+	state       *subscriberState
+}
+
+type subscriberState struct {
+	policy       DeliveryPolicy
+	subscription resource.Subscription
+}
+
+func (s Subscriber) ClassName() string {
+	return s.Name
+}
+func (s Subscriber) Constructors() ConstructorMap {
+	return NewConstructorMap()
+}
+func (s Subscriber) Get(key string) Object {
+	return nil
+}
+
+// Returns the event the subscriber is called with
+func (s Subscriber) Event() Event {
+	return s.event
+}
+
+// Returns the name the subscriber is known by outside of its context
+func (s Subscriber) qualifiedName() string {
+	return s.ctx.Name + "." + s.Name
+}
+
+func (s Subscriber) MethodClassFromNode(ctx *Context, node nodes.ContextMethod) (Class, error) {
+	s.Name = node.Name
+	s.Private = node.Private
+	s.Comment = node.Comment
+	s.node = node.Block
+	s.ctx = ctx
+	s.state = &subscriberState{}
+
+	table := ctx.Symbols().WithMethod(node.Name)
+	fn, err := table.ResolveFunctionBlock(node.Block, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(fn.arguments) != 1 {
+		return nil, NodeError(node, "%s should have one argument for the event, got %d", node.Name, len(fn.arguments))
+	}
+	event, ok := fn.arguments[0].(Event)
+	if !ok {
+		return nil, NodeError(node, "%s can only subscribe to events, got %s", node.Name, fn.arguments[0].ClassName())
+	}
+	if fn.returns != nil {
+		return nil, NodeError(node, "%s cannot return a value", node.Name)
+	}
+	s.event = event
+	s.idempotency, err = idempotencyFromOptions(table, node)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Subscribes to the event on the event bus of the build context
+func (s Subscriber) Attach(ctx Context) error {
+	s.state.policy = ctx.buildCtx.delivery
+	queue := s.state.policy.Queue
+	if queue == "" {
+		queue = s.qualifiedName()
+	}
+	sub, err := ctx.buildCtx.EventBus().Subscribe(s.event.subject, queue, s.handle)
+	if err != nil {
+		return fmt.Errorf("subscriber %s: %s", s.Name, err.Error())
+	}
+	s.state.subscription = sub
+	return nil
+}
+func (s Subscriber) Detach() error {
+	if s.state.subscription == nil {
+		return nil
+	}
+	err := s.state.subscription.Unsubscribe()
+	s.state.subscription = nil
+	return err
+}
+
+// Calls the subscriber with the event a message carries. If it fails it's
+// tried again by the scheduler of the build context as many times as the
+// delivery policy allows, so that the bus isn't held up waiting for it
+func (s Subscriber) handle(msg resource.Message) error {
+	buildCtx := s.ctx.buildCtx
+	policy := s.state.policy
+	obj, _, err := s.event.Decode(msg)
+	if err != nil {
+		return s.deadLetter(msg, 0, err)
+	}
+	work := NewUnitOfWork(buildCtx)
+	if err = s.run(work, obj); err == nil {
+		return work.Commit()
+	}
+	work.Discard()
+	if policy.Retries == 0 {
+		return s.deadLetter(msg, 1, err)
+	}
+	buildCtx.Logger().Warn(
+		fmt.Sprintf("%s failed, retrying: %s", s.qualifiedName(), err.Error()),
+		"event", s.event.subject,
+		"attempt", 1,
+	)
+	if retryErr := buildCtx.scheduler.retry(s, msg, policy.Backoff); retryErr != nil {
+		return s.deadLetter(msg, 1, errors.Join(err, retryErr))
+	}
+	return nil
+}
+
+// Returns the message the subscriber was delivered with data
+func (s Subscriber) message(data []byte) resource.Message {
+	return resource.Message{Subject: s.event.subject, Data: data}
+}
+
+// Calls the subscriber in a unit of work
+func (s Subscriber) run(work *UnitOfWork, obj EventObject) error {
+	table := s.ctx.Symbols().WithMethod(s.Name).WithWork(work)
+	args := []ValueObject{obj}
+	// an event with a key the subscriber has already handled is skipped
+	if s.idempotency != nil {
+		_, err := s.idempotency.call(table, s.qualifiedName(), args, nil, func(table SymbolTable) (ValueObject, error) {
+			return table.callFunctionBlock(s.node, nil, args, nil)
+		})
+		return err
+	}
+	_, err := table.callFunctionBlock(s.node, nil, args, nil)
+	return err
+}
+
+// Publishes a message the subscriber couldn't handle to its dead letter subject
+func (s Subscriber) deadLetter(msg resource.Message, attempts int, reason error) error {
+	buildCtx := s.ctx.buildCtx
+	subject := s.state.policy.DeadLetter
+	if subject == "" {
+		subject = "dead." + s.qualifiedName()
+	}
+	buildCtx.Logger().Error(
+		fmt.Sprintf("%s gave up: %s", s.qualifiedName(), reason.Error()),
+		"event", s.event.subject,
+		"attempts", attempts,
+		"deadLetter", subject,
+	)
+	data, err := json.Marshal(DeadLetter{
+		Subscriber: s.qualifiedName(),
+		Subject:    msg.Subject,
+		Attempts:   attempts,
+		Error:      reason.Error(),
+		Data:       msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+	return buildCtx.EventBus().Publish(resource.Message{Subject: subject, Data: data})
+}
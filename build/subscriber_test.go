@@ -0,0 +1,187 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hntrl/lang/resource"
+)
+
+// flakyPackage has a function that fails until it's been called a number of
+// times, so that subscribers calling it can be retried
+type flakyPackage struct {
+	failures int
+	calls    *int
+	handled  *[]string
+}
+
+func (pkg flakyPackage) Get(key string) Object {
+	if key != "handle" {
+		return nil
+	}
+	return NewFunction(FunctionOptions{
+		Arguments: []Class{String{}},
+		Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+			*pkg.calls++
+			if *pkg.calls <= pkg.failures {
+				return nil, fmt.Errorf("attempt %d failed", *pkg.calls)
+			}
+			*pkg.handled = append(*pkg.handled, string(args[0].(StringLiteral)))
+			return nil, nil
+		},
+	})
+}
+
+const notifySource = `import "flaky"
+
+context acme.notify {
+	event ProductDeleted {
+		product_id String
+	}
+
+	command Delete(product_id: String) {
+		emit(ProductDeleted{ product_id: product_id })
+	}
+
+	on Notify(e: ProductDeleted) {
+		flaky.handle(e.product_id)
+	}
+}`
+
+// Sets up a context whose subscriber fails a number of times before it
+// handles an event, without a storage resource
+func setupFlaky(t *testing.T, failures int, policy DeliveryPolicy) (*BuildContext, *ManualClock, *Context, *int, *[]string) {
+	t.Helper()
+	clock := NewManualClock(testEpoch)
+	buildCtx := NewBuildContext()
+	buildCtx.SetClock(clock)
+	buildCtx.SetSeed(1)
+	buildCtx.SetLogHandler(discardLogs)
+	buildCtx.SetDeliveryPolicy(policy)
+	calls, handled := 0, []string{}
+	buildCtx.RegisterPackage("flaky", flakyPackage{failures: failures, calls: &calls, handled: &handled})
+	ctx := setupContext(t, buildCtx, notifySource)
+	return buildCtx, clock, ctx, &calls, &handled
+}
+
+// Returns the dead letters published for a subscriber
+func deadLetters(t *testing.T, buildCtx *BuildContext, subject string) *[]DeadLetter {
+	t.Helper()
+	letters := []DeadLetter{}
+	_, err := buildCtx.EventBus().Subscribe(subject, "", func(msg resource.Message) error {
+		var letter DeadLetter
+		if err := json.Unmarshal(msg.Data, &letter); err != nil {
+			return err
+		}
+		letters = append(letters, letter)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &letters
+}
+
+// CAN RETRY A SUBSCRIBER WITH BACKOFF WITHOUT A STORAGE RESOURCE
+func TestSubscriberRetries(t *testing.T) {
+	buildCtx, clock, ctx, calls, handled := setupFlaky(t, 2, DeliveryPolicy{Retries: 3, Backoff: time.Second})
+	letters := deadLetters(t, buildCtx, "dead.acme.notify.Notify")
+
+	invoke(t, ctx, "Delete", `{"product_id": "p1"}`)
+	if *calls != 1 || len(*handled) != 0 {
+		t.Fatalf("expected one failed attempt, got %d", *calls)
+	}
+	jobs, err := buildCtx.Scheduler().Jobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || !jobs[0].Due.Equal(testEpoch.Add(time.Second)) {
+		t.Fatalf("expected a retry in a second, got %+v", jobs)
+	}
+
+	// the retry isn't run before it's due
+	buildCtx.Scheduler().RunDue()
+	if *calls != 1 {
+		t.Errorf("expected the retry to wait, got %d calls", *calls)
+	}
+	clock.Advance(time.Second)
+	buildCtx.Scheduler().RunDue()
+	// the backoff doubles after the second attempt
+	clock.Advance(time.Second)
+	buildCtx.Scheduler().RunDue()
+	if *calls != 2 {
+		t.Errorf("expected the backoff to double, got %d calls", *calls)
+	}
+	clock.Advance(time.Second)
+	buildCtx.Scheduler().RunDue()
+	if *calls != 3 || len(*handled) != 1 || (*handled)[0] != "p1" {
+		t.Errorf("expected the event to be handled on the third attempt, got %d calls and %v", *calls, *handled)
+	}
+	if jobs, _ := buildCtx.Scheduler().Jobs(); len(jobs) != 0 {
+		t.Errorf("expected no jobs to be left, got %+v", jobs)
+	}
+	if len(*letters) != 0 {
+		t.Errorf("expected no dead letters, got %+v", *letters)
+	}
+}
+
+// CAN PUBLISH AN EVENT A SUBSCRIBER GIVES UP ON TO ITS DEAD LETTER SUBJECT
+func TestSubscriberDeadLetter(t *testing.T) {
+	buildCtx, clock, ctx, calls, _ := setupFlaky(t, 10, DeliveryPolicy{Retries: 2, Backoff: time.Second})
+	letters := deadLetters(t, buildCtx, "dead.acme.notify.Notify")
+
+	invoke(t, ctx, "Delete", `{"product_id": "p1"}`)
+	for i := 0; i < 5; i++ {
+		clock.Advance(time.Minute)
+		buildCtx.Scheduler().RunDue()
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", *calls)
+	}
+	if len(*letters) != 1 || (*letters)[0].Attempts != 3 || (*letters)[0].Subject != "acme.notify.ProductDeleted" {
+		t.Fatalf("expected a dead letter after 3 attempts, got %+v", *letters)
+	}
+	if !strings.HasSuffix((*letters)[0].Error, "attempt 3 failed") {
+		t.Errorf("expected the last error, got %s", (*letters)[0].Error)
+	}
+}
+
+// CAN DEAD LETTER AN EVENT RIGHT AWAY WITHOUT RETRIES
+func TestSubscriberWithoutRetries(t *testing.T) {
+	buildCtx, _, ctx, calls, _ := setupFlaky(t, 1, DeliveryPolicy{DeadLetter: "dead"})
+	letters := deadLetters(t, buildCtx, "dead")
+
+	invoke(t, ctx, "Delete", `{"product_id": "p1"}`)
+	if *calls != 1 || len(*letters) != 1 || (*letters)[0].Attempts != 1 {
+		t.Errorf("expected a dead letter after one attempt, got %d calls and %+v", *calls, *letters)
+	}
+}
+
+// CAN DELIVER AN EVENT TO ONE SUBSCRIBER OF A QUEUE GROUP
+func TestSubscriberQueueGroup(t *testing.T) {
+	bus := resource.NewMemoryBus()
+	received := []string{}
+	for _, name := range []string{"a", "b"} {
+		name := name
+		bus.Subscribe("s", "group", func(msg resource.Message) error {
+			received = append(received, name)
+			return nil
+		})
+	}
+	bus.Subscribe("s", "", func(msg resource.Message) error {
+		received = append(received, "all")
+		return nil
+	})
+	bus.Publish(resource.Message{Subject: "s"})
+	bus.Publish(resource.Message{Subject: "s"})
+	counts := make(map[string]int)
+	for _, name := range received {
+		counts[name]++
+	}
+	if counts["a"] != 1 || counts["b"] != 1 || counts["all"] != 2 {
+		t.Errorf("expected the group to take turns, got %v", received)
+	}
+}
@@ -1,6 +1,8 @@
 package build
 
 import (
+	"fmt"
+	"math"
 	"strings"
 
 	"github.com/hntrl/lang/language/nodes"
@@ -17,6 +19,7 @@ import (
 type SymbolTable struct {
 	immutable map[string]Object
 	local     map[string]Object
+	frame     Frame
 }
 
 func (st SymbolTable) Joined() map[string]Object {
@@ -42,9 +45,51 @@ func (st SymbolTable) Clone() SymbolTable {
 	return SymbolTable{
 		immutable: immutable,
 		local:     local,
+		frame:     st.frame,
 	}
 }
 
+// Returns a copy of the symbol table that's evaluating the body of a method
+func (st SymbolTable) WithMethod(name string) SymbolTable {
+	st.frame.Method = name
+	return st
+}
+
+// Returns a copy of the symbol table that can't call anything that makes
+// changes, i.e. for evaluating the body of a query
+func (st SymbolTable) ReadOnly() SymbolTable {
+	st.frame.ReadOnly = true
+	return st
+}
+
+// Returns a copy of the symbol table that makes changes in a unit of work
+func (st SymbolTable) WithWork(work *UnitOfWork) SymbolTable {
+	st.frame.Work = work
+	return st
+}
+
+// Returns whether the symbol table isn't allowed to call a method because it
+// makes changes
+func (st SymbolTable) forbids(method interface{}) bool {
+	if mutating, ok := method.(MutatingMethod); ok {
+		return st.frame.ReadOnly && mutating.Mutates()
+	}
+	return false
+}
+
+// Calls a method, letting it know where it's being called from if it wants to
+func (st SymbolTable) callMethod(node nodes.Node, method interface {
+	Call([]ValueObject, ValueObject) (ValueObject, error)
+}, args []ValueObject) (ValueObject, error) {
+	if st.forbids(method) {
+		return nil, fmt.Errorf("cannot make changes from %s", st.frame.Method)
+	}
+	if siteMethod, ok := method.(CallSiteMethod); ok {
+		return siteMethod.CallAt(CallSite{Frame: st.frame, Position: node.Pos()}, args, GenericObject{})
+	}
+	return method.Call(args, GenericObject{})
+}
+
 // Returns the Object targeted by a selector
 func (st SymbolTable) ResolveSelector(selector nodes.Selector) (Object, error) {
 	table := st.Joined()
@@ -70,6 +115,8 @@ func (st SymbolTable) ResolveExpression(expr nodes.Expression) (Object, error) {
 	switch expr := expr.Init.(type) {
 	case nodes.Literal:
 		return st.ResolveLiteral(expr)
+	case nodes.TemplateLiteral:
+		return st.ResolveTemplateLiteral(expr)
 	case nodes.ArrayExpression:
 		return st.ResolveArrayExpression(expr)
 	case nodes.InstanceExpression:
@@ -84,6 +131,12 @@ func (st SymbolTable) ResolveExpression(expr nodes.Expression) (Object, error) {
 			return nil, err
 		}
 		return *generic, nil
+	case nodes.FunctionExpression:
+		fn, err := st.ResolveFunctionExpression(expr)
+		if err != nil {
+			return nil, err
+		}
+		return *fn, nil
 	case nodes.ValueExpression:
 		return st.ResolveValueExpression(expr)
 	case nodes.Expression:
@@ -101,6 +154,8 @@ func (st SymbolTable) ValidateExpression(expr nodes.Expression) (Class, error) {
 			return nil, err
 		}
 		return lit.Class(), nil
+	case nodes.TemplateLiteral:
+		return st.ValidateTemplateLiteral(expr)
 	case nodes.ArrayExpression:
 		return st.ValidateArrayExpression(expr)
 	case nodes.InstanceExpression:
@@ -115,6 +170,8 @@ func (st SymbolTable) ValidateExpression(expr nodes.Expression) (Class, error) {
 			return nil, err
 		}
 		return *obj, nil
+	case nodes.FunctionExpression:
+		return st.ValidateFunctionExpression(expr)
 	case nodes.ValueExpression:
 		return st.ValidateValueExpression(expr)
 	case nodes.Expression:
@@ -152,6 +209,42 @@ func (st SymbolTable) ResolveLiteral(expr nodes.Literal) (ValueObject, error) {
 	}
 }
 
+// Interpolates each expression in a template literal into the surrounding
+// strings, using the String constructor for each value
+func (st SymbolTable) ResolveTemplateLiteral(expr nodes.TemplateLiteral) (ValueObject, error) {
+	var builder strings.Builder
+	for idx, str := range expr.Strings {
+		builder.WriteString(str)
+		if idx >= len(expr.Expressions) {
+			break
+		}
+		valueExpr := expr.Expressions[idx]
+		obj, err := st.ResolveValueObject(valueExpr)
+		if err != nil {
+			return nil, err
+		}
+		value, err := Construct(String{}, obj)
+		if err != nil {
+			return nil, NodeError(valueExpr, "%s", err)
+		}
+		builder.WriteString(string(value.(StringLiteral)))
+	}
+	return StringLiteral(builder.String()), nil
+}
+func (st SymbolTable) ValidateTemplateLiteral(expr nodes.TemplateLiteral) (Class, error) {
+	for _, valueExpr := range expr.Expressions {
+		class, err := st.ValidateExpression(valueExpr)
+		if err != nil {
+			return nil, err
+		}
+		err = ShouldConstruct(String{}, class)
+		if err != nil {
+			return nil, NodeError(valueExpr, "%s", err)
+		}
+	}
+	return String{}, nil
+}
+
 // --
 // ARRAY EXPRESSIONS
 // --
@@ -165,11 +258,11 @@ func (st SymbolTable) ResolveArrayExpression(expr nodes.ArrayExpression) (ValueO
 	for idx, elementExpr := range expr.Elements {
 		element, err := st.ResolveValueObject(elementExpr)
 		if err != nil {
-			return nil, NodeError(elementExpr, err.Error())
+			return nil, NodeError(elementExpr, "%s", err)
 		}
-		iterable.Items[idx], err = Construct(iterable.ParentType, element)
+		iterable.Items[idx], err = Convert(iterable.ParentType, element)
 		if err != nil {
-			return nil, NodeError(elementExpr, err.Error())
+			return nil, NodeError(elementExpr, "%s", err)
 		}
 	}
 	return iterable, nil
@@ -185,9 +278,9 @@ func (st SymbolTable) ValidateArrayExpression(expr nodes.ArrayExpression) (Class
 		if err != nil {
 			return nil, err
 		}
-		err = ShouldConstruct(iterable.ParentType, element)
+		err = ShouldConvert(iterable.ParentType, element)
 		if err != nil {
-			return nil, NodeError(elementExpr, err.Error())
+			return nil, NodeError(elementExpr, "%s", err)
 		}
 	}
 	return iterable, nil
@@ -224,7 +317,7 @@ func (st SymbolTable) ValidateInstanceExpression(expr nodes.InstanceExpression)
 		}
 		err = ShouldConstruct(class, *generic)
 		if err != nil {
-			return nil, NodeError(expr, err.Error())
+			return nil, NodeError(expr, "%s", err)
 		}
 		return class, nil
 	} else {
@@ -243,14 +336,27 @@ func (st SymbolTable) ResolveUnaryExpression(expr nodes.UnaryExpression) (ValueO
 	}
 	switch expr.Operator {
 	case tokens.ADD, tokens.SUB:
-		class := obj.Class()
-		if numValue, ok := obj.(NumberLiteral); ok {
-			if expr.Operator == tokens.ADD {
-				return Construct(class, NumberLiteral(+float64(numValue)))
-			} else {
-				return Construct(class, NumberLiteral(-float64(numValue)))
+		if expr.Operator == tokens.ADD {
+			if !isNumeric(obj.Class()) {
+				return nil, NodeError(expr, "cannot apply unary %s to %s", expr.Operator, obj.Class().ClassName())
 			}
-		} else {
+			return obj, nil
+		}
+		switch value := obj.(type) {
+		case IntegerLiteral:
+			if value == math.MinInt64 {
+				return nil, NodeError(expr, "%s", errIntegerOverflow)
+			}
+			return -value, nil
+		case FloatLiteral:
+			return -value, nil
+		case DoubleLiteral:
+			return -value, nil
+		case NumberLiteral:
+			return -value, nil
+		case DecimalLiteral:
+			return value.Neg(), nil
+		default:
 			return nil, NodeError(expr, "cannot apply unary %s to %s", expr.Operator, obj.Class().ClassName())
 		}
 	case tokens.NOT:
@@ -270,15 +376,14 @@ func (st SymbolTable) ValidateUnaryExpression(expr nodes.UnaryExpression) (Class
 	}
 	switch expr.Operator {
 	case tokens.ADD, tokens.SUB:
-		err = ShouldConstruct(Number{}, class)
-		if err != nil {
-			return nil, NodeError(expr, err.Error())
+		if !isNumeric(class) {
+			return nil, NodeError(expr, "cannot apply unary %s to %s", expr.Operator, class.ClassName())
 		}
 		return class, nil
 	case tokens.NOT:
 		err = ShouldConstruct(Boolean{}, class)
 		if err != nil {
-			return nil, NodeError(expr, err.Error())
+			return nil, NodeError(expr, "%s", err)
 		}
 		return Boolean{}, nil
 	default:
@@ -304,7 +409,7 @@ func (st SymbolTable) ResolveBinaryExpression(expr nodes.BinaryExpression) (Valu
 	}
 	obj, err := Operate(expr.Operator, left, right)
 	if err != nil {
-		return nil, NodeError(expr, err.Error())
+		return nil, NodeError(expr, "%s", err)
 	}
 	return obj, nil
 }
@@ -320,14 +425,11 @@ func (st SymbolTable) ValidateBinaryExpression(expr nodes.BinaryExpression) (Cla
 	if err != nil {
 		return nil, err
 	}
-	err = ShouldOperate(expr.Operator, left, right)
+	returns, err := OperatorReturns(expr.Operator, left, right)
 	if err != nil {
-		return nil, NodeError(expr, err.Error())
+		return nil, NodeError(expr, "%s", err)
 	}
-	if expr.Operator.IsComparableOperator() {
-		return Boolean{}, nil
-	}
-	return left, nil
+	return returns, nil
 }
 
 // --
@@ -351,6 +453,27 @@ func (st SymbolTable) valueExpressionPredicate(expr nodes.ValueExpression) (stri
 	}
 	return resolveChainString, current, nil
 }
+
+// Gives the type arguments of a call expression to the object being called
+func (st SymbolTable) applyTypeArguments(expr nodes.CallExpression, resolveChainString string, current Object) (Object, error) {
+	parameterized, ok := current.(ParameterizedMethod)
+	if !ok {
+		return nil, NodeError(expr, "%s does not accept type arguments", resolveChainString)
+	}
+	typeArgs := make([]Class, len(expr.TypeArguments))
+	for idx, typeExpr := range expr.TypeArguments {
+		class, err := st.ResolveTypeExpression(typeExpr)
+		if err != nil {
+			return nil, err
+		}
+		typeArgs[idx] = class
+	}
+	method, err := parameterized.WithTypeArguments(typeArgs)
+	if err != nil {
+		return nil, NodeError(expr, "%s", err)
+	}
+	return method, nil
+}
 func (st SymbolTable) ResolveValueExpression(expr nodes.ValueExpression) (Object, error) {
 	resolveChainString, current, err := st.valueExpressionPredicate(expr)
 	if err != nil {
@@ -366,7 +489,27 @@ func (st SymbolTable) ResolveValueExpression(expr nodes.ValueExpression) (Object
 			}
 			resolveChainString += "." + expr
 		case nodes.CallExpression:
-			if method, ok := current.(Method); ok {
+			if len(expr.TypeArguments) > 0 {
+				current, err = st.applyTypeArguments(expr, resolveChainString, current)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if generic, ok := current.(GenericMethod); ok {
+				passedArguments := make([]ValueObject, len(expr.Arguments))
+				for idx, argExpr := range expr.Arguments {
+					arg, err := st.ResolveValueObject(argExpr)
+					if err != nil {
+						return nil, err
+					}
+					passedArguments[idx] = arg
+				}
+				current, err = st.callMethod(memberExpr, generic, passedArguments)
+				if err != nil {
+					return nil, NodeError(memberExpr, "%s", err)
+				}
+				resolveChainString += "()"
+			} else if method, ok := current.(Method); ok {
 				passedArguments := make([]ValueObject, len(expr.Arguments))
 				for idx, argExpr := range expr.Arguments {
 					arg, err := st.ResolveValueObject(argExpr)
@@ -377,11 +520,11 @@ func (st SymbolTable) ResolveValueExpression(expr nodes.ValueExpression) (Object
 				}
 				args, err := ResolveMethodArguments(method, passedArguments)
 				if err != nil {
-					return nil, NodeError(memberExpr, err.Error())
+					return nil, NodeError(memberExpr, "%s", err)
 				}
-				current, err = method.Call(args, GenericObject{})
+				current, err = st.callMethod(memberExpr, method, args)
 				if err != nil {
-					return nil, NodeError(memberExpr, err.Error())
+					return nil, NodeError(memberExpr, "%s", err)
 				}
 				resolveChainString += "()"
 			} else if class, ok := current.(Class); ok {
@@ -394,7 +537,7 @@ func (st SymbolTable) ResolveValueExpression(expr nodes.ValueExpression) (Object
 				}
 				current, err = Construct(class, arg)
 				if err != nil {
-					return nil, NodeError(memberExpr, err.Error())
+					return nil, NodeError(memberExpr, "%s", err)
 				}
 				resolveChainString += "()"
 			} else {
@@ -416,7 +559,7 @@ func (st SymbolTable) ResolveValueExpression(expr nodes.ValueExpression) (Object
 						}
 					}
 					if expr.IsRange {
-						var right int
+						right := indexable.Len()
 						if expr.Right != nil {
 							rightExpr, err := st.ResolveValueObject(*expr.Right)
 							if err != nil {
@@ -430,12 +573,12 @@ func (st SymbolTable) ResolveValueExpression(expr nodes.ValueExpression) (Object
 						}
 						current, err = indexable.Range(left, right)
 						if err != nil {
-							return nil, NodeError(memberExpr, err.Error())
+							return nil, NodeError(memberExpr, "%s", err)
 						}
 					} else {
 						current, err = indexable.GetIndex(left)
 						if err != nil {
-							return nil, NodeError(memberExpr, err.Error())
+							return nil, NodeError(memberExpr, "%s", err)
 						}
 					}
 					resolveChainString += "[]"
@@ -456,11 +599,18 @@ func (st SymbolTable) ValidateValueExpression(expr nodes.ValueExpression) (Class
 	if err != nil {
 		return nil, err
 	}
+	// a class that's named in the expression is the class itself, rather than a
+	// value of it like a variable or what a method returns
+	_, isLocal := st.local[resolveChainString]
+	static := !isLocal && resolveChainString != "self"
 
 	for _, memberExpr := range expr.Members[1:] {
 		switch expr := memberExpr.Init.(type) {
 		case string:
-			if prop := current.Get(expr); prop != nil {
+			_, isClass := current.(Class)
+			if instance, ok := current.(InstanceClass); ok && !static && instance.InstanceGet(expr) != nil {
+				current = instance.InstanceGet(expr)
+			} else if prop := current.Get(expr); prop != nil {
 				current = current.Get(expr)
 				if current == nil {
 					return nil, NoPropertyError(memberExpr, resolveChainString, current, expr)
@@ -476,9 +626,34 @@ func (st SymbolTable) ValidateValueExpression(expr nodes.ValueExpression) (Class
 			} else {
 				return nil, NoPropertyError(memberExpr, resolveChainString, current, expr)
 			}
+			static = static && !isClass
 			resolveChainString += "." + expr
 		case nodes.CallExpression:
-			if method, ok := current.(Method); ok {
+			static = false
+			if len(expr.TypeArguments) > 0 {
+				current, err = st.applyTypeArguments(expr, resolveChainString, current)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if st.forbids(current) {
+				return nil, NodeError(memberExpr, "cannot call %s from %s since it makes changes", resolveChainString, st.frame.Method)
+			}
+			if generic, ok := current.(GenericMethod); ok {
+				passedArguments := make([]Class, len(expr.Arguments))
+				for idx, argExpr := range expr.Arguments {
+					arg, err := st.ValidateExpression(argExpr)
+					if err != nil {
+						return nil, err
+					}
+					passedArguments[idx] = arg
+				}
+				current, err = generic.ValidateArguments(passedArguments)
+				if err != nil {
+					return nil, NodeError(memberExpr, "%s", err)
+				}
+				resolveChainString += "()"
+			} else if method, ok := current.(Method); ok {
 				passedArguments := make([]Class, len(expr.Arguments))
 				for idx, argExpr := range expr.Arguments {
 					arg, err := st.ValidateExpression(argExpr)
@@ -489,7 +664,7 @@ func (st SymbolTable) ValidateValueExpression(expr nodes.ValueExpression) (Class
 				}
 				err := ValidateMethodArguments(method, passedArguments)
 				if err != nil {
-					return nil, NodeError(memberExpr, err.Error())
+					return nil, NodeError(memberExpr, "%s", err)
 				}
 				current = method.Returns()
 				resolveChainString += "()"
@@ -503,41 +678,48 @@ func (st SymbolTable) ValidateValueExpression(expr nodes.ValueExpression) (Class
 				}
 				err = ShouldConstruct(class, arg)
 				if err != nil {
-					return nil, NodeError(memberExpr, err.Error())
+					return nil, NodeError(memberExpr, "%s", err)
 				}
 				resolveChainString += "()"
 			} else {
 				return nil, UncallableError(memberExpr, resolveChainString, current)
 			}
 		case nodes.IndexExpression:
-			if valueObj, ok := current.(ValueObject); ok {
-				if _, ok := valueObj.(Indexable[ValueObject]); ok {
-					if expr.Left != nil {
-						leftExpr, err := st.ValidateExpression(*expr.Left)
+			static = false
+			class, ok := current.(Class)
+			if valueObj, isValue := current.(ValueObject); !ok && isValue {
+				class, ok = valueObj.Class(), true
+			}
+			if !ok {
+				return nil, AmbiguousObjectError(memberExpr, current)
+			}
+			if indexable, ok := class.(IndexableClass); ok {
+				if expr.Left != nil {
+					leftExpr, err := st.ValidateExpression(*expr.Left)
+					if err != nil {
+						return nil, err
+					}
+					if _, ok := leftExpr.(Integer); !ok {
+						return nil, InvalidIndexError(memberExpr, leftExpr)
+					}
+				}
+				if expr.IsRange {
+					if expr.Right != nil {
+						rightExpr, err := st.ValidateExpression(*expr.Right)
 						if err != nil {
 							return nil, err
 						}
-						if _, ok := leftExpr.(Integer); !ok {
-							return nil, InvalidIndexError(memberExpr, leftExpr)
+						if _, ok := rightExpr.(Integer); !ok {
+							return nil, InvalidIndexError(memberExpr, rightExpr)
 						}
 					}
-					if expr.IsRange {
-						if expr.Right != nil {
-							rightExpr, err := st.ValidateExpression(*expr.Right)
-							if err != nil {
-								return nil, err
-							}
-							if _, ok := rightExpr.(Integer); ok {
-								return nil, InvalidIndexError(memberExpr, rightExpr)
-							}
-						}
-					}
-					resolveChainString += "[]"
+					current = indexable
 				} else {
-					return nil, NotIndexableError(memberExpr, resolveChainString, current)
+					current = indexable.ElementClass()
 				}
+				resolveChainString += "[]"
 			} else {
-				return nil, AmbiguousObjectError(memberExpr, current)
+				return nil, NotIndexableError(memberExpr, resolveChainString, current)
 			}
 		default:
 			return nil, InvalidValueExpressionError(memberExpr)
@@ -0,0 +1,706 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hntrl/lang/language/tokens"
+)
+
+const dateLayout = "2006-01-02"
+
+// The layouts that are accepted when constructing a DateTime from a String, in
+// order of preference. Layouts without an offset are parsed as UTC
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	dateLayout,
+}
+
+func parseDate(str string) (time.Time, error) {
+	t, err := time.Parse(dateLayout, str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q as Date", str)
+	}
+	return t, nil
+}
+func parseDateTime(str string) (time.Time, error) {
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as DateTime", str)
+}
+
+// Returns the parse method of Date and DateTime, which takes a string and an
+// optional Go layout i.e. Date.parse("02/01/2024", "02/01/2006"). Without a
+// layout the string is parsed as ISO-8601, the same as the String constructor
+func timeParseFunction(class Class, parse func(string) (time.Time, error), wrap func(time.Time) ValueObject) Object {
+	return NewGenericFunction(GenericFunctionOptions{
+		Validator: func(args []Class) (Class, error) {
+			if len(args) != 1 && len(args) != 2 {
+				return nil, fmt.Errorf("expected a string and optional layout, got %d arguments", len(args))
+			}
+			for _, arg := range args {
+				if err := ShouldConstruct(String{}, arg); err != nil {
+					return nil, err
+				}
+			}
+			return class, nil
+		},
+		Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+			strs := make([]string, len(args))
+			for idx, arg := range args {
+				str, err := Construct(String{}, arg)
+				if err != nil {
+					return nil, err
+				}
+				strs[idx] = string(str.(StringLiteral))
+			}
+			if len(strs) == 1 {
+				t, err := parse(strs[0])
+				if err != nil {
+					return nil, err
+				}
+				return wrap(t), nil
+			}
+			t, err := time.Parse(strs[1], strs[0])
+			if err != nil {
+				return nil, err
+			}
+			return wrap(t), nil
+		},
+	})
+}
+
+// Truncates a time to midnight of the same calendar day. Dates are always kept
+// in UTC so that two dates on the same day are equal regardless of where they
+// came from
+func truncateDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func timeComparePredicate(cb func(a, b time.Time) bool) OperatorFn {
+	return func(a, b ValueObject) (ValueObject, error) {
+		return BooleanLiteral(cb(a.Value().(time.Time), b.Value().(time.Time))), nil
+	}
+}
+func timeCompareMap(class Class) ComparatorRules {
+	rules := NewComparatorRules()
+	rules.AddComparator(class, tokens.EQUALS, timeComparePredicate(func(a, b time.Time) bool {
+		return a.Equal(b)
+	}))
+	rules.AddComparator(class, tokens.NOT_EQUALS, timeComparePredicate(func(a, b time.Time) bool {
+		return !a.Equal(b)
+	}))
+	rules.AddComparator(class, tokens.LESS, timeComparePredicate(func(a, b time.Time) bool {
+		return a.Before(b)
+	}))
+	rules.AddComparator(class, tokens.GREATER, timeComparePredicate(func(a, b time.Time) bool {
+		return a.After(b)
+	}))
+	rules.AddComparator(class, tokens.LESS_EQUAL, timeComparePredicate(func(a, b time.Time) bool {
+		return !a.After(b)
+	}))
+	rules.AddComparator(class, tokens.GREATER_EQUAL, timeComparePredicate(func(a, b time.Time) bool {
+		return !a.Before(b)
+	}))
+	return rules
+}
+
+// Returns the accessors shared between Date and DateTime values
+func timeFields(t time.Time) map[string]Object {
+	return map[string]Object{
+		"year":    IntegerLiteral(t.Year()),
+		"month":   IntegerLiteral(t.Month()),
+		"day":     IntegerLiteral(t.Day()),
+		"weekday": StringLiteral(t.Weekday().String()),
+		"yearDay": IntegerLiteral(t.YearDay()),
+		"format": NewFunction(FunctionOptions{
+			Arguments: []Class{
+				String{},
+			},
+			Returns: String{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return StringLiteral(t.Format(string(args[0].(StringLiteral)))), nil
+			},
+		}),
+	}
+}
+
+// Returns the calendar methods shared between Date and DateTime values. wrap
+// turns the resulting time back into a value of the right class
+func timeCalendarMethods(t time.Time, class Class, wrap func(time.Time) ValueObject) map[string]Object {
+	addDate := func(years, months, days int) Function {
+		return NewFunction(FunctionOptions{
+			Arguments: []Class{
+				Integer{},
+			},
+			Returns: class,
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				n := int(args[0].(IntegerLiteral))
+				return wrap(t.AddDate(years*n, months*n, days*n)), nil
+			},
+		})
+	}
+	return map[string]Object{
+		"addYears":  addDate(1, 0, 0),
+		"addMonths": addDate(0, 1, 0),
+		"addDays":   addDate(0, 0, 1),
+	}
+}
+
+type Date struct {
+	// used to get the current time, ignored when comparing classes
+	buildCtx *BuildContext
+}
+
+func (d Date) ClassName() string {
+	return "Date"
+}
+func (d Date) Constructors() ConstructorMap {
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(Date{}, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddConstructor(DateTime{}, func(obj ValueObject) (ValueObject, error) {
+		return NewDateLiteral(obj.(DateTimeLiteral).Time), nil
+	})
+	csMap.AddConstructor(String{}, func(obj ValueObject) (ValueObject, error) {
+		t, err := parseDate(string(obj.(StringLiteral)))
+		if err != nil {
+			return nil, err
+		}
+		return DateLiteral{t}, nil
+	})
+	return csMap
+}
+
+func (d Date) ComparableRules() ComparatorRules {
+	return timeCompareMap(Date{})
+}
+func (d Date) OperatorRules() OperatorRules {
+	rules := NewOperatorRules()
+	rules.AddOperatorReturning(Date{}, tokens.SUB, Duration{}, func(a, b ValueObject) (ValueObject, error) {
+		return DurationLiteral(a.(DateLiteral).Sub(b.(DateLiteral).Time)), nil
+	})
+	rules.AddOperator(Duration{}, tokens.ADD, func(a, b ValueObject) (ValueObject, error) {
+		return NewDateLiteral(a.(DateLiteral).Add(time.Duration(b.(DurationLiteral)))), nil
+	})
+	rules.AddOperator(Duration{}, tokens.SUB, func(a, b ValueObject) (ValueObject, error) {
+		return NewDateLiteral(a.(DateLiteral).Add(-time.Duration(b.(DurationLiteral)))), nil
+	})
+	return rules
+}
+
+func (d Date) Get(key string) Object {
+	switch key {
+	case "now":
+		return NewFunction(FunctionOptions{
+			Returns: Date{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return NewDateLiteral(d.buildCtx.Now()), nil
+			},
+		})
+	case "of":
+		return NewFunction(FunctionOptions{
+			Arguments: []Class{
+				Integer{},
+				Integer{},
+				Integer{},
+			},
+			Returns: Date{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				year, month, day := int(args[0].(IntegerLiteral)), int(args[1].(IntegerLiteral)), int(args[2].(IntegerLiteral))
+				return DateLiteral{time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)}, nil
+			},
+		})
+	case "parse":
+		return timeParseFunction(Date{}, parseDate, func(t time.Time) ValueObject {
+			return NewDateLiteral(t)
+		})
+	}
+	// methods available on the instance so they can be validated without a value
+	return DateLiteral{}.Get(key)
+}
+
+// DateLiteral represents a calendar day without a time or timezone
+type DateLiteral struct {
+	time.Time
+}
+
+func NewDateLiteral(t time.Time) DateLiteral {
+	return DateLiteral{truncateDate(t)}
+}
+
+func (dl DateLiteral) Class() Class {
+	return Date{}
+}
+func (dl DateLiteral) Value() interface{} {
+	return dl.Time
+}
+func (dl DateLiteral) Set(key string, obj ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, dl.Class().ClassName())
+}
+func (dl DateLiteral) Get(key string) Object {
+	fields := timeFields(dl.Time)
+	for name, method := range timeCalendarMethods(dl.Time, Date{}, func(t time.Time) ValueObject {
+		return NewDateLiteral(t)
+	}) {
+		fields[name] = method
+	}
+	return fields[key]
+}
+
+func (dl DateLiteral) String() string {
+	return dl.Format(dateLayout)
+}
+func (dl DateLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dl.String())
+}
+func (dl *DateLiteral) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	t, err := parseDate(str)
+	if err != nil {
+		return err
+	}
+	dl.Time = t
+	return nil
+}
+
+type DateTime struct {
+	// used to get the current time, ignored when comparing classes
+	buildCtx *BuildContext
+}
+
+func (d DateTime) ClassName() string {
+	return "DateTime"
+}
+func (d DateTime) Constructors() ConstructorMap {
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(DateTime{}, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddConstructor(Date{}, func(obj ValueObject) (ValueObject, error) {
+		return DateTimeLiteral{obj.(DateLiteral).Time}, nil
+	})
+	csMap.AddConstructor(String{}, func(obj ValueObject) (ValueObject, error) {
+		t, err := parseDateTime(string(obj.(StringLiteral)))
+		if err != nil {
+			return nil, err
+		}
+		return DateTimeLiteral{t}, nil
+	})
+	return csMap
+}
+
+func (d DateTime) ComparableRules() ComparatorRules {
+	return timeCompareMap(DateTime{})
+}
+func (d DateTime) OperatorRules() OperatorRules {
+	rules := NewOperatorRules()
+	rules.AddOperatorReturning(DateTime{}, tokens.SUB, Duration{}, func(a, b ValueObject) (ValueObject, error) {
+		return DurationLiteral(a.(DateTimeLiteral).Sub(b.(DateTimeLiteral).Time)), nil
+	})
+	rules.AddOperator(Duration{}, tokens.ADD, func(a, b ValueObject) (ValueObject, error) {
+		return DateTimeLiteral{a.(DateTimeLiteral).Add(time.Duration(b.(DurationLiteral)))}, nil
+	})
+	rules.AddOperator(Duration{}, tokens.SUB, func(a, b ValueObject) (ValueObject, error) {
+		return DateTimeLiteral{a.(DateTimeLiteral).Add(-time.Duration(b.(DurationLiteral)))}, nil
+	})
+	return rules
+}
+
+func (d DateTime) Get(key string) Object {
+	switch key {
+	case "now":
+		return NewFunction(FunctionOptions{
+			Returns: DateTime{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return DateTimeLiteral{d.buildCtx.Now()}, nil
+			},
+		})
+	case "of":
+		return NewFunction(FunctionOptions{
+			Arguments: []Class{
+				Integer{},
+				Integer{},
+				Integer{},
+				Integer{},
+				Integer{},
+				Integer{},
+				String{},
+			},
+			Returns: DateTime{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				parts := make([]int, 6)
+				for idx := range parts {
+					parts[idx] = int(args[idx].(IntegerLiteral))
+				}
+				loc, err := time.LoadLocation(string(args[6].(StringLiteral)))
+				if err != nil {
+					return nil, err
+				}
+				return DateTimeLiteral{time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, loc)}, nil
+			},
+		})
+	case "parse":
+		return timeParseFunction(DateTime{}, parseDateTime, func(t time.Time) ValueObject {
+			return DateTimeLiteral{t}
+		})
+	}
+	// methods available on the instance so they can be validated without a value
+	return DateTimeLiteral{}.Get(key)
+}
+
+// DateTimeLiteral represents an instant in time along with the timezone it
+// should be displayed in
+type DateTimeLiteral struct {
+	time.Time
+}
+
+func (dl DateTimeLiteral) Class() Class {
+	return DateTime{}
+}
+func (dl DateTimeLiteral) Value() interface{} {
+	return dl.Time
+}
+func (dl DateTimeLiteral) Set(key string, obj ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, dl.Class().ClassName())
+}
+func (dl DateTimeLiteral) Get(key string) Object {
+	fields := timeFields(dl.Time)
+	for name, method := range timeCalendarMethods(dl.Time, DateTime{}, func(t time.Time) ValueObject {
+		return DateTimeLiteral{t}
+	}) {
+		fields[name] = method
+	}
+	fields["hour"] = IntegerLiteral(dl.Hour())
+	fields["minute"] = IntegerLiteral(dl.Minute())
+	fields["second"] = IntegerLiteral(dl.Second())
+	fields["timezone"] = StringLiteral(dl.Location().String())
+	fields["date"] = NewDateLiteral(dl.Time)
+	fields["inTimezone"] = NewFunction(FunctionOptions{
+		Arguments: []Class{
+			String{},
+		},
+		Returns: DateTime{},
+		Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+			loc, err := time.LoadLocation(string(args[0].(StringLiteral)))
+			if err != nil {
+				return nil, err
+			}
+			return DateTimeLiteral{dl.In(loc)}, nil
+		},
+	})
+	fields["utc"] = NewFunction(FunctionOptions{
+		Returns: DateTime{},
+		Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+			return DateTimeLiteral{dl.UTC()}, nil
+		},
+	})
+	return fields[key]
+}
+
+func (dl DateTimeLiteral) String() string {
+	return dl.Format(time.RFC3339Nano)
+}
+func (dl DateTimeLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dl.String())
+}
+func (dl *DateTimeLiteral) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	t, err := parseDateTime(str)
+	if err != nil {
+		return err
+	}
+	dl.Time = t
+	return nil
+}
+
+// Parses a duration in ISO-8601, i.e. PT1H30M or P1D, falling back to the
+// syntax of Go, i.e. 1h30m. Days are always 24 hours and weeks 7 days, so years
+// and months aren't allowed since their length depends on the date
+func parseDuration(str string) (time.Duration, error) {
+	iso, negative := strings.CutPrefix(str, "-")
+	iso, ok := strings.CutPrefix(iso, "P")
+	if !ok {
+		return time.ParseDuration(str)
+	}
+	var total time.Duration
+	// the unit of the last component, since they have to go from largest to
+	// smallest
+	last := time.Duration(math.MaxInt64)
+	inTime, empty := false, true
+	for iso != "" {
+		if iso[0] == 'T' {
+			if inTime || len(iso) == 1 {
+				return 0, fmt.Errorf("expected time after T")
+			}
+			inTime, iso = true, iso[1:]
+			continue
+		}
+		idx := strings.IndexFunc(iso, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != ','
+		})
+		if idx <= 0 {
+			return 0, fmt.Errorf("expected a number followed by a unit")
+		}
+		number, designator := iso[:idx], iso[idx]
+		iso = iso[idx+1:]
+		var unit time.Duration
+		switch {
+		case !inTime && designator == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && designator == 'D':
+			unit = 24 * time.Hour
+		case inTime && designator == 'H':
+			unit = time.Hour
+		case inTime && designator == 'M':
+			unit = time.Minute
+		case inTime && designator == 'S':
+			unit = time.Second
+		case designator == 'Y' || designator == 'M':
+			return 0, fmt.Errorf("years and months don't have a fixed length")
+		default:
+			return 0, fmt.Errorf("unknown unit %c", designator)
+		}
+		if unit >= last {
+			return 0, fmt.Errorf("expected units from largest to smallest")
+		}
+		last = unit
+		value, err := durationComponent(number, unit)
+		if err != nil {
+			return 0, err
+		}
+		if total > math.MaxInt64-value {
+			return 0, fmt.Errorf("duration is too large")
+		}
+		total += value
+		empty = false
+	}
+	if empty {
+		return 0, fmt.Errorf("expected at least one component")
+	}
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+// Returns a number of units, which can have a fraction, i.e. 1.5 in PT1.5H
+func durationComponent(number string, unit time.Duration) (time.Duration, error) {
+	whole, frac, _ := strings.Cut(strings.Replace(number, ",", ".", 1), ".")
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || n > int64(math.MaxInt64/unit) {
+		return 0, fmt.Errorf("%s is too large", number)
+	}
+	value := time.Duration(n) * unit
+	if frac != "" {
+		f, err := strconv.ParseFloat("0."+frac, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse %s", number)
+		}
+		value += time.Duration(math.Round(f * float64(unit)))
+	}
+	return value, nil
+}
+
+// Formats a duration in ISO-8601 using days, hours, minutes and seconds, i.e.
+// P1DT1H30M. Seconds keep their fraction down to nanoseconds
+func formatDuration(dur time.Duration) string {
+	if dur == 0 {
+		return "PT0S"
+	}
+	var builder strings.Builder
+	// the magnitude is unsigned so the smallest duration can be negated
+	magnitude := uint64(dur)
+	if dur < 0 {
+		builder.WriteString("-")
+		magnitude = -magnitude
+	}
+	builder.WriteString("P")
+	if days := magnitude / uint64(24*time.Hour); days > 0 {
+		fmt.Fprintf(&builder, "%dD", days)
+		magnitude %= uint64(24 * time.Hour)
+	}
+	if magnitude == 0 {
+		return builder.String()
+	}
+	builder.WriteString("T")
+	if hours := magnitude / uint64(time.Hour); hours > 0 {
+		fmt.Fprintf(&builder, "%dH", hours)
+		magnitude %= uint64(time.Hour)
+	}
+	if minutes := magnitude / uint64(time.Minute); minutes > 0 {
+		fmt.Fprintf(&builder, "%dM", minutes)
+		magnitude %= uint64(time.Minute)
+	}
+	if magnitude > 0 {
+		fmt.Fprintf(&builder, "%d", magnitude/uint64(time.Second))
+		if nanos := magnitude % uint64(time.Second); nanos > 0 {
+			builder.WriteString("." + strings.TrimRight(fmt.Sprintf("%09d", nanos), "0"))
+		}
+		builder.WriteString("S")
+	}
+	return builder.String()
+}
+
+type Duration struct{}
+
+func (d Duration) ClassName() string {
+	return "Duration"
+}
+func (d Duration) Constructors() ConstructorMap {
+	csMap := NewConstructorMap()
+	csMap.AddConstructor(Duration{}, func(obj ValueObject) (ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddConstructor(String{}, func(obj ValueObject) (ValueObject, error) {
+		dur, err := parseDuration(string(obj.(StringLiteral)))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q as Duration", string(obj.(StringLiteral)))
+		}
+		return DurationLiteral(dur), nil
+	})
+	return csMap
+}
+
+func durationComparePredicate(cb func(a, b time.Duration) bool) OperatorFn {
+	return func(a, b ValueObject) (ValueObject, error) {
+		return BooleanLiteral(cb(time.Duration(a.(DurationLiteral)), time.Duration(b.(DurationLiteral)))), nil
+	}
+}
+func (d Duration) ComparableRules() ComparatorRules {
+	rules := NewComparatorRules()
+	rules.AddComparator(Duration{}, tokens.EQUALS, durationComparePredicate(func(a, b time.Duration) bool {
+		return a == b
+	}))
+	rules.AddComparator(Duration{}, tokens.NOT_EQUALS, durationComparePredicate(func(a, b time.Duration) bool {
+		return a != b
+	}))
+	rules.AddComparator(Duration{}, tokens.LESS, durationComparePredicate(func(a, b time.Duration) bool {
+		return a < b
+	}))
+	rules.AddComparator(Duration{}, tokens.GREATER, durationComparePredicate(func(a, b time.Duration) bool {
+		return a > b
+	}))
+	rules.AddComparator(Duration{}, tokens.LESS_EQUAL, durationComparePredicate(func(a, b time.Duration) bool {
+		return a <= b
+	}))
+	rules.AddComparator(Duration{}, tokens.GREATER_EQUAL, durationComparePredicate(func(a, b time.Duration) bool {
+		return a >= b
+	}))
+	return rules
+}
+func (d Duration) OperatorRules() OperatorRules {
+	numConstructor := (Number{}).Constructors().Get(Number{})
+	scale := func(cb func(time.Duration, float64) time.Duration) OperatorFn {
+		return func(a, b ValueObject) (ValueObject, error) {
+			num, err := numConstructor(b)
+			if err != nil {
+				return nil, err
+			}
+			return DurationLiteral(cb(time.Duration(a.(DurationLiteral)), float64(num.(NumberLiteral)))), nil
+		}
+	}
+	rules := NewOperatorRules()
+	rules.AddOperator(Duration{}, tokens.ADD, func(a, b ValueObject) (ValueObject, error) {
+		return a.(DurationLiteral) + b.(DurationLiteral), nil
+	})
+	rules.AddOperator(Duration{}, tokens.SUB, func(a, b ValueObject) (ValueObject, error) {
+		return a.(DurationLiteral) - b.(DurationLiteral), nil
+	})
+	for _, class := range []Class{Number{}, Double{}, Integer{}, Float{}} {
+		rules.AddOperator(class, tokens.MUL, scale(func(dur time.Duration, n float64) time.Duration {
+			return time.Duration(float64(dur) * n)
+		}))
+		rules.AddOperator(class, tokens.QUO, scale(func(dur time.Duration, n float64) time.Duration {
+			return time.Duration(float64(dur) / n)
+		}))
+	}
+	return rules
+}
+
+func (d Duration) Get(key string) Object {
+	unit := func(unit time.Duration) Function {
+		return NewFunction(FunctionOptions{
+			Arguments: []Class{
+				Float{},
+			},
+			Returns: Duration{},
+			Handler: func(args []ValueObject, proto ValueObject) (ValueObject, error) {
+				return DurationLiteral(float64(args[0].(FloatLiteral)) * float64(unit)), nil
+			},
+		})
+	}
+	switch key {
+	case "ofMilliseconds":
+		return unit(time.Millisecond)
+	case "ofSeconds":
+		return unit(time.Second)
+	case "ofMinutes":
+		return unit(time.Minute)
+	case "ofHours":
+		return unit(time.Hour)
+	case "ofDays":
+		return unit(24 * time.Hour)
+	}
+	// methods available on the instance so they can be validated without a value
+	return DurationLiteral(0).Get(key)
+}
+
+type DurationLiteral time.Duration
+
+func (dl DurationLiteral) Class() Class {
+	return Duration{}
+}
+func (dl DurationLiteral) Value() interface{} {
+	return time.Duration(dl)
+}
+func (dl DurationLiteral) Set(key string, obj ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, dl.Class().ClassName())
+}
+func (dl DurationLiteral) Get(key string) Object {
+	dur := time.Duration(dl)
+	fields := map[string]Object{
+		"milliseconds": FloatLiteral(float64(dur) / float64(time.Millisecond)),
+		"seconds":      FloatLiteral(dur.Seconds()),
+		"minutes":      FloatLiteral(dur.Minutes()),
+		"hours":        FloatLiteral(dur.Hours()),
+		"days":         FloatLiteral(dur.Hours() / 24),
+	}
+	return fields[key]
+}
+
+func (dl DurationLiteral) String() string {
+	return formatDuration(time.Duration(dl))
+}
+func (dl DurationLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dl.String())
+}
+func (dl *DurationLiteral) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	dur, err := parseDuration(str)
+	if err != nil {
+		return err
+	}
+	*dl = DurationLiteral(dur)
+	return nil
+}
@@ -0,0 +1,96 @@
+package build
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// CAN PARSE ISO-8601 DURATIONS AND GO DURATIONS
+func TestParseDuration(t *testing.T) {
+	fixtures := map[string]time.Duration{
+		"PT1H30M":    90 * time.Minute,
+		"P1D":        24 * time.Hour,
+		"P1W":        7 * 24 * time.Hour,
+		"P1DT12H":    36 * time.Hour,
+		"PT0.5S":     500 * time.Millisecond,
+		"PT1,5M":     90 * time.Second,
+		"-PT10M":     -10 * time.Minute,
+		"PT0S":       0,
+		"1h30m":      90 * time.Minute,
+		"-1.5s":      -1500 * time.Millisecond,
+		"PT36H":      36 * time.Hour,
+		"P2DT3M4.2S": 48*time.Hour + 3*time.Minute + 4200*time.Millisecond,
+	}
+	for str, expected := range fixtures {
+		dur, err := parseDuration(str)
+		if err != nil {
+			t.Errorf("cannot parse %s: %s", str, err)
+		} else if dur != expected {
+			t.Errorf("expected %s to be %v, got %v", str, expected, dur)
+		}
+	}
+}
+
+// CANNOT PARSE MALFORMED DURATIONS OR DURATIONS IN YEARS OR MONTHS
+func TestParseInvalidDuration(t *testing.T) {
+	for _, str := range []string{"P", "PT", "P1Y", "P1M", "PT1M1H", "P1H", "PT1D", "P1DT", "PTH", "P1.2.3D", "P9999999999999W", "1 hour"} {
+		if dur, err := parseDuration(str); err == nil {
+			t.Errorf("expected %s to fail, got %v", str, dur)
+		}
+	}
+}
+
+// CAN FORMAT A DURATION AS ISO-8601
+func TestFormatDuration(t *testing.T) {
+	fixtures := map[time.Duration]string{
+		0:                                    "PT0S",
+		90 * time.Minute:                     "PT1H30M",
+		24 * time.Hour:                       "P1D",
+		25*time.Hour + 1500*time.Millisecond: "P1DT1H1.5S",
+		-10 * time.Minute:                    "-PT10M",
+		time.Nanosecond:                      "PT0.000000001S",
+	}
+	for dur, expected := range fixtures {
+		str := formatDuration(dur)
+		if str != expected {
+			t.Errorf("expected %v to be %s, got %s", dur, expected, str)
+		}
+		if parsed, err := parseDuration(str); err != nil || parsed != dur {
+			t.Errorf("expected %s to parse back to %v, got %v (%v)", str, dur, parsed, err)
+		}
+	}
+}
+
+// CAN PARSE DATES AND TIMES AS ISO-8601 OR WITH A LAYOUT
+func TestParseTime(t *testing.T) {
+	fixtures := map[string]string{
+		`Date.parse("2024-02-29")`:                                `"2024-02-29"`,
+		`Date.parse("29/02/2024", "02/01/2006")`:                  `"2024-02-29"`,
+		`DateTime.parse("2024-02-29T10:30:00+02:00")`:             `"2024-02-29T10:30:00+02:00"`,
+		`DateTime.parse("2024-02-29T10:30:00")`:                   `"2024-02-29T10:30:00Z"`,
+		`DateTime.parse("Feb 29 2024 10:30", "Jan 2 2006 15:04")`: `"2024-02-29T10:30:00Z"`,
+	}
+	for expr, expected := range fixtures {
+		returns := "Date"
+		if strings.HasPrefix(expr, "DateTime") {
+			returns = "DateTime"
+		}
+		out, err := evaluate(t, returns, "return "+expr)
+		if err != nil {
+			t.Errorf("cannot evaluate %s: %s", expr, err)
+		} else if out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+	for _, expr := range []string{
+		`Date.parse("29/02/2024")`,
+		`Date.parse("2024-02-30")`,
+		`DateTime.parse("2024-02-29", "15:04")`,
+		`DateTime.parse("2024-02-29", "2006-01-02", "UTC")`,
+	} {
+		if _, err := evaluate(t, "DateTime", "return "+expr); err == nil {
+			t.Errorf("expected %s to fail", expr)
+		}
+	}
+}
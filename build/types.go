@@ -113,6 +113,9 @@ func (t Type) ObjectClassFromNode(ctx *Context, node nodes.ContextObject) (Class
 	}
 	for _, item := range node.Fields {
 		if typeExpr, ok := item.Init.(nodes.TypeStatement); ok {
+			if typeExpr.Default != nil {
+				return nil, NodeError(item, "%s cannot have a default for %s", node.Name, typeExpr.Name)
+			}
 			obj, err := ctx.EvaluateTypeExpression(typeExpr.Init)
 			if err != nil {
 				return nil, err
@@ -152,7 +155,7 @@ func (to TypeObject) Get(key string) Object {
 
 type Iterable struct {
 	ParentType Class
-	Items      []ValueObject
+	Items      []ValueObject `hash:"ignore"`
 }
 
 func NewIterable(class Class, len int) Iterable {
@@ -184,21 +187,41 @@ func (it Iterable) Set(key string, obj ValueObject) error {
 	return nil
 }
 func (it Iterable) Get(key string) Object {
-	return nil
+	return it.methods()[key]
 }
 
-func (it Iterable) GetIndex(index int) ValueObject {
-	return it.Items[index]
+func (it Iterable) ElementClass() Class {
+	return it.ParentType
 }
-func (it Iterable) SetIndex(index int, obj ValueObject) error {
-	it.Items[index] = obj
-	return nil
+
+func (it Iterable) GetIndex(index int) (ValueObject, error) {
+	if index < 0 || index >= len(it.Items) {
+		return nil, fmt.Errorf("index %d out of range with length %d", index, len(it.Items))
+	}
+	return it.Items[index], nil
 }
-func (it Iterable) Range(a, b int) Iterable {
+func (it Iterable) SetIndex(index int, obj ValueObject) (Indexable[ValueObject], error) {
+	if index < 0 || index >= len(it.Items) {
+		return nil, fmt.Errorf("index %d out of range with length %d", index, len(it.Items))
+	}
+	item, err := Construct(it.ParentType, obj)
+	if err != nil {
+		return nil, err
+	}
+	// arrays are values, so other copies of the array keep their items
+	items := make([]ValueObject, len(it.Items))
+	copy(items, it.Items)
+	items[index] = item
+	return Iterable{ParentType: it.ParentType, Items: items}, nil
+}
+func (it Iterable) Range(a, b int) (Indexable[ValueObject], error) {
+	if a < 0 || b > len(it.Items) || a > b {
+		return nil, fmt.Errorf("slice bounds [%d:%d] out of range with length %d", a, b, len(it.Items))
+	}
 	return Iterable{
 		ParentType: it.ParentType,
 		Items:      it.Items[a:b],
-	}
+	}, nil
 }
 func (it Iterable) Len() int {
 	return len(it.Items)
@@ -0,0 +1,343 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hntrl/lang/resource"
+)
+
+// The collection events are kept in from when they're emitted by a command
+// until they've been published
+const OutboxCollection = "outbox"
+
+// UnitOfWork collects the changes made while a command runs so that they're
+// only kept if the command succeeds. Events that are emitted are kept in an
+// outbox with the rest of the changes and published once they're committed
+type UnitOfWork struct {
+	buildCtx *BuildContext
+	driver   resource.StorageDriver
+	storage  *resource.Batch
+	// the events emitted in the unit of work, in the order they were emitted
+	outbox []outboxEntry
+	// the events that are appended to event streams once the unit of work is
+	// committed, in the order the streams were first appended to
+	streams []*pendingStream
+	// called if the unit of work is discarded, i.e. to give up something that's
+	// been held for it outside of it
+	discarded []func()
+}
+
+type pendingStream struct {
+	store  resource.EventStore
+	stream string
+	// the version the stream is expected to be at when the events are appended
+	expected int
+	events   []json.RawMessage
+	// the state of the stream to keep once the events are appended, if there's
+	// one to keep
+	snapshot *resource.Snapshot
+	// the entries of the outbox the events are published with
+	outbox []outboxEntry
+}
+
+type outboxEntry struct {
+	key string
+	msg resource.Message
+}
+
+func NewUnitOfWork(buildCtx *BuildContext) *UnitOfWork {
+	return &UnitOfWork{buildCtx: buildCtx}
+}
+
+// Returns the driver that changes to storage should be made through
+func (work *UnitOfWork) Storage(driver resource.StorageDriver) resource.StorageDriver {
+	if work.storage == nil {
+		work.driver = driver
+		work.storage = resource.NewBatch(driver)
+	}
+	return work.storage
+}
+
+// Returns the driver the outbox is kept in, which is the storage resource of
+// the build context. Without one the outbox is only kept in memory
+func (work *UnitOfWork) outboxStorage() resource.StorageDriver {
+	if work.storage != nil {
+		return work.storage
+	}
+	if driver, ok := work.buildCtx.resources[StorageResource].(resource.StorageDriver); ok {
+		return work.Storage(driver)
+	}
+	return nil
+}
+
+// Keeps an event in the outbox until the unit of work is committed
+func (work *UnitOfWork) Emit(obj EventObject) error {
+	msg, err := work.buildCtx.eventMessage(obj)
+	if err != nil {
+		return err
+	}
+	_, err = work.buffer(msg)
+	return err
+}
+func (work *UnitOfWork) buffer(msg resource.Message) (outboxEntry, error) {
+	var envelope EventMessage
+	if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+		return outboxEntry{}, err
+	}
+	// keys are ordered by when the event was emitted so the relay publishes
+	// them in the same order, and end with the ID of the event so that units of
+	// work emitting at the same time don't collide
+	entry := outboxEntry{
+		key: fmt.Sprintf("%s-%06d-%s", envelope.Time.UTC().Format("20060102T150405.000000000"), len(work.outbox), envelope.ID),
+		msg: msg,
+	}
+	if driver := work.outboxStorage(); driver != nil {
+		if err := driver.Insert(OutboxCollection, entry.key, outboxDocument(msg)); err != nil {
+			return outboxEntry{}, err
+		}
+	}
+	work.outbox = append(work.outbox, entry)
+	return entry, nil
+}
+
+// Keeps events to append to a stream until the unit of work is committed,
+// along with them in the outbox so they're published once they're appended.
+// Events appended to a stream more than once are appended together, so each
+// has to expect the version the one before it leaves the stream at
+func (work *UnitOfWork) append(store resource.EventStore, stream string, expected int, msgs []resource.Message, snapshot *resource.Snapshot) error {
+	var pending *pendingStream
+	for _, existing := range work.streams {
+		if existing.store != store || existing.stream != stream {
+			continue
+		}
+		if version := existing.expected + len(existing.events); version != expected {
+			return fmt.Errorf("%s has changed since it was loaded", stream)
+		}
+		pending = existing
+	}
+	if pending == nil {
+		pending = &pendingStream{store: store, stream: stream, expected: expected}
+		work.streams = append(work.streams, pending)
+	}
+	for _, msg := range msgs {
+		entry, err := work.buffer(msg)
+		if err != nil {
+			return err
+		}
+		pending.events = append(pending.events, msg.Data)
+		pending.outbox = append(pending.outbox, entry)
+	}
+	if snapshot != nil {
+		pending.snapshot = snapshot
+	}
+	return nil
+}
+
+// Returns the events that are kept to append to a stream, as they'll be
+// stored
+func (work *UnitOfWork) pending(store resource.EventStore, stream string) []resource.StoredEvent {
+	out := []resource.StoredEvent{}
+	for _, pending := range work.streams {
+		if pending.store != store || pending.stream != stream {
+			continue
+		}
+		for idx, data := range pending.events {
+			out = append(out, resource.StoredEvent{Stream: stream, Version: pending.expected + idx + 1, Data: data})
+		}
+	}
+	return out
+}
+
+func (pending *pendingStream) commit() error {
+	if _, err := pending.store.Append(pending.stream, pending.expected, pending.events); err != nil {
+		if errors.Is(err, resource.ErrConflict) {
+			if pending.expected == 0 {
+				return fmt.Errorf("%s already exists", pending.stream)
+			}
+			return fmt.Errorf("%s has changed since it was loaded", pending.stream)
+		}
+		return err
+	}
+	return nil
+}
+
+func outboxDocument(msg resource.Message) resource.Document {
+	return resource.Document{"subject": msg.Subject, "data": string(msg.Data)}
+}
+
+// Keeps the changes that were made and publishes the events that were emitted.
+// Events are appended to their streams first, so that a stream that's changed
+// since it was loaded keeps the rest of the changes from being made. An event
+// that can't be published stays in the outbox until it's relayed.
+//
+// The event store and the storage resource can't be committed together, and
+// events can't be taken back out of a stream once they're appended. If the
+// rest of the changes can't be made after that, the events that were appended
+// are still put in the outbox so that they're published like any other, but
+// the rest of the changes are lost
+func (work *UnitOfWork) Commit() error {
+	publish, err := work.commit()
+	if err != nil {
+		return err
+	}
+	publish()
+	return nil
+}
+
+// Keeps the changes that were made, returning a function that publishes the
+// events that were emitted
+func (work *UnitOfWork) commit() (func(), error) {
+	streams := work.streams
+	work.streams = nil
+	for idx, pending := range streams {
+		if err := pending.commit(); err != nil {
+			work.keepAppended(streams[:idx], err)
+			work.Discard()
+			return nil, err
+		}
+	}
+	if work.storage != nil {
+		if err := work.storage.Commit(); err != nil {
+			work.keepAppended(streams, err)
+			work.Discard()
+			return nil, err
+		}
+	}
+	work.discarded = nil
+	// snapshots only save replaying events, so the changes are kept without them
+	for _, pending := range streams {
+		if pending.snapshot == nil {
+			continue
+		}
+		if err := pending.store.SaveSnapshot(*pending.snapshot); err != nil {
+			work.buildCtx.Logger().Warn(
+				fmt.Sprintf("cannot keep a snapshot of %s: %s", pending.stream, err.Error()),
+				"version", pending.snapshot.Version,
+			)
+		}
+	}
+	outbox := work.outbox
+	work.outbox = nil
+	return func() {
+		for _, entry := range outbox {
+			if err := work.buildCtx.relay(work.driver, entry); err != nil {
+				work.buildCtx.Logger().Warn(
+					fmt.Sprintf("cannot publish %s, leaving it in the outbox: %s", entry.msg.Subject, err.Error()),
+					"key", entry.key,
+				)
+			}
+		}
+	}, nil
+}
+
+// Puts the events that were appended to their streams in the outbox when the
+// rest of the unit of work can't be committed, since they're kept regardless.
+// Without an outbox to put them in they're published right away
+func (work *UnitOfWork) keepAppended(streams []*pendingStream, reason error) {
+	for _, pending := range streams {
+		work.buildCtx.Logger().Error(
+			fmt.Sprintf("events were appended to %s, but the rest of the changes couldn't be kept: %s", pending.stream, reason.Error()),
+			"events", len(pending.events),
+		)
+		for _, entry := range pending.outbox {
+			if work.driver != nil {
+				err := work.driver.Put(OutboxCollection, entry.key, outboxDocument(entry.msg))
+				if err == nil {
+					continue
+				}
+				work.buildCtx.Logger().Warn(fmt.Sprintf("cannot put %s in the outbox, publishing it instead: %s", entry.msg.Subject, err.Error()), "key", entry.key)
+			}
+			if err := work.buildCtx.bus.Publish(entry.msg); err != nil {
+				work.buildCtx.Logger().Error(fmt.Sprintf("cannot publish %s: %s", entry.msg.Subject, err.Error()), "key", entry.key)
+			}
+		}
+	}
+}
+
+// Calls a function if the unit of work is discarded instead of committed
+func (work *UnitOfWork) OnDiscard(fn func()) {
+	work.discarded = append(work.discarded, fn)
+}
+
+// Forgets the changes that were made
+func (work *UnitOfWork) Discard() {
+	if work.storage != nil {
+		work.storage.Discard()
+	}
+	work.outbox = nil
+	work.streams = nil
+	discarded := work.discarded
+	work.discarded = nil
+	for _, fn := range discarded {
+		fn()
+	}
+}
+
+// Publishes an event from the outbox and removes it once it's been published
+func (ctx *BuildContext) relay(driver resource.StorageDriver, entry outboxEntry) error {
+	if err := ctx.bus.Publish(entry.msg); err != nil {
+		return err
+	}
+	if driver == nil {
+		return nil
+	}
+	if err := driver.Delete(OutboxCollection, entry.key); err != nil && !errors.Is(err, resource.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Publishes every event that's still in the outbox of the storage resource,
+// i.e. because publishing it failed after its command was committed. Events
+// can be published more than once, so consumers should use their IDs to tell
+// when they've already handled one
+func (ctx *BuildContext) RelayOutbox() error {
+	driver, ok := ctx.resources[StorageResource].(resource.StorageDriver)
+	if !ok {
+		return nil
+	}
+	records, err := driver.Find(OutboxCollection, resource.Document{})
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		subject, _ := record.Document["subject"].(string)
+		data, _ := record.Document["data"].(string)
+		entry := outboxEntry{key: record.Key, msg: resource.Message{Subject: subject, Data: []byte(data)}}
+		if err := ctx.relay(driver, entry); err != nil {
+			return fmt.Errorf("cannot publish %s: %s", subject, err.Error())
+		}
+	}
+	return nil
+}
+
+// Evaluates something in a new unit of work, only keeping the changes that are
+// made if it succeeds
+func (ctx *BuildContext) evaluateInWork(st SymbolTable, eval func(SymbolTable) (ValueObject, error)) (ValueObject, error) {
+	obj, publish, err := ctx.evaluateInWorkUnpublished(st, eval)
+	if err != nil {
+		return nil, err
+	}
+	publish()
+	return obj, nil
+}
+
+// Evaluates something in a new unit of work like evaluateInWork, but leaves
+// publishing the events that were emitted to the caller. The in-memory bus
+// calls handlers before it returns, so a caller that holds a lock has to
+// release it before publishing in case a handler needs it too
+func (ctx *BuildContext) evaluateInWorkUnpublished(st SymbolTable, eval func(SymbolTable) (ValueObject, error)) (ValueObject, func(), error) {
+	work := NewUnitOfWork(ctx)
+	obj, err := eval(st.WithWork(work))
+	if err != nil {
+		work.Discard()
+		return nil, nil, err
+	}
+	publish, err := work.commit()
+	if err != nil {
+		return nil, nil, err
+	}
+	return obj, publish, nil
+}
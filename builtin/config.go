@@ -0,0 +1,458 @@
+package packages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hntrl/lang/build"
+	"github.com/hntrl/lang/language/nodes"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSources describes where the values of config objects are read from.
+// Each layer overrides the one before it: the defaults declared on the fields,
+// then the file, then the environment
+type ConfigSources struct {
+	// A JSON or YAML file with a section for each config object, keyed by its
+	// name i.e. { "Settings": { "currency": "EUR" } }
+	File string
+	// Environment variables are named by the prefix, the config object and the
+	// field i.e. SHOP_SETTINGS_MAX_ITEMS for the prefix SHOP_
+	EnvPrefix string
+	// Used to look up environment variables, which is os.LookupEnv if not set
+	LookupEnv func(string) (string, bool)
+}
+
+func (src ConfigSources) lookupEnv(key string) (string, bool) {
+	if src.LookupEnv == nil {
+		return os.LookupEnv(key)
+	}
+	return src.LookupEnv(key)
+}
+
+// Returns the environment variable a field of a config object is read from
+func (src ConfigSources) envName(object, field string) string {
+	return src.EnvPrefix + screamingSnakeCase(object) + "_" + screamingSnakeCase(field)
+}
+
+// i.e. maxItems -> MAX_ITEMS
+func screamingSnakeCase(str string) string {
+	var out strings.Builder
+	runes := []rune(str)
+	for idx, r := range runes {
+		if idx > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[idx-1]) || unicode.IsDigit(runes[idx-1])) {
+			out.WriteRune('_')
+		}
+		out.WriteRune(unicode.ToUpper(r))
+	}
+	return out.String()
+}
+
+// Reads the file of the sources, returning the section of every config object
+func (src ConfigSources) readFile() (map[string]interface{}, error) {
+	if src.File == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(src.File)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	switch ext := filepath.Ext(src.File); ext {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&out); err != nil {
+			return nil, fmt.Errorf("%s: %s", src.File, err.Error())
+		}
+	case ".yaml", ".yml":
+		out, err = parseYAML(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", src.File, err.Error())
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown config format %q, expected .json, .yaml or .yml", src.File, ext)
+	}
+	if out == nil {
+		return nil, nil
+	}
+	sections, ok := out.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected an object at the top level", src.File)
+	}
+	return sections, nil
+}
+
+// Fills the settings of something the host makes, such as a resource, from the
+// same layers as config objects. Every exported string, integer or boolean
+// field of the struct target points to is read from the section of the file
+// with the name, and then from the environment, i.e. SHOP_MONGO_URL for the URL
+// field with the name Mongo and the prefix SHOP_. Fields that aren't set in
+// either keep the value they have
+func (src ConfigSources) Configure(name string, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config %s: expected a pointer to a struct, got %T", name, target)
+	}
+	value = value.Elem()
+	sections, err := src.readFile()
+	if err != nil {
+		return fmt.Errorf("config %s: %s", name, err.Error())
+	}
+	var section map[string]interface{}
+	if raw, ok := sections[name]; ok {
+		if section, ok = raw.(map[string]interface{}); !ok {
+			return fmt.Errorf("config %s: expected an object in %s", name, src.File)
+		}
+	}
+	for idx := 0; idx < value.NumField(); idx++ {
+		field := value.Type().Field(idx)
+		if !field.IsExported() || !configurable(field.Type.Kind()) {
+			continue
+		}
+		var raw string
+		envName := src.envName(name, field.Name)
+		if env, ok := src.lookupEnv(envName); ok {
+			raw = env
+		} else if setting, ok := section[lowerFirst(field.Name)]; ok {
+			raw = fmt.Sprint(setting)
+		} else {
+			continue
+		}
+		if err := setField(value.Field(idx), raw); err != nil {
+			return fmt.Errorf("config %s: %s: %s", name, field.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// i.e. URL -> url and MaxItems -> maxItems
+func lowerFirst(str string) string {
+	runes := []rune(str)
+	idx := 0
+	for idx < len(runes) && unicode.IsUpper(runes[idx]) && (idx == 0 || idx+1 == len(runes) || unicode.IsUpper(runes[idx+1])) {
+		runes[idx] = unicode.ToLower(runes[idx])
+		idx++
+	}
+	return string(runes)
+}
+
+func configurable(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func setField(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot use %q as an integer", raw)
+		}
+		field.SetInt(value)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("cannot use %q as a boolean", raw)
+		}
+		field.SetBool(value)
+	}
+	return nil
+}
+
+// Registers the config interface with where its values should be read from.
+// This has to be called before any contexts are made
+func RegisterConfig(ctx *build.BuildContext, sources ConfigSources) {
+	ctx.RegisterClass("config", Config{sources: &sources})
+	ctx.RegisterPackage("config", ConfigPackage{sources: &sources})
+	// resources that are made when they're requested are configured from the
+	// section named by their key, i.e. SHOP_STORAGE_URL
+	ctx.SetResourceConfigurer(func(key string, target interface{}) error {
+		return sources.Configure(key, target)
+	})
+}
+
+type ConfigPackage struct {
+	sources *ConfigSources
+}
+
+func (cp ConfigPackage) Get(key string) build.Object {
+	methods := map[string]build.Object{
+		// reads an environment variable as is, which should only be needed for
+		// values that can't be declared ahead of time
+		"env": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+			},
+			Returns: build.NilableObject{ClassObject: build.String{}},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				value, ok := cp.sources.lookupEnv(string(args[0].(build.StringLiteral)))
+				if !ok {
+					return build.NilableObject{ClassObject: build.String{}}, nil
+				}
+				return build.NilableObject{ClassObject: build.String{}, Object: build.StringLiteral(value)}, nil
+			},
+		}),
+	}
+	return methods[key]
+}
+
+// Config represents a set of typed settings that are filled when the context
+// is attached
+// i.e. config Settings { currency String = "USD"  maxItems Int }
+type Config struct {
+	Name    string
+	Private bool
+	Comment string
+	fields  map[string]build.Class
+	// the declared defaults, which are replaced by the loaded values once the
+	// config is attached
+	defaults map[string]build.ValueObject
+	values   map[string]build.ValueObject
+	sources  *ConfigSources
+}
+
+func (c Config) ClassName() string {
+	return c.Name
+}
+func (c Config) Fields() map[string]build.Class {
+	return c.fields
+}
+func (c Config) Constructors() build.ConstructorMap {
+	return build.NewConstructorMap()
+}
+func (c Config) Get(key string) build.Object {
+	if value, ok := c.values[key]; ok {
+		return value
+	}
+	return nil
+}
+
+func (c Config) ObjectClassFromNode(ctx *build.Context, node nodes.ContextObject) (build.Class, error) {
+	c.Name = node.Name
+	c.Private = node.Private
+	c.Comment = node.Comment
+	c.fields = make(map[string]build.Class)
+	c.defaults = make(map[string]build.ValueObject)
+	c.values = make(map[string]build.ValueObject)
+	if c.sources == nil {
+		c.sources = &ConfigSources{}
+	}
+
+	if node.Extends != nil {
+		return nil, build.NodeError(node, "config %s cannot extend %s", node.Name, strings.Join(node.Extends.Members, "."))
+	}
+	symbols := ctx.Symbols()
+	for _, item := range node.Fields {
+		typeExpr, ok := item.Init.(nodes.TypeStatement)
+		if !ok {
+			return nil, build.NodeError(item, "expected type statement")
+		}
+		class, err := ctx.EvaluateTypeExpression(typeExpr.Init)
+		if err != nil {
+			return nil, err
+		}
+		c.fields[typeExpr.Name] = class
+		if typeExpr.Default != nil {
+			defaultClass, err := symbols.ValidateExpression(*typeExpr.Default)
+			if err != nil {
+				return nil, err
+			}
+			if err := build.ShouldConvert(class, defaultClass); err != nil {
+				return nil, build.NodeError(typeExpr, "invalid default for %s: %s", typeExpr.Name, err.Error())
+			}
+			value, err := symbols.ResolveValueObject(*typeExpr.Default)
+			if err != nil {
+				return nil, err
+			}
+			value, err = build.Convert(class, value)
+			if err != nil {
+				return nil, build.NodeError(typeExpr, "invalid default for %s: %s", typeExpr.Name, err.Error())
+			}
+			c.defaults[typeExpr.Name] = value
+			c.values[typeExpr.Name] = value
+		}
+	}
+	return c, nil
+}
+
+// Returns a setting read from the file as the class of its field
+func decodeFileSetting(class build.Class, raw interface{}) (build.ValueObject, error) {
+	return build.FromInterfaceAs(raw, class)
+}
+
+// Returns a setting read from an environment variable as the class of its
+// field. Anything that can't be made from a string is read as JSON
+func decodeEnvSetting(class build.Class, raw string) (build.ValueObject, error) {
+	target := class
+	if nilable, ok := class.(build.NilableObject); ok {
+		target = nilable.ClassObject
+	}
+	if build.ShouldConstruct(target, build.String{}) == nil {
+		value, err := build.Construct(target, build.StringLiteral(raw))
+		if err != nil {
+			return nil, err
+		}
+		return build.Convert(class, value)
+	}
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	var obj interface{}
+	if err := decoder.Decode(&obj); err != nil || decoder.More() {
+		return nil, fmt.Errorf("cannot use %q as %s", raw, target.ClassName())
+	}
+	return build.FromInterfaceAs(obj, class)
+}
+
+// Fills the values of the config from its sources, failing if any of them are
+// missing or can't be used as the class of their field
+func (c Config) Attach(ctx build.Context) error {
+	sections, err := c.sources.readFile()
+	if err != nil {
+		return fmt.Errorf("config %s: %s", c.Name, err.Error())
+	}
+	var section map[string]interface{}
+	if raw, ok := sections[c.Name]; ok {
+		if section, ok = raw.(map[string]interface{}); !ok {
+			return fmt.Errorf("config %s: expected an object in %s", c.Name, c.sources.File)
+		}
+	}
+	for key := range section {
+		if _, ok := c.fields[key]; !ok {
+			return fmt.Errorf("config %s: unknown setting %s in %s", c.Name, key, c.sources.File)
+		}
+	}
+
+	keys := make([]string, 0, len(c.fields))
+	for key := range c.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make(map[string]build.ValueObject)
+	for _, key := range keys {
+		class := c.fields[key]
+		envName := c.sources.envName(c.Name, key)
+		if raw, ok := c.sources.lookupEnv(envName); ok {
+			value, err := decodeEnvSetting(class, raw)
+			if err != nil {
+				return fmt.Errorf("config %s: %s (from %s): %s", c.Name, key, envName, err.Error())
+			}
+			values[key] = value
+		} else if raw, ok := section[key]; ok {
+			value, err := decodeFileSetting(class, raw)
+			if err != nil {
+				return fmt.Errorf("config %s: %s (from %s): %s", c.Name, key, c.sources.File, err.Error())
+			}
+			values[key] = value
+		} else if value, ok := c.defaults[key]; ok {
+			values[key] = value
+		} else if nilable, ok := class.(build.NilableObject); ok {
+			values[key] = build.NilableObject{ClassObject: nilable.ClassObject}
+		} else {
+			where := envName
+			if c.sources.File != "" {
+				where += " or " + key + " in " + c.sources.File
+			}
+			return fmt.Errorf("config %s: missing value for %s, set %s", c.Name, key, where)
+		}
+	}
+	for key, value := range values {
+		c.values[key] = value
+	}
+	return nil
+}
+func (c Config) Detach() error {
+	return nil
+}
+
+// --
+// YAML
+// --
+
+// Reads a YAML document into the same form a JSON file is decoded into, so
+// that numbers are kept as json.Number and decimals stay exact
+func parseYAML(src string) (interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return yamlValue(doc.Content[0])
+}
+
+func yamlValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.SequenceNode:
+		out := make([]interface{}, len(node.Content))
+		for idx, item := range node.Content {
+			value, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[idx] = value
+		}
+		return out, nil
+	case yaml.MappingNode:
+		out := make(map[string]interface{})
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			key := node.Content[idx]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: keys have to be strings", key.Line)
+			}
+			if _, ok := out[key.Value]; ok {
+				return nil, fmt.Errorf("line %d: %s is already set", key.Line, key.Value)
+			}
+			value, err := yamlValue(node.Content[idx+1])
+			if err != nil {
+				return nil, err
+			}
+			out[key.Value] = value
+		}
+		return out, nil
+	}
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var value bool
+		err := node.Decode(&value)
+		return value, err
+	case "!!int":
+		var value int64
+		if err := node.Decode(&value); err != nil {
+			return nil, fmt.Errorf("line %d: %s is too large", node.Line, node.Value)
+		}
+		return json.Number(strconv.FormatInt(value, 10)), nil
+	case "!!float":
+		// the text is kept when it's already a JSON number, i.e. 1.50
+		if json.Valid([]byte(node.Value)) {
+			return json.Number(node.Value), nil
+		}
+		var value float64
+		if err := node.Decode(&value); err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, fmt.Errorf("line %d: %s isn't a number", node.Line, node.Value)
+		}
+		return json.Number(strconv.FormatFloat(value, 'g', -1, 64)), nil
+	case "!!str", "!!timestamp":
+		return node.Value, nil
+	}
+	return nil, fmt.Errorf("line %d: unsupported value %s", node.Line, node.ShortTag())
+}
@@ -0,0 +1,208 @@
+package packages
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hntrl/lang/build"
+	"github.com/hntrl/lang/resource"
+)
+
+// CAN READ NESTED MAPPINGS, SEQUENCES, QUOTED STRINGS AND COMMENTS FROM YAML
+func TestParseYAML(t *testing.T) {
+	type YAMLFixture struct {
+		Source   string
+		Expected interface{}
+	}
+	fixtures := []YAMLFixture{
+		{"", nil},
+		{"# only a comment\n", nil},
+		{"a:\n  b:\n    c: 1\n", map[string]interface{}{
+			"a": map[string]interface{}{"b": map[string]interface{}{"c": json.Number("1")}},
+		}},
+		{"a:\n  - 1\n  - two\n  - x: true\nb: [1.50, null]\n", map[string]interface{}{
+			"a": []interface{}{json.Number("1"), "two", map[string]interface{}{"x": true}},
+			"b": []interface{}{json.Number("1.50"), nil},
+		}},
+		{"a: \"x # y\"\nb: 'it''s'\nc: \"1\"\nd: plain text\n", map[string]interface{}{
+			"a": "x # y", "b": "it's", "c": "1", "d": "plain text",
+		}},
+		{"# settings\na: 1 # the first\n\nb: 2\n", map[string]interface{}{
+			"a": json.Number("1"), "b": json.Number("2"),
+		}},
+		{"a: 0x1F\nb: 1e3\nc: -.5\n", map[string]interface{}{
+			"a": json.Number("31"), "b": json.Number("1e3"), "c": json.Number("-0.5"),
+		}},
+	}
+	for _, fixture := range fixtures {
+		value, err := parseYAML(fixture.Source)
+		if err != nil {
+			t.Errorf("cannot parse %q: %s", fixture.Source, err)
+		} else if !reflect.DeepEqual(value, fixture.Expected) {
+			t.Errorf("expected %#v for %q, got %#v", fixture.Expected, fixture.Source, value)
+		}
+	}
+}
+
+// CANNOT READ YAML THAT'S BADLY INDENTED OR HAS VALUES THAT AREN'T NUMBERS
+func TestParseInvalidYAML(t *testing.T) {
+	for _, src := range []string{
+		"a:\n  b: 1\n c: 2\n",
+		"a:\n\tb: 1\n",
+		"a: 1\na: 2\n",
+		"a: \"unterminated\n",
+		"a: .inf\n",
+		"? [a, b]\n: 1\n",
+	} {
+		if value, err := parseYAML(src); err == nil {
+			t.Errorf("expected %q to fail, got %#v", src, value)
+		}
+	}
+}
+
+const settingsSource = `context acme.shop {
+	config Settings {
+		currency String = "USD"
+		maxItems Int
+		rate Float = 1.5
+		tags []String?
+	}
+}`
+
+// Writes a config file to a temporary directory, returning its path
+func writeConfigFile(t *testing.T, name string, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Builds the settings with the sources and attaches them
+func attachSettings(t *testing.T, sources ConfigSources) (Config, error) {
+	t.Helper()
+	buildCtx := setupBuildContext()
+	RegisterConfig(buildCtx, sources)
+	ctx, err := newContext(buildCtx, settingsSource)
+	if err != nil {
+		t.Fatalf("cannot build: %s", err)
+	}
+	config := ctx.Get("Settings").(Config)
+	return config, config.Attach(*ctx)
+}
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+// CAN LAYER THE DEFAULTS, THE FILE AND THE ENVIRONMENT
+func TestConfigLayers(t *testing.T) {
+	file := writeConfigFile(t, "config.yaml", "Settings:\n  currency: EUR\n  maxItems: 5\n  rate: 2.25\n")
+	config, err := attachSettings(t, ConfigSources{
+		File:      file,
+		EnvPrefix: "SHOP_",
+		LookupEnv: envLookup(map[string]string{"SHOP_SETTINGS_MAX_ITEMS": "10", "SHOP_SETTINGS_TAGS": `["a", "b"]`}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := config.Get("currency"); value != build.StringLiteral("EUR") {
+		t.Errorf("expected the file to replace the default, got %v", value)
+	}
+	if value := config.Get("maxItems"); value != build.IntegerLiteral(10) {
+		t.Errorf("expected the environment to replace the file, got %v", value)
+	}
+	if value := config.Get("rate"); value != build.FloatLiteral(2.25) {
+		t.Errorf("expected the rate of the file, got %v", value)
+	}
+	if value := config.Get("tags").(build.NilableObject); len(value.Object.(build.Iterable).Items) != 2 {
+		t.Errorf("expected the tags of the environment, got %v", value)
+	}
+
+	// without a file or environment the defaults are kept
+	config, err = attachSettings(t, ConfigSources{LookupEnv: envLookup(map[string]string{"SETTINGS_MAX_ITEMS": "1"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Get("currency") != build.StringLiteral("USD") || config.Get("tags").(build.NilableObject).Object != nil {
+		t.Errorf("expected the defaults, got %v and %v", config.Get("currency"), config.Get("tags"))
+	}
+}
+
+// CANNOT ATTACH A CONFIG WITH SETTINGS THAT ARE MISSING, UNKNOWN OR OF THE
+// WRONG TYPE
+func TestConfigErrors(t *testing.T) {
+	type ErrorFixture struct {
+		File    string
+		Env     map[string]string
+		Message string
+	}
+	fixtures := []ErrorFixture{
+		{"", nil, "missing value for maxItems, set SETTINGS_MAX_ITEMS"},
+		{"Settings:\n  maxItems: 1\n  color: red\n", nil, "unknown setting color"},
+		{"Settings:\n  maxItems: many\n", nil, "maxItems (from"},
+		{"", map[string]string{"SETTINGS_MAX_ITEMS": "1.5"}, "maxItems (from SETTINGS_MAX_ITEMS)"},
+		{"Settings: [1, 2]\n", nil, "expected an object"},
+		{"Settings:\n  maxItems: 1\n    currency: EUR\n", nil, "config Settings"},
+	}
+	for _, fixture := range fixtures {
+		sources := ConfigSources{LookupEnv: envLookup(fixture.Env)}
+		if fixture.File != "" {
+			sources.File = writeConfigFile(t, "config.yml", fixture.File)
+		}
+		_, err := attachSettings(t, sources)
+		if err == nil || !strings.Contains(err.Error(), fixture.Message) {
+			t.Errorf("expected an error containing %q, got %v", fixture.Message, err)
+		}
+	}
+}
+
+// connectionSettings is a resource with the kinds of settings a connection has
+type connectionSettings struct {
+	URL      string
+	Port     int
+	Verbose  bool
+	internal string
+}
+
+func (res connectionSettings) Attach() (resource.Resource, error) {
+	return res, nil
+}
+func (res connectionSettings) Detach() error {
+	return nil
+}
+
+// CAN CONFIGURE A RESOURCE FROM THE SECTION AND ENVIRONMENT OF ITS KEY
+func TestConfigureResource(t *testing.T) {
+	buildCtx := setupBuildContext()
+	RegisterConfig(buildCtx, ConfigSources{
+		File:      writeConfigFile(t, "config.json", `{"storage": {"url": "mongodb://db", "port": 27017}}`),
+		EnvPrefix: "SHOP_",
+		LookupEnv: envLookup(map[string]string{"SHOP_STORAGE_VERBOSE": "true", "SHOP_STORAGE_PORT": "27018"}),
+	})
+	ctx, err := newContext(buildCtx, `context acme.shop {}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res connectionSettings
+	if err := ctx.Resource("storage", &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.URL != "mongodb://db" || res.Port != 27018 || !res.Verbose || res.internal != "" {
+		t.Errorf("expected the resource to be configured, got %+v", res)
+	}
+
+	var other connectionSettings
+	err = ConfigSources{LookupEnv: envLookup(map[string]string{"OTHER_PORT": "many"})}.Configure("other", &other)
+	if err == nil {
+		t.Errorf("expected a port that isn't a number to fail")
+	}
+}
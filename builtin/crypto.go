@@ -0,0 +1,99 @@
+package packages
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"fmt"
+	"hash"
+
+	"github.com/hntrl/lang/build"
+)
+
+type CryptoPackage struct{}
+
+// The most bytes randomBytes makes at once, which is far more than a key or
+// token needs
+const maxRandomBytes = 1 << 16
+
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+func (cp CryptoPackage) Get(key string) build.Object {
+	methods := map[string]build.Object{
+		"sha256": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.Bytes{},
+			},
+			Returns: build.Bytes{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				sum := sha256.Sum256(args[0].(build.BytesLiteral))
+				return build.BytesLiteral(sum[:]), nil
+			},
+		}),
+		"sha512": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.Bytes{},
+			},
+			Returns: build.Bytes{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				sum := sha512.Sum512(args[0].(build.BytesLiteral))
+				return build.BytesLiteral(sum[:]), nil
+			},
+		}),
+		"hmac": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+				build.Bytes{},
+				build.Bytes{},
+			},
+			Returns: build.Bytes{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				algorithm := string(args[0].(build.StringLiteral))
+				newHash, ok := hashAlgorithms[algorithm]
+				if !ok {
+					return nil, fmt.Errorf("unknown hash algorithm %q", algorithm)
+				}
+				mac := hmac.New(newHash, args[1].(build.BytesLiteral))
+				mac.Write(args[2].(build.BytesLiteral))
+				return build.BytesLiteral(mac.Sum(nil)), nil
+			},
+		}),
+		// compares in time that only depends on the length of the input so that
+		// signatures can't be guessed byte by byte
+		"constantTimeEqual": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.Bytes{},
+				build.Bytes{},
+			},
+			Returns: build.Boolean{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return build.BooleanLiteral(subtle.ConstantTimeCompare(args[0].(build.BytesLiteral), args[1].(build.BytesLiteral)) == 1), nil
+			},
+		}),
+		// always taken from the operating system, never from the seeded random
+		// source of the build context
+		"randomBytes": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.Integer{},
+			},
+			Returns: build.Bytes{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				n := int64(args[0].(build.IntegerLiteral))
+				if n < 0 || n > maxRandomBytes {
+					return nil, fmt.Errorf("invalid length %d, expected 0 to %d", n, maxRandomBytes)
+				}
+				out := make([]byte, n)
+				if _, err := rand.Read(out); err != nil {
+					return nil, err
+				}
+				return build.BytesLiteral(out), nil
+			},
+		}),
+	}
+	return methods[key]
+}
@@ -0,0 +1,57 @@
+package packages
+
+import (
+	"strings"
+	"testing"
+)
+
+// CAN HASH AND SIGN BYTES, MATCHING THE PUBLISHED TEST VECTORS
+func TestCryptoVectors(t *testing.T) {
+	fixtures := map[string]string{
+		`crypto.sha256(Bytes("abc"))`: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		`crypto.sha256(Bytes(""))`:    "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		`crypto.sha512(Bytes("abc"))`: "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+		// RFC 4231, test case 2
+		`crypto.hmac("sha256", Bytes("Jefe"), Bytes("what do ya want for nothing?"))`: "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		`crypto.hmac("sha512", Bytes("Jefe"), Bytes("what do ya want for nothing?"))`: "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737",
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "crypto encoding", "String", "encoding.hexEncode("+expr+")"); out != quote(expected) {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+	if _, err := evaluate(setupBuildContext(), "crypto", "Bytes", `crypto.hmac("md5", Bytes("k"), Bytes("m"))`); err == nil || !strings.Contains(err.Error(), `unknown hash algorithm "md5"`) {
+		t.Errorf("expected an unknown algorithm to fail, got %v", err)
+	}
+}
+
+// CAN COMPARE BYTES REGARDLESS OF WHERE THEY DIFFER
+func TestConstantTimeEqual(t *testing.T) {
+	fixtures := map[string]string{
+		`crypto.constantTimeEqual(Bytes("abc"), Bytes("abc"))`: "true",
+		`crypto.constantTimeEqual(Bytes("abc"), Bytes("abd"))`: "false",
+		`crypto.constantTimeEqual(Bytes("abc"), Bytes("ab"))`:  "false",
+		`crypto.constantTimeEqual(Bytes(""), Bytes(""))`:       "true",
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "crypto", "Bool", expr); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
+
+// CAN MAKE RANDOM BYTES OF A LENGTH THAT'S IN RANGE
+func TestRandomBytes(t *testing.T) {
+	if out := mustEvaluate(t, "crypto", "Int", `len(crypto.randomBytes(32))`); out != "32" {
+		t.Errorf("expected 32 bytes, got %s", out)
+	}
+	if out := mustEvaluate(t, "crypto", "Int", `len(crypto.randomBytes(0))`); out != "0" {
+		t.Errorf("expected no bytes, got %s", out)
+	}
+	for _, expr := range []string{`crypto.randomBytes(-1)`, `crypto.randomBytes(65537)`, `crypto.randomBytes(9223372036854775807)`} {
+		_, err := evaluate(setupBuildContext(), "crypto", "Bytes", expr)
+		if err == nil || !strings.Contains(err.Error(), "invalid length") {
+			t.Errorf("expected %s to fail, got %v", expr, err)
+		}
+	}
+}
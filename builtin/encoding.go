@@ -0,0 +1,74 @@
+package packages
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/hntrl/lang/build"
+)
+
+type EncodingPackage struct{}
+
+func encodeFunction(fn func([]byte) string) build.Function {
+	return build.NewFunction(build.FunctionOptions{
+		Arguments: []build.Class{
+			build.Bytes{},
+		},
+		Returns: build.String{},
+		Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+			return build.StringLiteral(fn(args[0].(build.BytesLiteral))), nil
+		},
+	})
+}
+func decodeFunction(fn func(string) ([]byte, error)) build.Function {
+	return build.NewFunction(build.FunctionOptions{
+		Arguments: []build.Class{
+			build.String{},
+		},
+		Returns: build.Bytes{},
+		Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+			out, err := fn(string(args[0].(build.StringLiteral)))
+			if err != nil {
+				return nil, err
+			}
+			return build.BytesLiteral(out), nil
+		},
+	})
+}
+func escapeFunction(fn func(string) (string, error)) build.Function {
+	return build.NewFunction(build.FunctionOptions{
+		Arguments: []build.Class{
+			build.String{},
+		},
+		Returns: build.String{},
+		Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+			out, err := fn(string(args[0].(build.StringLiteral)))
+			if err != nil {
+				return nil, err
+			}
+			return build.StringLiteral(out), nil
+		},
+	})
+}
+
+func (ep EncodingPackage) Get(key string) build.Object {
+	methods := map[string]build.Object{
+		"base64Encode": encodeFunction(base64.StdEncoding.EncodeToString),
+		"base64Decode": decodeFunction(base64.StdEncoding.DecodeString),
+		// base64url is written without padding, but padding is accepted when
+		// decoding
+		"base64UrlEncode": encodeFunction(base64.RawURLEncoding.EncodeToString),
+		"base64UrlDecode": decodeFunction(func(str string) ([]byte, error) {
+			return base64.RawURLEncoding.DecodeString(strings.TrimRight(str, "="))
+		}),
+		"hexEncode": encodeFunction(hex.EncodeToString),
+		"hexDecode": decodeFunction(hex.DecodeString),
+		"queryEscape": escapeFunction(func(str string) (string, error) {
+			return url.QueryEscape(str), nil
+		}),
+		"queryUnescape": escapeFunction(url.QueryUnescape),
+	}
+	return methods[key]
+}
@@ -0,0 +1,49 @@
+package packages
+
+import "testing"
+
+// CAN ENCODE BYTES AND DECODE THEM AGAIN
+func TestEncoding(t *testing.T) {
+	fixtures := map[string]string{
+		`encoding.base64Encode(Bytes("hi?>"))`:                   `"aGk/Pg=="`,
+		`encoding.base64UrlEncode(Bytes("hi?>"))`:                `"aGk_Pg"`,
+		`encoding.hexEncode(Bytes("é"))`:                         `"c3a9"`,
+		`encoding.queryEscape("a b&c=d/é")`:                      `"a+b%26c%3Dd%2F%C3%A9"`,
+		`encoding.queryUnescape("a+b%2Fc")`:                      `"a b/c"`,
+		`encoding.base64Encode(encoding.base64UrlDecode("_-8"))`: `"/+8="`,
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "encoding", "String", expr); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
+
+// CAN DECODE TEXT INTO THE BYTES IT WAS ENCODED FROM
+func TestDecoding(t *testing.T) {
+	for _, expr := range []string{
+		`encoding.base64Decode("aGk/Pg==") == Bytes("hi?>")`,
+		`encoding.base64UrlDecode("aGk_Pg") == Bytes("hi?>")`,
+		`encoding.base64UrlDecode("aGk_Pg==") == Bytes("hi?>")`,
+		`encoding.hexDecode("C3A9") == Bytes("é")`,
+	} {
+		if out := mustEvaluate(t, "encoding", "Bool", expr); out != "true" {
+			t.Errorf("expected %s to be true", expr)
+		}
+	}
+}
+
+// CANNOT DECODE TEXT THAT ISN'T IN THE ENCODING
+func TestEncodingErrors(t *testing.T) {
+	for _, expr := range []string{
+		`encoding.base64Decode("a!")`,
+		`encoding.base64Decode("aGk_Pg")`,
+		`encoding.hexDecode("abc")`,
+		`encoding.hexDecode("zz")`,
+		`encoding.queryUnescape("%zz")`,
+	} {
+		if _, err := evaluate(setupBuildContext(), "encoding", "Bytes", expr); err == nil {
+			t.Errorf("expected %s to fail", expr)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package packages
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/hntrl/lang/build"
+	"github.com/hntrl/lang/language/tokens"
+)
+
+type IdsPackage struct {
+	buildCtx *build.BuildContext
+}
+
+// Fills a buffer from a random source. rand.Rand.Read isn't safe to share
+// between goroutines, so this only uses Uint64
+func randomBytes(random *rand.Rand, n int) []byte {
+	out := make([]byte, n)
+	for idx := 0; idx < n; idx += 8 {
+		value := random.Uint64()
+		for offset := 0; offset < 8 && idx+offset < n; offset++ {
+			out[idx+offset] = byte(value >> (8 * offset))
+		}
+	}
+	return out
+}
+
+// Writes a millisecond unix timestamp into the first 6 bytes of a buffer
+func putTimestamp(buf []byte, ms int64) {
+	for idx := 0; idx < 6; idx++ {
+		buf[idx] = byte(ms >> (8 * (5 - idx)))
+	}
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+const nanoidAlphabet = "useandom-26T198340PX75pxJACKVERYMINDBUSHWOLF_GQZbfghjklqvwyzrict"
+
+func (ip IdsPackage) Get(key string) build.Object {
+	methods := map[string]build.Object{
+		"UUID": UUID{},
+		"uuid4": build.NewFunction(build.FunctionOptions{
+			Returns: UUID{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				var id UUIDLiteral
+				copy(id[:], randomBytes(ip.buildCtx.Random(), 16))
+				id[6] = (id[6] & 0x0f) | 0x40
+				id[8] = (id[8] & 0x3f) | 0x80
+				return id, nil
+			},
+		}),
+		// version 7 ids start with the current time so they sort in the order
+		// they were made
+		"uuid7": build.NewFunction(build.FunctionOptions{
+			Returns: UUID{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				var id UUIDLiteral
+				putTimestamp(id[:], ip.buildCtx.Now().UnixMilli())
+				copy(id[6:], randomBytes(ip.buildCtx.Random(), 10))
+				id[6] = (id[6] & 0x0f) | 0x70
+				id[8] = (id[8] & 0x3f) | 0x80
+				return id, nil
+			},
+		}),
+		"ulid": build.NewFunction(build.FunctionOptions{
+			Returns: build.String{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				buf := make([]byte, 16)
+				putTimestamp(buf, ip.buildCtx.Now().UnixMilli())
+				copy(buf[6:], randomBytes(ip.buildCtx.Random(), 10))
+				// 128 bits as 26 base32 characters, the first only holding 3 bits
+				var out strings.Builder
+				for idx := 0; idx < 26; idx++ {
+					bit := 128 - 5*(26-idx)
+					value := 0
+					for offset := 0; offset < 5; offset++ {
+						if pos := bit + offset; pos >= 0 && buf[pos/8]&(0x80>>(pos%8)) != 0 {
+							value |= 0x10 >> offset
+						}
+					}
+					out.WriteByte(crockfordAlphabet[value])
+				}
+				return build.StringLiteral(out.String()), nil
+			},
+		}),
+		"nanoid": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.Integer{},
+			},
+			Returns: build.String{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				size := int64(args[0].(build.IntegerLiteral))
+				if size <= 0 {
+					return nil, fmt.Errorf("invalid size %d", size)
+				}
+				buf := randomBytes(ip.buildCtx.Random(), int(size))
+				for idx, b := range buf {
+					buf[idx] = nanoidAlphabet[b&63]
+				}
+				return build.StringLiteral(buf), nil
+			},
+		}),
+	}
+	return methods[key]
+}
+
+// UUID represents a 128 bit identifier as described in RFC 9562
+type UUID struct{}
+
+func (u UUID) ClassName() string {
+	return "UUID"
+}
+func (u UUID) Constructors() build.ConstructorMap {
+	csMap := build.NewConstructorMap()
+	csMap.AddConstructor(UUID{}, func(obj build.ValueObject) (build.ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddConstructor(build.String{}, func(obj build.ValueObject) (build.ValueObject, error) {
+		return ParseUUID(string(obj.(build.StringLiteral)))
+	})
+	return csMap
+}
+
+func uuidComparePredicate(cb func(cmp int) bool) build.OperatorFn {
+	return func(a, b build.ValueObject) (build.ValueObject, error) {
+		ua, ub := a.(UUIDLiteral), b.(UUIDLiteral)
+		return build.BooleanLiteral(cb(bytes.Compare(ua[:], ub[:]))), nil
+	}
+}
+func (u UUID) ComparableRules() build.ComparatorRules {
+	rules := build.NewComparatorRules()
+	rules.AddComparator(UUID{}, tokens.EQUALS, uuidComparePredicate(func(cmp int) bool {
+		return cmp == 0
+	}))
+	rules.AddComparator(UUID{}, tokens.NOT_EQUALS, uuidComparePredicate(func(cmp int) bool {
+		return cmp != 0
+	}))
+	rules.AddComparator(UUID{}, tokens.LESS, uuidComparePredicate(func(cmp int) bool {
+		return cmp < 0
+	}))
+	rules.AddComparator(UUID{}, tokens.GREATER, uuidComparePredicate(func(cmp int) bool {
+		return cmp > 0
+	}))
+	return rules
+}
+func (u UUID) Get(key string) build.Object {
+	// methods available on the instance so they can be validated without a value
+	return UUIDLiteral{}.Get(key)
+}
+
+type UUIDLiteral [16]byte
+
+// Parses a UUID from its canonical form, i.e.
+// "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"
+func ParseUUID(str string) (UUIDLiteral, error) {
+	var id UUIDLiteral
+	if len(str) != 36 || str[8] != '-' || str[13] != '-' || str[18] != '-' || str[23] != '-' {
+		return id, fmt.Errorf("invalid UUID %q", str)
+	}
+	raw := str[0:8] + str[9:13] + str[14:18] + str[19:23] + str[24:36]
+	if _, err := hex.Decode(id[:], []byte(raw)); err != nil {
+		return id, fmt.Errorf("invalid UUID %q", str)
+	}
+	return id, nil
+}
+
+func (ul UUIDLiteral) Class() build.Class {
+	return UUID{}
+}
+func (ul UUIDLiteral) Value() interface{} {
+	return ul.String()
+}
+func (ul UUIDLiteral) Set(key string, obj build.ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, ul.Class().ClassName())
+}
+func (ul UUIDLiteral) Get(key string) build.Object {
+	switch key {
+	case "version":
+		return build.IntegerLiteral(ul[6] >> 4)
+	case "format":
+		return build.NewFunction(build.FunctionOptions{
+			Returns: build.String{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return build.StringLiteral(ul.String()), nil
+			},
+		})
+	}
+	return nil
+}
+
+func (ul UUIDLiteral) String() string {
+	str := hex.EncodeToString(ul[:])
+	return str[0:8] + "-" + str[8:12] + "-" + str[12:16] + "-" + str[16:20] + "-" + str[20:32]
+}
+
+func (ul UUIDLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ul.String())
+}
+func (ul *UUIDLiteral) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	id, err := ParseUUID(str)
+	if err != nil {
+		return err
+	}
+	*ul = id
+	return nil
+}
@@ -0,0 +1,48 @@
+package packages
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hntrl/lang/build"
+)
+
+type JSONPackage struct{}
+
+func (jp JSONPackage) Get(key string) build.Object {
+	methods := map[string]build.Object{
+		"parse": build.NewParameterizedFunction(build.ParameterizedFunctionOptions{
+			Instantiate: func(types []build.Class) (build.Object, error) {
+				if len(types) != 1 {
+					return nil, fmt.Errorf("expected 1 type argument, got %d", len(types))
+				}
+				class := types[0]
+				return build.NewFunction(build.FunctionOptions{
+					Arguments: []build.Class{
+						build.String{},
+					},
+					Returns: class,
+					Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+						return build.FromBytes([]byte(args[0].(build.StringLiteral)), class)
+					},
+				}), nil
+			},
+		}),
+		"stringify": build.NewGenericFunction(build.GenericFunctionOptions{
+			Validator: func(args []build.Class) (build.Class, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("expected 1 argument, got %d", len(args))
+				}
+				return build.String{}, nil
+			},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				bytes, err := json.Marshal(build.ToInterface(args[0]))
+				if err != nil {
+					return nil, err
+				}
+				return build.StringLiteral(bytes), nil
+			},
+		}),
+	}
+	return methods[key]
+}
@@ -0,0 +1,82 @@
+package packages
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const ordersSource = `import "json"
+
+context acme.orders {
+	type Line {
+		sku String
+		quantity Int
+		price Decimal
+	}
+	type Order {
+		id String
+		placed Date
+		lines []Line
+		note String?
+	}
+
+	query Parse(body: String) Order {
+		return json.parse<Order>(body)
+	}
+	query Stringify(body: String) String {
+		return json.stringify(json.parse<Order>(body))
+	}
+}`
+
+// Quotes a string as a JSON string
+func quote(str string) string {
+	bytes, _ := json.Marshal(str)
+	return string(bytes)
+}
+
+// CAN PARSE JSON AS A TYPE AND STRINGIFY IT WITHOUT LOSING PRECISION
+func TestJSON(t *testing.T) {
+	ctx, err := newContext(setupBuildContext(), ordersSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"id": "o1", "placed": "2024-02-29", "lines": [{"sku": "a", "quantity": 2, "price": 0.10}]}`
+	out, err := ctx.Invoke("Stringify", []byte(`{"body": `+quote(body)+`}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"id":"o1","lines":[{"price":"0.10","quantity":2,"sku":"a"}],"note":null,"placed":"2024-02-29"}`
+	if string(out) != quote(expected) {
+		t.Errorf("expected %s, got %s", expected, out)
+	}
+}
+
+// CANNOT PARSE JSON THAT DOESN'T FIT THE TYPE, WITH THE PATH OF THE VALUE THAT
+// DOESN'T IN THE ERROR
+func TestJSONPathErrors(t *testing.T) {
+	ctx, err := newContext(setupBuildContext(), ordersSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := map[string]string{
+		`{"id": 1, "placed": "2024-02-29", "lines": []}`:                                                                                     "id: expected String, got number",
+		`{"placed": "2024-02-29", "lines": []}`:                                                                                              "id: missing property of Order",
+		`{"id": "o1", "placed": "2024-02-29", "lines": [], "total": 1}`:                                                                      "total: unknown property of Order",
+		`{"id": "o1", "placed": "2024-02-30", "lines": []}`:                                                                                  "placed:",
+		`{"id": "o1", "placed": "2024-02-29", "lines": {}}`:                                                                                  "lines: expected array, got object",
+		`{"id": "o1", "placed": "2024-02-29", "lines": [{"sku": "a", "quantity": 1.5, "price": 1}]}`:                                         "lines[0].quantity: expected Int, got 1.5",
+		`{"id": "o1", "placed": "2024-02-29", "lines": [{"sku": "a", "quantity": 1, "price": 1}, {"sku": null, "quantity": 1, "price": 1}]}`: "lines[1].sku: expected String, got null",
+		`{"id": "o1"} trailing`: "unexpected data after value",
+		`[]`:                    "expected Order, got array",
+	}
+	for body, message := range fixtures {
+		_, err := ctx.Invoke("Parse", []byte(`{"body": `+quote(body)+`}`))
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %s to fail with %q, got %v", body, message, err)
+		}
+	}
+	if _, err := evaluate(setupBuildContext(), "json", "String", `json.parse<String, Int>("1")`); err == nil {
+		t.Errorf("expected two type arguments to fail")
+	}
+}
@@ -0,0 +1,81 @@
+package packages
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/hntrl/lang/build"
+)
+
+type LogPackage struct {
+	buildCtx *build.BuildContext
+}
+
+func (lp LogPackage) Get(key string) build.Object {
+	methods := map[string]build.Object{
+		"debug": logFunction{buildCtx: lp.buildCtx, level: slog.LevelDebug},
+		"info":  logFunction{buildCtx: lp.buildCtx, level: slog.LevelInfo},
+		"warn":  logFunction{buildCtx: lp.buildCtx, level: slog.LevelWarn},
+		"error": logFunction{buildCtx: lp.buildCtx, level: slog.LevelError},
+	}
+	return methods[key]
+}
+
+// logFunction writes a message and an optional object of fields to the logger
+// of the build context, along with where it was called from
+// i.e. log.info("order placed", { id: order.id })
+type logFunction struct {
+	buildCtx *build.BuildContext
+	level    slog.Level
+}
+
+func (fn logFunction) Get(key string) build.Object {
+	return nil
+}
+
+func (fn logFunction) ValidateArguments(args []build.Class) (build.Class, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("expected message and optional fields, got %d arguments", len(args))
+	}
+	if err := build.ShouldConstruct(build.String{}, args[0]); err != nil {
+		return nil, err
+	}
+	if len(args) == 2 {
+		if objectClass, ok := args[1].(build.ObjectClass); !ok || objectClass.Fields() == nil {
+			return nil, fmt.Errorf("expected fields object, got %s", args[1].ClassName())
+		}
+	}
+	return nil, nil
+}
+func (fn logFunction) Call(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+	return fn.CallAt(build.CallSite{}, args, proto)
+}
+func (fn logFunction) CallAt(site build.CallSite, args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+	message, err := build.Construct(build.String{}, args[0])
+	if err != nil {
+		return nil, err
+	}
+	attrs := site.Attrs()
+	if len(args) == 2 {
+		fields := args[1].Class().(build.ObjectClass).Fields()
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, _ := args[1].Get(key).(build.ValueObject)
+			attrs = append(attrs, slog.Any(key, build.ToInterface(value)))
+		}
+	}
+	// entries are timed by the clock of the build context rather than the host
+	logger := fn.buildCtx.Logger()
+	if !logger.Enabled(context.Background(), fn.level) {
+		return nil, nil
+	}
+	record := slog.NewRecord(fn.buildCtx.Now(), fn.level, string(message.(build.StringLiteral)), 0)
+	record.AddAttrs(attrs...)
+	return nil, logger.Handler().Handle(context.Background(), record)
+}
@@ -0,0 +1,119 @@
+package packages
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hntrl/lang/build"
+)
+
+const shippingSource = `import "log"
+
+context acme.shipping {
+	query Ship(id: String, weight: Int) Int {
+		log.debug("weighing", { id: id })
+		log.info("shipping " + id, { id: id, weight: weight, express: weight < 5 })
+		log.warn("carrier is slow")
+		log.error("label failed", { attempts: []Int{1, 2} })
+		return weight
+	}
+}`
+
+// Builds the shipping context with its entries written to out as JSON lines,
+// leaving out the ones below level
+func setupShipping(t *testing.T, level slog.Level, out *bytes.Buffer) *build.Context {
+	t.Helper()
+	buildCtx := setupBuildContext()
+	buildCtx.SetClock(build.NewManualClock(time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)))
+	buildCtx.SetLogHandler(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level}))
+	ctx, err := newContext(buildCtx, shippingSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+// Reads the JSON lines written by a handler
+func readEntries(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("cannot read %s: %s", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// CAN WRITE LOG ENTRIES AT EACH LEVEL WITH THEIR FIELDS AND WHERE THEY CAME FROM
+func TestLog(t *testing.T) {
+	var out bytes.Buffer
+	ctx := setupShipping(t, slog.LevelDebug, &out)
+	if _, err := ctx.Invoke("Ship", []byte(`{"id": "s1", "weight": 3}`)); err != nil {
+		t.Fatal(err)
+	}
+	entries := readEntries(t, &out)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+	for idx, level := range []string{"DEBUG", "INFO", "WARN", "ERROR"} {
+		if entries[idx]["level"] != level {
+			t.Errorf("expected entry %d to be %s, got %v", idx, level, entries[idx]["level"])
+		}
+		if entries[idx]["time"] != "2024-02-29T12:00:00Z" {
+			t.Errorf("expected entries to be timed by the clock of the build context, got %v", entries[idx]["time"])
+		}
+		if entries[idx]["context"] != "acme.shipping" || entries[idx]["method"] != "Ship" {
+			t.Errorf("expected the call site of entry %d, got %v", idx, entries[idx])
+		}
+	}
+	info := entries[1]
+	if info["msg"] != "shipping s1" || info["id"] != "s1" || info["weight"] != float64(3) || info["express"] != true {
+		t.Errorf("expected the fields of the entry, got %v", info)
+	}
+	if source, _ := info["source"].(string); !strings.Contains(source, ":6:") {
+		t.Errorf("expected the entry to come from line 6, got %v", info["source"])
+	}
+	if attempts, _ := entries[3]["attempts"].([]interface{}); len(attempts) != 2 {
+		t.Errorf("expected arrays to be written as arrays, got %v", entries[3]["attempts"])
+	}
+}
+
+// CAN LEAVE OUT THE ENTRIES BELOW THE LEVEL OF THE HANDLER
+func TestLogLevel(t *testing.T) {
+	var out bytes.Buffer
+	ctx := setupShipping(t, slog.LevelWarn, &out)
+	if _, err := ctx.Invoke("Ship", []byte(`{"id": "s1", "weight": 3}`)); err != nil {
+		t.Fatal(err)
+	}
+	entries := readEntries(t, &out)
+	if len(entries) != 2 || entries[0]["msg"] != "carrier is slow" || entries[1]["msg"] != "label failed" {
+		t.Errorf("expected only the warning and the error, got %v", entries)
+	}
+}
+
+// CANNOT LOG WITHOUT A MESSAGE OR WITH FIELDS THAT AREN'T AN OBJECT
+func TestLogErrors(t *testing.T) {
+	fixtures := map[string]string{
+		`log.info()`:            "expected message and optional fields, got 0 arguments",
+		`log.info("a", {}, {})`: "expected message and optional fields, got 3 arguments",
+		`log.info("a", 1)`:      "expected fields object, got Integer",
+		`log.info([]Int{1})`:    "cannot construct String",
+	}
+	for call, message := range fixtures {
+		src := strings.Replace(shippingSource, `log.warn("carrier is slow")`, call, 1)
+		_, err := newContext(setupBuildContext(), src)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %s to fail with %q, got %v", call, message, err)
+		}
+	}
+}
@@ -1,88 +1,371 @@
 package packages
 
 import (
+	"fmt"
 	"math"
+	"math/rand"
 
 	"github.com/hntrl/lang/build"
+	"github.com/hntrl/lang/language/tokens"
 )
 
-type MathPackage struct{}
+type MathPackage struct {
+	buildCtx *build.BuildContext
+}
+
+// Returns the value of any numeric literal as a float64
+func floatValue(obj build.ValueObject) float64 {
+	switch value := obj.(type) {
+	case build.IntegerLiteral:
+		return float64(value)
+	case build.FloatLiteral:
+		return float64(value)
+	case build.DoubleLiteral:
+		return float64(value)
+	case build.NumberLiteral:
+		return float64(value)
+	}
+	return math.NaN()
+}
+
+func argumentClasses(args []build.ValueObject) []build.Class {
+	classes := make([]build.Class, len(args))
+	for idx, arg := range args {
+		classes[idx] = arg.Class()
+	}
+	return classes
+}
+
+// Returns the class every argument of an overloaded function is widened to
+func numericArguments(args []build.Class, count int) (build.Class, error) {
+	if len(args) != count {
+		return nil, fmt.Errorf("expected %d arguments, got %d", count, len(args))
+	}
+	return build.CommonNumericClass(args...)
+}
+
+// Functions that can only be computed in floating point return Float when
+// they're called with integers
+func floatClass(class build.Class) build.Class {
+	if _, ok := class.(build.Integer); ok {
+		return build.Float{}
+	}
+	return class
+}
+
+// Creates a function that accepts any numeric class and is computed in
+// floating point
+func floatFunction(count int, fn func(x []float64) float64) build.GenericFunction {
+	return build.NewGenericFunction(build.GenericFunctionOptions{
+		Validator: func(args []build.Class) (build.Class, error) {
+			class, err := numericArguments(args, count)
+			if err != nil {
+				return nil, err
+			}
+			return floatClass(class), nil
+		},
+		Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+			class, err := numericArguments(argumentClasses(args), count)
+			if err != nil {
+				return nil, err
+			}
+			values := make([]float64, len(args))
+			for idx, arg := range args {
+				values[idx] = floatValue(arg)
+			}
+			return build.Construct(floatClass(class), build.NumberLiteral(fn(values)))
+		},
+	})
+}
+
+// Creates a function that accepts any numeric class and returns the same
+// class. Integers are handled separately so they stay exact
+func matchingFunction(count int, intFn func(x []int64) (int64, error), floatFn func(x []float64) (float64, error)) build.GenericFunction {
+	return build.NewGenericFunction(build.GenericFunctionOptions{
+		Validator: func(args []build.Class) (build.Class, error) {
+			return numericArguments(args, count)
+		},
+		Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+			class, err := numericArguments(argumentClasses(args), count)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := class.(build.Integer); ok {
+				values := make([]int64, len(args))
+				for idx, arg := range args {
+					values[idx] = int64(arg.(build.IntegerLiteral))
+				}
+				result, err := intFn(values)
+				if err != nil {
+					return nil, err
+				}
+				return build.IntegerLiteral(result), nil
+			}
+			values := make([]float64, len(args))
+			for idx, arg := range args {
+				values[idx] = floatValue(arg)
+			}
+			result, err := floatFn(values)
+			if err != nil {
+				return nil, err
+			}
+			return build.Construct(class, build.NumberLiteral(result))
+		},
+	})
+}
+
+// Creates a function that accepts any numeric class and always returns Int
+func integerFunction(fn func(x float64) float64) build.GenericFunction {
+	return build.NewGenericFunction(build.GenericFunctionOptions{
+		Validator: func(args []build.Class) (build.Class, error) {
+			if _, err := numericArguments(args, 1); err != nil {
+				return nil, err
+			}
+			return build.Integer{}, nil
+		},
+		Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+			if value, ok := args[0].(build.IntegerLiteral); ok {
+				return value, nil
+			}
+			return build.Construct(build.Integer{}, build.NumberLiteral(fn(floatValue(args[0]))))
+		},
+	})
+}
+
+// Returns the element class of an array of numbers
+func numericArray(args []build.Class) (build.Class, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected 1 argument, got %d", len(args))
+	}
+	iterable, ok := args[0].(build.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("expected array, got %s", args[0].ClassName())
+	}
+	return build.CommonNumericClass(iterable.ParentType)
+}
+
+func sumArray(iterable build.Iterable) (build.ValueObject, error) {
+	sum, err := build.Construct(iterable.ParentType, build.IntegerLiteral(0))
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range iterable.Items {
+		sum, err = build.Operate(tokens.ADD, sum, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sum, nil
+}
+
+func unaryFloat(fn func(float64) float64) func(x []float64) float64 {
+	return func(x []float64) float64 {
+		return fn(x[0])
+	}
+}
+func binaryFloat(fn func(float64, float64) float64) func(x []float64) float64 {
+	return func(x []float64) float64 {
+		return fn(x[0], x[1])
+	}
+}
+
+// Returns a random integer in [min, max). The size of the range is worked out
+// as a uint64 since it can be too large for an int64, in which case values
+// that are out of range are drawn again
+func randomInt(r *rand.Rand, min, max int64) int64 {
+	n := uint64(max) - uint64(min)
+	if n <= math.MaxInt64 {
+		return min + r.Int63n(int64(n))
+	}
+	for {
+		if v := r.Uint64(); v < n {
+			return int64(uint64(min) + v)
+		}
+	}
+}
 
 func (mp MathPackage) Get(key string) build.Object {
 	methods := map[string]build.Object{
-		// "Abs":  AbsFunction{},
-		// "Acos": AcosFunction{},
-		// "Asin": AsinFunction{},
-		// "Atan": AtanFunction{},
-		// "Atan2": Atan2Function{},
-		"Ceil": build.NewFunction(build.FunctionOptions{
-			Arguments: []build.Class{
-				build.Float{},
+		"E":     build.FloatLiteral(math.E),
+		"Pi":    build.FloatLiteral(math.Pi),
+		"Sqrt2": build.FloatLiteral(math.Sqrt2),
+		"Abs": matchingFunction(1, func(x []int64) (int64, error) {
+			if x[0] == math.MinInt64 {
+				return 0, fmt.Errorf("integer overflow")
+			}
+			if x[0] < 0 {
+				return -x[0], nil
+			}
+			return x[0], nil
+		}, func(x []float64) (float64, error) {
+			return math.Abs(x[0]), nil
+		}),
+		"Acos":  floatFunction(1, unaryFloat(math.Acos)),
+		"Acosh": floatFunction(1, unaryFloat(math.Acosh)),
+		"Asin":  floatFunction(1, unaryFloat(math.Asin)),
+		"Asinh": floatFunction(1, unaryFloat(math.Asinh)),
+		"Atan":  floatFunction(1, unaryFloat(math.Atan)),
+		"Atan2": floatFunction(2, binaryFloat(math.Atan2)),
+		"Atanh": floatFunction(1, unaryFloat(math.Atanh)),
+		"Avg": build.NewGenericFunction(build.GenericFunctionOptions{
+			Validator: func(args []build.Class) (build.Class, error) {
+				class, err := numericArray(args)
+				if err != nil {
+					return nil, err
+				}
+				return floatClass(class), nil
 			},
-			Returns: build.Integer{},
 			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
-				num := args[0].(build.FloatLiteral)
-				return build.IntegerLiteral(math.Ceil(float64(num))), nil
+				iterable := args[0].(build.Iterable)
+				if len(iterable.Items) == 0 {
+					return nil, fmt.Errorf("cannot average an empty array")
+				}
+				sum := 0.0
+				for _, item := range iterable.Items {
+					sum += floatValue(item)
+				}
+				return build.Construct(floatClass(iterable.ParentType), build.NumberLiteral(sum/float64(len(iterable.Items))))
 			},
 		}),
-		// "Cos": CosFunction{},
-		"Floor": build.NewFunction(build.FunctionOptions{
+		"Cbrt": floatFunction(1, unaryFloat(math.Cbrt)),
+		"Ceil": integerFunction(math.Ceil),
+		"Clamp": matchingFunction(3, func(x []int64) (int64, error) {
+			if x[1] > x[2] {
+				return 0, fmt.Errorf("invalid range [%d, %d]", x[1], x[2])
+			}
+			if x[0] < x[1] {
+				return x[1], nil
+			}
+			if x[0] > x[2] {
+				return x[2], nil
+			}
+			return x[0], nil
+		}, func(x []float64) (float64, error) {
+			if x[1] > x[2] {
+				return 0, fmt.Errorf("invalid range [%v, %v]", x[1], x[2])
+			}
+			return math.Min(math.Max(x[0], x[1]), x[2]), nil
+		}),
+		"Cos":   floatFunction(1, unaryFloat(math.Cos)),
+		"Cosh":  floatFunction(1, unaryFloat(math.Cosh)),
+		"Exp":   floatFunction(1, unaryFloat(math.Exp)),
+		"Exp2":  floatFunction(1, unaryFloat(math.Exp2)),
+		"Floor": integerFunction(math.Floor),
+		"Hypot": floatFunction(2, binaryFloat(math.Hypot)),
+		"Inf": build.NewFunction(build.FunctionOptions{
 			Arguments: []build.Class{
-				build.Float{},
+				build.Integer{},
 			},
-			Returns: build.Integer{},
+			Returns: build.Float{},
 			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
-				num := args[0].(build.FloatLiteral)
-				return build.IntegerLiteral(math.Floor(float64(num))), nil
+				return build.FloatLiteral(math.Inf(int(args[0].(build.IntegerLiteral)))), nil
 			},
 		}),
-		"Log": build.NewFunction(build.FunctionOptions{
+		"IsInf": build.NewFunction(build.FunctionOptions{
 			Arguments: []build.Class{
-				build.Float{},
+				build.Double{},
+				build.Integer{},
 			},
-			Returns: build.Float{},
+			Returns: build.Boolean{},
 			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
-				num := args[0].(build.FloatLiteral)
-				return build.FloatLiteral(math.Log(float64(num))), nil
+				return build.BooleanLiteral(math.IsInf(float64(args[0].(build.DoubleLiteral)), int(args[1].(build.IntegerLiteral)))), nil
 			},
 		}),
-		"Max": build.NewFunction(build.FunctionOptions{
+		"IsNaN": build.NewFunction(build.FunctionOptions{
 			Arguments: []build.Class{
-				build.Float{},
-				build.Float{},
+				build.Double{},
 			},
-			Returns: build.Float{},
+			Returns: build.Boolean{},
 			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
-				x := args[0].(build.FloatLiteral)
-				y := args[1].(build.FloatLiteral)
-				return build.FloatLiteral(math.Max(float64(x), float64(y))), nil
+				return build.BooleanLiteral(math.IsNaN(float64(args[0].(build.DoubleLiteral)))), nil
 			},
 		}),
-		"Min": build.NewFunction(build.FunctionOptions{
-			Arguments: []build.Class{
-				build.Float{},
-				build.Float{},
+		"Log":   floatFunction(1, unaryFloat(math.Log)),
+		"Log10": floatFunction(1, unaryFloat(math.Log10)),
+		"Log1p": floatFunction(1, unaryFloat(math.Log1p)),
+		"Log2":  floatFunction(1, unaryFloat(math.Log2)),
+		"Max": matchingFunction(2, func(x []int64) (int64, error) {
+			if x[1] > x[0] {
+				return x[1], nil
+			}
+			return x[0], nil
+		}, func(x []float64) (float64, error) {
+			return math.Max(x[0], x[1]), nil
+		}),
+		"Min": matchingFunction(2, func(x []int64) (int64, error) {
+			if x[1] < x[0] {
+				return x[1], nil
+			}
+			return x[0], nil
+		}, func(x []float64) (float64, error) {
+			return math.Min(x[0], x[1]), nil
+		}),
+		"Mod": matchingFunction(2, func(x []int64) (int64, error) {
+			if x[1] == 0 {
+				return 0, fmt.Errorf("integer division by zero")
+			}
+			return x[0] % x[1], nil
+		}, func(x []float64) (float64, error) {
+			return math.Mod(x[0], x[1]), nil
+		}),
+		"NaN": build.NewFunction(build.FunctionOptions{
+			Returns: build.Float{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return build.FloatLiteral(math.NaN()), nil
 			},
+		}),
+		"Pow": matchingFunction(2, func(x []int64) (int64, error) {
+			// integer powers share the overflow checks of the ** operator
+			result, err := build.Operate(tokens.PWR, build.IntegerLiteral(x[0]), build.IntegerLiteral(x[1]))
+			if err != nil {
+				return 0, err
+			}
+			return int64(result.(build.IntegerLiteral)), nil
+		}, func(x []float64) (float64, error) {
+			return math.Pow(x[0], x[1]), nil
+		}),
+		"Random": build.NewFunction(build.FunctionOptions{
 			Returns: build.Float{},
 			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
-				x := args[0].(build.FloatLiteral)
-				y := args[1].(build.FloatLiteral)
-				return build.FloatLiteral(math.Min(float64(x), float64(y))), nil
+				return build.FloatLiteral(mp.buildCtx.Random().Float64()), nil
 			},
 		}),
-		"Round": build.NewFunction(build.FunctionOptions{
+		"RandomInt": build.NewFunction(build.FunctionOptions{
 			Arguments: []build.Class{
-				build.Float{},
+				build.Integer{},
+				build.Integer{},
 			},
-			Returns: build.Float{},
+			Returns: build.Integer{},
 			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
-				num := args[0].(build.FloatLiteral)
-				return build.IntegerLiteral(math.Round(float64(num))), nil
+				min := int64(args[0].(build.IntegerLiteral))
+				max := int64(args[1].(build.IntegerLiteral))
+				if max <= min {
+					return nil, fmt.Errorf("invalid range [%d, %d)", min, max)
+				}
+				return build.IntegerLiteral(randomInt(mp.buildCtx.Random(), min, max)), nil
 			},
 		}),
-		// "Sin": SinFunction{},
-		// "Sqrt": SqrtFunction{},
-		// "Tan": TanFunction{},
+		"Round": matchingFunction(1, func(x []int64) (int64, error) {
+			return x[0], nil
+		}, func(x []float64) (float64, error) {
+			return math.Round(x[0]), nil
+		}),
+		"Sin":  floatFunction(1, unaryFloat(math.Sin)),
+		"Sinh": floatFunction(1, unaryFloat(math.Sinh)),
+		"Sqrt": floatFunction(1, unaryFloat(math.Sqrt)),
+		"Sum": build.NewGenericFunction(build.GenericFunctionOptions{
+			Validator: numericArray,
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return sumArray(args[0].(build.Iterable))
+			},
+		}),
+		"Tan":  floatFunction(1, unaryFloat(math.Tan)),
+		"Tanh": floatFunction(1, unaryFloat(math.Tanh)),
+		"Trunc": matchingFunction(1, func(x []int64) (int64, error) {
+			return x[0], nil
+		}, func(x []float64) (float64, error) {
+			return math.Trunc(x[0]), nil
+		}),
 	}
 	return methods[key]
 }
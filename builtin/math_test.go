@@ -0,0 +1,81 @@
+package packages
+
+import (
+	"strings"
+	"testing"
+)
+
+// CAN CALL MATH FUNCTIONS WITH INTEGERS AND FLOATS, KEEPING INTEGERS EXACT
+// WHERE THE FUNCTION ALLOWS IT
+func TestMath(t *testing.T) {
+	type MathFixture struct {
+		Expr     string
+		Returns  string
+		Expected string
+	}
+	fixtures := []MathFixture{
+		{`math.Abs(-9223372036854775807)`, "Int", "9223372036854775807"},
+		{`math.Abs(-2.5)`, "Float", "2.5"},
+		{`math.Max(3, 7)`, "Int", "7"},
+		{`math.Min(3, 2.5)`, "Float", "2.5"},
+		{`math.Clamp(12, 0, 10)`, "Int", "10"},
+		{`math.Clamp(-0.5, 0.0, 1.0)`, "Float", "0"},
+		{`math.Mod(-7, 3)`, "Int", "-1"},
+		{`math.Pow(2, 62)`, "Int", "4611686018427387904"},
+		{`math.Pow(2.0, -1.0)`, "Float", "0.5"},
+		{`math.Sqrt(16)`, "Float", "4"},
+		{`math.Hypot(3, 4)`, "Float", "5"},
+		{`math.Ceil(1.2)`, "Int", "2"},
+		{`math.Floor(-1.2)`, "Int", "-2"},
+		{`math.Round(2.5)`, "Float", "3"},
+		{`math.Trunc(-2.7)`, "Float", "-2"},
+		{`math.Sum([]Int{1, 2, 3})`, "Int", "6"},
+		{`math.Sum([]Int{})`, "Int", "0"},
+		{`math.Avg([]Int{1, 2})`, "Float", "1.5"},
+		{`math.IsNaN(math.NaN())`, "Bool", "true"},
+		{`math.IsInf(math.Inf(-1), -1)`, "Bool", "true"},
+	}
+	for _, fixture := range fixtures {
+		if out := mustEvaluate(t, "math", fixture.Returns, fixture.Expr); out != fixture.Expected {
+			t.Errorf("expected %s to be %s, got %s", fixture.Expr, fixture.Expected, out)
+		}
+	}
+}
+
+// CANNOT CALL MATH FUNCTIONS THAT WOULD OVERFLOW, DIVIDE BY ZERO OR HAVE AN
+// EMPTY RANGE
+func TestMathErrors(t *testing.T) {
+	fixtures := map[string]string{
+		`math.Abs((-9223372036854775807) - 1)`: "integer overflow",
+		`math.Pow(2, 63)`:                      "overflow",
+		`math.Mod(1, 0)`:                       "integer division by zero",
+		`math.Clamp(1, 10, 0)`:                 "invalid range [10, 0]",
+		`math.RandomInt(5, 5)`:                 "invalid range [5, 5)",
+		`math.Avg([]Int{})`:                    "cannot average an empty array",
+		`math.Sum(1)`:                          "expected array, got Integer",
+		`math.Max(1)`:                          "expected 2 arguments, got 1",
+	}
+	for expr, message := range fixtures {
+		_, err := evaluate(setupBuildContext(), "math", "Float", expr)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %s to fail with %q, got %v", expr, message, err)
+		}
+	}
+}
+
+// CAN DRAW THE SAME RANDOM NUMBERS FOR THE SAME SEED, WITHIN THEIR RANGE
+func TestMathRandom(t *testing.T) {
+	first := mustEvaluate(t, "math", "Int", `math.RandomInt(0, 1000000)`)
+	if second := mustEvaluate(t, "math", "Int", `math.RandomInt(0, 1000000)`); first != second {
+		t.Errorf("expected the same seed to draw the same number, got %s and %s", first, second)
+	}
+	for _, expr := range []string{
+		`math.RandomInt((-9223372036854775807) - 1, 9223372036854775807) != 0`,
+		`math.Random() < 1.0`,
+		`math.RandomInt(-3, -2) == -3`,
+	} {
+		if out := mustEvaluate(t, "math", "Bool", expr); out != "true" {
+			t.Errorf("expected %s to be true", expr)
+		}
+	}
+}
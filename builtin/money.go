@@ -0,0 +1,267 @@
+package packages
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hntrl/lang/build"
+	"github.com/hntrl/lang/language/tokens"
+)
+
+type MoneyPackage struct{}
+
+func (mp MoneyPackage) Get(key string) build.Object {
+	objects := map[string]build.Object{
+		"Money": Money{},
+	}
+	return objects[key]
+}
+
+// The number of digits after the decimal point for currencies that don't use
+// two. Everything else is assumed to use two
+var currencyMinorUnits = map[string]int32{
+	"BHD": 3,
+	"CLP": 0,
+	"IQD": 3,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"UGX": 0,
+	"VND": 0,
+	"XAF": 0,
+	"XOF": 0,
+}
+
+func minorUnits(currency string) int32 {
+	if units, ok := currencyMinorUnits[currency]; ok {
+		return units
+	}
+	return 2
+}
+
+func validateCurrency(currency string) error {
+	if len(currency) != 3 {
+		return fmt.Errorf("invalid currency %q", currency)
+	}
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return fmt.Errorf("invalid currency %q", currency)
+		}
+	}
+	return nil
+}
+
+type Money struct{}
+
+func (m Money) ClassName() string {
+	return "Money"
+}
+func (m Money) Fields() map[string]build.Class {
+	return map[string]build.Class{
+		"amount":   build.Decimal{},
+		"currency": build.String{},
+	}
+}
+func (m Money) Constructors() build.ConstructorMap {
+	csMap := build.NewConstructorMap()
+	csMap.AddConstructor(Money{}, func(obj build.ValueObject) (build.ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddConstructor(build.String{}, func(obj build.ValueObject) (build.ValueObject, error) {
+		return ParseMoney(string(obj.(build.StringLiteral)))
+	})
+	csMap.AddGenericConstructor(m, func(fields map[string]build.ValueObject) (build.ValueObject, error) {
+		currency := string(fields["currency"].(build.StringLiteral))
+		if err := validateCurrency(currency); err != nil {
+			return nil, err
+		}
+		return MoneyLiteral{
+			Amount:   fields["amount"].(build.DecimalLiteral),
+			Currency: currency,
+		}, nil
+	})
+	return csMap
+}
+
+func (m Money) ToString(obj build.ValueObject) (string, error) {
+	return obj.(MoneyLiteral).String(), nil
+}
+
+func moneyComparePredicate(cb func(cmp int) bool) build.OperatorFn {
+	return func(a, b build.ValueObject) (build.ValueObject, error) {
+		ma, mb := a.(MoneyLiteral), b.(MoneyLiteral)
+		if ma.Currency != mb.Currency {
+			return nil, fmt.Errorf("cannot compare %s and %s", ma.Currency, mb.Currency)
+		}
+		return build.BooleanLiteral(cb(ma.Amount.Cmp(mb.Amount))), nil
+	}
+}
+
+// Amounts in different currencies are never equal, so unlike ordering them
+// comparing them for equality isn't an error
+func moneyEqualsPredicate(equals bool) build.OperatorFn {
+	return func(a, b build.ValueObject) (build.ValueObject, error) {
+		ma, mb := a.(MoneyLiteral), b.(MoneyLiteral)
+		same := ma.Currency == mb.Currency && ma.Amount.Cmp(mb.Amount) == 0
+		return build.BooleanLiteral(same == equals), nil
+	}
+}
+func (m Money) ComparableRules() build.ComparatorRules {
+	rules := build.NewComparatorRules()
+	rules.AddComparator(Money{}, tokens.EQUALS, moneyEqualsPredicate(true))
+	rules.AddComparator(Money{}, tokens.NOT_EQUALS, moneyEqualsPredicate(false))
+	rules.AddComparator(Money{}, tokens.LESS, moneyComparePredicate(func(cmp int) bool {
+		return cmp < 0
+	}))
+	rules.AddComparator(Money{}, tokens.GREATER, moneyComparePredicate(func(cmp int) bool {
+		return cmp > 0
+	}))
+	rules.AddComparator(Money{}, tokens.LESS_EQUAL, moneyComparePredicate(func(cmp int) bool {
+		return cmp <= 0
+	}))
+	rules.AddComparator(Money{}, tokens.GREATER_EQUAL, moneyComparePredicate(func(cmp int) bool {
+		return cmp >= 0
+	}))
+	return rules
+}
+
+func moneyOperatorPredicate(token tokens.Token) build.OperatorFn {
+	verb := map[tokens.Token]string{tokens.ADD: "add", tokens.SUB: "subtract"}[token]
+	return func(a, b build.ValueObject) (build.ValueObject, error) {
+		ma, mb := a.(MoneyLiteral), b.(MoneyLiteral)
+		if ma.Currency != mb.Currency {
+			return nil, fmt.Errorf("cannot %s %s and %s", verb, ma.Currency, mb.Currency)
+		}
+		if token == tokens.SUB {
+			return MoneyLiteral{Amount: ma.Amount.Sub(mb.Amount), Currency: ma.Currency}, nil
+		}
+		return MoneyLiteral{Amount: ma.Amount.Add(mb.Amount), Currency: ma.Currency}, nil
+	}
+}
+func moneyScalePredicate(token tokens.Token) build.OperatorFn {
+	return func(a, b build.ValueObject) (build.ValueObject, error) {
+		ma := a.(MoneyLiteral)
+		factor, err := build.Construct(build.Decimal{}, b)
+		if err != nil {
+			return nil, err
+		}
+		if token == tokens.QUO {
+			amount, err := ma.Amount.Quo(factor.(build.DecimalLiteral))
+			if err != nil {
+				return nil, err
+			}
+			return MoneyLiteral{Amount: amount, Currency: ma.Currency}, nil
+		}
+		return MoneyLiteral{Amount: ma.Amount.Mul(factor.(build.DecimalLiteral)), Currency: ma.Currency}, nil
+	}
+}
+func (m Money) OperatorRules() build.OperatorRules {
+	rules := build.NewOperatorRules()
+	rules.AddOperator(Money{}, tokens.ADD, moneyOperatorPredicate(tokens.ADD))
+	rules.AddOperator(Money{}, tokens.SUB, moneyOperatorPredicate(tokens.SUB))
+	for _, class := range []build.Class{build.Decimal{}, build.Integer{}} {
+		rules.AddOperator(class, tokens.MUL, moneyScalePredicate(tokens.MUL))
+		rules.AddOperator(class, tokens.QUO, moneyScalePredicate(tokens.QUO))
+	}
+	return rules
+}
+
+func (m Money) Get(key string) build.Object {
+	// methods available on the instance so they can be validated without a value
+	return MoneyLiteral{}.Get(key)
+}
+
+// MoneyLiteral represents an exact amount in a single ISO 4217 currency
+type MoneyLiteral struct {
+	Amount   build.DecimalLiteral
+	Currency string
+}
+
+// Parses money from its string representation, i.e. "12.50 USD"
+func ParseMoney(str string) (MoneyLiteral, error) {
+	parts := strings.Fields(str)
+	if len(parts) != 2 {
+		return MoneyLiteral{}, fmt.Errorf("cannot parse %q as Money", str)
+	}
+	amount, err := build.ParseDecimal(parts[0])
+	if err != nil {
+		return MoneyLiteral{}, err
+	}
+	if err := validateCurrency(parts[1]); err != nil {
+		return MoneyLiteral{}, err
+	}
+	return MoneyLiteral{Amount: amount, Currency: parts[1]}, nil
+}
+
+func (ml MoneyLiteral) Class() build.Class {
+	return Money{}
+}
+func (ml MoneyLiteral) Value() interface{} {
+	return map[string]interface{}{
+		"amount":   ml.Amount.String(),
+		"currency": ml.Currency,
+	}
+}
+func (ml MoneyLiteral) Set(key string, obj build.ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, ml.Class().ClassName())
+}
+func (ml MoneyLiteral) Get(key string) build.Object {
+	switch key {
+	case "amount":
+		return ml.Amount
+	case "currency":
+		return build.StringLiteral(ml.Currency)
+	case "format":
+		return build.NewFunction(build.FunctionOptions{
+			Returns: build.String{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return build.StringLiteral(ml.String()), nil
+			},
+		})
+	case "round":
+		return build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+			},
+			Returns: Money{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				mode, err := build.ParseRoundingMode(string(args[0].(build.StringLiteral)))
+				if err != nil {
+					return nil, err
+				}
+				return MoneyLiteral{Amount: ml.Amount.Rescale(minorUnits(ml.Currency), mode), Currency: ml.Currency}, nil
+			},
+		})
+	}
+	return nil
+}
+
+func (ml MoneyLiteral) String() string {
+	return ml.Amount.String() + " " + ml.Currency
+}
+
+type moneyJSON struct {
+	Amount   build.DecimalLiteral `json:"amount"`
+	Currency string               `json:"currency"`
+}
+
+func (ml MoneyLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON(ml))
+}
+func (ml *MoneyLiteral) UnmarshalJSON(data []byte) error {
+	var obj moneyJSON
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if err := validateCurrency(obj.Currency); err != nil {
+		return err
+	}
+	*ml = MoneyLiteral(obj)
+	return nil
+}
@@ -0,0 +1,118 @@
+package packages
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// CAN ADD, SUBTRACT AND SCALE MONEY WITHOUT LOSING PRECISION
+func TestMoneyArithmetic(t *testing.T) {
+	fixtures := map[string]string{
+		`money.Money("0.10 USD") + money.Money("0.20 USD")`:      `"0.30 USD"`,
+		`money.Money("10.00 EUR") - money.Money("12.50 EUR")`:    `"-2.50 EUR"`,
+		`money.Money("19.99 USD") * 3`:                           `"59.97 USD"`,
+		`money.Money("19.99 USD") * Decimal("1.5")`:              `"29.985 USD"`,
+		`money.Money("10.00 USD") / 4`:                           `"2.50 USD"`,
+		`money.Money("1 JPY") + money.Money("2 JPY")`:            `"3 JPY"`,
+		`money.Money({ amount: Decimal("5"), currency: "GBP" })`: `"5 GBP"`,
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "money", "String", "String("+expr+")"); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
+
+// CAN COMPARE MONEY, WHICH IS NEVER EQUAL IN DIFFERENT CURRENCIES
+func TestMoneyComparison(t *testing.T) {
+	fixtures := map[string]string{
+		`money.Money("10 USD") == money.Money("10.00 USD")`: "true",
+		`money.Money("10 USD") == money.Money("10 EUR")`:    "false",
+		`money.Money("10 USD") != money.Money("10 EUR")`:    "true",
+		`money.Money("9.99 USD") < money.Money("10 USD")`:   "true",
+		`money.Money("9.99 USD") >= money.Money("10 USD")`:  "false",
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "money", "Bool", expr); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
+
+// CAN ROUND MONEY TO THE MINOR UNITS OF ITS CURRENCY
+func TestMoneyRounding(t *testing.T) {
+	fixtures := map[string]string{
+		`money.Money("2.345 USD").round("halfEven")`:  `"2.34 USD"`,
+		`money.Money("2.345 USD").round("halfUp")`:    `"2.35 USD"`,
+		`money.Money("2.341 USD").round("up")`:        `"2.35 USD"`,
+		`money.Money("-2.345 USD").round("floor")`:    `"-2.35 USD"`,
+		`money.Money("1234.5 JPY").round("halfEven")`: `"1234 JPY"`,
+		`money.Money("1.2345 KWD").round("halfUp")`:   `"1.235 KWD"`,
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "money", "String", "String("+expr+")"); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+	if _, err := evaluate(setupBuildContext(), "money", "money.Money", `money.Money("1 USD").round("sideways")`); err == nil {
+		t.Errorf("expected an unknown rounding mode to fail")
+	}
+}
+
+// CANNOT COMBINE OR ORDER MONEY IN DIFFERENT CURRENCIES, OR MAKE MONEY WITH A
+// CURRENCY THAT ISN'T ONE
+func TestMoneyErrors(t *testing.T) {
+	fixtures := map[string]string{
+		`money.Money("1 USD") + money.Money("1 EUR")`:                "cannot add USD and EUR",
+		`money.Money("1 USD") - money.Money("1 EUR")`:                "cannot subtract USD and EUR",
+		`money.Money("1 USD") / 0`:                                   "division by zero",
+		`money.Money("1 usd")`:                                       "invalid currency",
+		`money.Money("1")`:                                           "cannot parse",
+		`money.Money({ amount: Decimal("1"), currency: "DOLLARS" })`: "invalid currency",
+	}
+	for expr, message := range fixtures {
+		_, err := evaluate(setupBuildContext(), "money", "money.Money", expr)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %s to fail with %q, got %v", expr, message, err)
+		}
+	}
+	_, err := evaluate(setupBuildContext(), "money", "Bool", `money.Money("1 USD") < money.Money("1 EUR")`)
+	if err == nil || !strings.Contains(err.Error(), "cannot compare USD and EUR") {
+		t.Errorf("expected ordering different currencies to fail, got %v", err)
+	}
+}
+
+// CAN MAKE MONEY INTO A STRING AND BACK
+func TestMoneyString(t *testing.T) {
+	fixtures := map[string]string{
+		`String(money.Money("12.50 USD"))`:                       `"12.50 USD"`,
+		"`total: ${money.Money(\"12.50 USD\")}`":                 `"total: 12.50 USD"`,
+		`strings.format("%s due", money.Money("3 EUR"))`:         `"3 EUR due"`,
+		`money.Money(String(money.Money("12.50 USD"))).format()`: `"12.50 USD"`,
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "money strings", "String", expr); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
+
+// CAN ENCODE MONEY AS JSON WITH AN EXACT AMOUNT AND DECODE IT AGAIN
+func TestMoneyJSON(t *testing.T) {
+	out := mustEvaluate(t, "money json", "String", `json.stringify(money.Money("0.10 USD"))`)
+	var str string
+	if err := json.Unmarshal([]byte(out), &str); err != nil {
+		t.Fatal(err)
+	}
+	if str != `{"amount":"0.10","currency":"USD"}` {
+		t.Errorf("expected the amount to be kept as a string, got %s", str)
+	}
+	out = mustEvaluate(t, "money json", "String", `String(json.parse<money.Money>("{\"amount\": \"0.10\", \"currency\": \"USD\"}"))`)
+	if out != `"0.10 USD"` {
+		t.Errorf("expected the money to be decoded, got %s", out)
+	}
+	if _, err := evaluate(setupBuildContext(), "money json", "money.Money", `json.parse<money.Money>("{\"amount\": \"0.10\", \"currency\": \"usd\"}")`); err == nil {
+		t.Errorf("expected an invalid currency to fail to decode")
+	}
+}
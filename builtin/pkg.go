@@ -6,7 +6,16 @@ import (
 
 func RegisterDefaults(ctx *build.BuildContext) {
 	// Register the default packages
-	ctx.RegisterPackage("math", MathPackage{})
+	ctx.RegisterPackage("math", MathPackage{buildCtx: ctx})
 	ctx.RegisterPackage("errors", ErrorsPackage{})
 	ctx.RegisterPackage("units", UnitsPackage{})
+	ctx.RegisterPackage("strings", StringsPackage{})
+	ctx.RegisterPackage("money", MoneyPackage{})
+	ctx.RegisterPackage("json", JSONPackage{})
+	ctx.RegisterPackage("regex", NewRegexPackage())
+	ctx.RegisterPackage("crypto", CryptoPackage{})
+	ctx.RegisterPackage("encoding", EncodingPackage{})
+	ctx.RegisterPackage("ids", IdsPackage{buildCtx: ctx})
+	ctx.RegisterPackage("log", LogPackage{buildCtx: ctx})
+	RegisterConfig(ctx, ConfigSources{})
 }
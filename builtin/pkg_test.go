@@ -0,0 +1,73 @@
+package packages
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/hntrl/lang/build"
+	"github.com/hntrl/lang/language/nodes"
+	"github.com/hntrl/lang/language/parser"
+	"github.com/hntrl/lang/language/tokens"
+)
+
+// Returns a build context with the default packages that doesn't write
+// anywhere and has a fixed seed
+func setupBuildContext() *build.BuildContext {
+	buildCtx := build.NewBuildContext()
+	buildCtx.SetSeed(1)
+	buildCtx.SetOutput(io.Discard)
+	buildCtx.SetLogHandler(slog.NewTextHandler(io.Discard, nil))
+	RegisterDefaults(buildCtx)
+	return buildCtx
+}
+
+// Builds a context from its source without attaching it
+func newContext(buildCtx *build.BuildContext, src string) (*build.Context, error) {
+	var lexErr error
+	reader := bufio.NewReader(strings.NewReader(src))
+	lexer := parser.NewLexer(reader, func(pos tokens.Position, msg string) {
+		if lexErr == nil {
+			lexErr = fmt.Errorf("%s, %s", pos.String(), msg)
+		}
+	})
+	manifest, err := nodes.ParseManifest(parser.NewParser(lexer))
+	if lexErr != nil {
+		return nil, lexErr
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot parse: %s", err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("cannot validate: %s", err)
+	}
+	return build.NewContext(buildCtx, manifest.Context.Name, *manifest)
+}
+
+// Evaluates an expression in a query of a context that imports the packages,
+// returning the result as JSON
+func evaluate(buildCtx *build.BuildContext, imports string, returns string, expr string) (string, error) {
+	var src strings.Builder
+	for _, pkg := range strings.Fields(imports) {
+		fmt.Fprintf(&src, "import %q\n", pkg)
+	}
+	fmt.Fprintf(&src, "context acme.test {\n\tquery Run() %s {\n\t\treturn %s\n\t}\n}", returns, expr)
+	ctx, err := newContext(buildCtx, src.String())
+	if err != nil {
+		return "", err
+	}
+	out, err := ctx.Invoke("Run", nil)
+	return string(out), err
+}
+
+// Evaluates an expression, failing the test if it can't be
+func mustEvaluate(t *testing.T, imports string, returns string, expr string) string {
+	t.Helper()
+	out, err := evaluate(setupBuildContext(), imports, returns, expr)
+	if err != nil {
+		t.Fatalf("cannot evaluate %s: %s", expr, err)
+	}
+	return out
+}
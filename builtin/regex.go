@@ -0,0 +1,363 @@
+package packages
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/hntrl/lang/build"
+)
+
+// How many compiled patterns a build context keeps before it forgets the ones
+// that were used the longest time ago
+const regexCacheSize = 256
+
+// regexCache holds the patterns that were compiled most recently in a build
+// context so that patterns compiled in a loop or a hot method are only compiled
+// once. Patterns can come from strings made at runtime, so only so many are
+// kept
+type regexCache struct {
+	mu   sync.Mutex
+	size int
+	// the entries ordered by when they were last used, most recent first
+	order    *list.List
+	patterns map[string]*list.Element
+}
+
+type regexCacheEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRegexCache() *regexCache {
+	return &regexCache{
+		size:     regexCacheSize,
+		order:    list.New(),
+		patterns: make(map[string]*list.Element),
+	}
+}
+
+func (c *regexCache) compile(pattern string) (*regexp.Regexp, error) {
+	if c == nil {
+		return regexp.Compile(pattern)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.patterns[pattern]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(regexCacheEntry).re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	c.patterns[pattern] = c.order.PushFront(regexCacheEntry{pattern, re})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.patterns, oldest.Value.(regexCacheEntry).pattern)
+	}
+	return re, nil
+}
+
+type RegexPackage struct {
+	cache *regexCache
+}
+
+func NewRegexPackage() RegexPackage {
+	return RegexPackage{cache: newRegexCache()}
+}
+
+func (rp RegexPackage) Get(key string) build.Object {
+	objects := map[string]build.Object{
+		"Regex": Regex{cache: rp.cache},
+		"Match": Match{},
+		"compile": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+			},
+			Returns: Regex{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return rp.cache.compileLiteral(string(args[0].(build.StringLiteral)))
+			},
+		}),
+		"escape": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+			},
+			Returns: build.String{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return build.StringLiteral(regexp.QuoteMeta(string(args[0].(build.StringLiteral)))), nil
+			},
+		}),
+	}
+	return objects[key]
+}
+
+func (c *regexCache) compileLiteral(pattern string) (RegexLiteral, error) {
+	re, err := c.compile(pattern)
+	if err != nil {
+		return RegexLiteral{}, err
+	}
+	// matches has to cover the whole string, so it gets its own anchored pattern
+	anchored, err := c.compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return RegexLiteral{}, err
+	}
+	return RegexLiteral{re: re, anchored: anchored}, nil
+}
+
+// Regex represents a compiled regular expression
+type Regex struct {
+	// ignored when comparing classes since it's unexported
+	cache *regexCache
+}
+
+func (r Regex) ClassName() string {
+	return "Regex"
+}
+func (r Regex) Constructors() build.ConstructorMap {
+	csMap := build.NewConstructorMap()
+	csMap.AddConstructor(Regex{}, func(obj build.ValueObject) (build.ValueObject, error) {
+		return obj, nil
+	})
+	csMap.AddConstructor(build.String{}, func(obj build.ValueObject) (build.ValueObject, error) {
+		return r.cache.compileLiteral(string(obj.(build.StringLiteral)))
+	})
+	return csMap
+}
+func (r Regex) Get(key string) build.Object {
+	// methods available on the instance so they can be validated without a value
+	return RegexLiteral{}.Get(key)
+}
+
+type RegexLiteral struct {
+	re       *regexp.Regexp
+	anchored *regexp.Regexp
+}
+
+func (rl RegexLiteral) Class() build.Class {
+	return Regex{}
+}
+func (rl RegexLiteral) Value() interface{} {
+	return rl.re.String()
+}
+func (rl RegexLiteral) Set(key string, obj build.ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, rl.Class().ClassName())
+}
+
+// Converts the byte offsets of a match into a Match
+func (rl RegexLiteral) newMatch(str string, loc []int) MatchLiteral {
+	match := MatchLiteral{
+		Text:   str[loc[0]:loc[1]],
+		Start:  utf8.RuneCountInString(str[:loc[0]]),
+		End:    utf8.RuneCountInString(str[:loc[1]]),
+		Groups: make([]string, len(loc)/2-1),
+	}
+	for idx := range match.Groups {
+		if start := loc[2*idx+2]; start >= 0 {
+			match.Groups[idx] = str[start:loc[2*idx+3]]
+		}
+	}
+	return match
+}
+
+func (rl RegexLiteral) Get(key string) build.Object {
+	switch key {
+	case "pattern":
+		if rl.re == nil {
+			return build.StringLiteral("")
+		}
+		return build.StringLiteral(rl.re.String())
+	case "matches":
+		return build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+			},
+			Returns: build.Boolean{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return build.BooleanLiteral(rl.anchored.MatchString(string(args[0].(build.StringLiteral)))), nil
+			},
+		})
+	case "find":
+		return build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+			},
+			Returns: build.NewOptionalClass(Match{}),
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				str := string(args[0].(build.StringLiteral))
+				loc := rl.re.FindStringSubmatchIndex(str)
+				if loc == nil {
+					return build.NilableObject{ClassObject: Match{}}, nil
+				}
+				return build.NilableObject{ClassObject: Match{}, Object: rl.newMatch(str, loc)}, nil
+			},
+		})
+	case "findAll":
+		return build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+			},
+			Returns: build.NewIterable(Match{}, 0),
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				str := string(args[0].(build.StringLiteral))
+				locs := rl.re.FindAllStringSubmatchIndex(str, -1)
+				matches := build.NewIterable(Match{}, len(locs))
+				for idx, loc := range locs {
+					matches.Items[idx] = rl.newMatch(str, loc)
+				}
+				return matches, nil
+			},
+		})
+	case "replace":
+		return build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+				build.String{},
+			},
+			Returns: build.String{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				str := string(args[0].(build.StringLiteral))
+				replacement := string(args[1].(build.StringLiteral))
+				return build.StringLiteral(rl.re.ReplaceAllString(str, replacement)), nil
+			},
+		})
+	case "split":
+		return build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+			},
+			Returns: build.NewIterable(build.String{}, 0),
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				parts := rl.re.Split(string(args[0].(build.StringLiteral)), -1)
+				iterable := build.NewIterable(build.String{}, len(parts))
+				for idx, part := range parts {
+					iterable.Items[idx] = build.StringLiteral(part)
+				}
+				return iterable, nil
+			},
+		})
+	case "groups":
+		// returns the named groups of the first match as the type given, where
+		// each field is constructed from the group of the same name
+		return build.NewParameterizedFunction(build.ParameterizedFunctionOptions{
+			Instantiate: func(types []build.Class) (build.Object, error) {
+				if len(types) != 1 {
+					return nil, fmt.Errorf("expected 1 type argument, got %d", len(types))
+				}
+				class, ok := types[0].(build.ObjectClass)
+				if !ok || class.Fields() == nil {
+					return nil, fmt.Errorf("cannot use %s for regex groups", types[0].ClassName())
+				}
+				for name, field := range class.Fields() {
+					if err := build.ShouldConstruct(field, build.String{}); err != nil {
+						return nil, fmt.Errorf("group %s: %s", name, err.Error())
+					}
+				}
+				return build.NewFunction(build.FunctionOptions{
+					Arguments: []build.Class{
+						build.String{},
+					},
+					Returns: build.NewOptionalClass(class),
+					Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+						return rl.namedGroups(class, string(args[0].(build.StringLiteral)))
+					},
+				}), nil
+			},
+		})
+	}
+	return nil
+}
+
+func (rl RegexLiteral) namedGroups(class build.ObjectClass, str string) (build.ValueObject, error) {
+	submatches := rl.re.FindStringSubmatchIndex(str)
+	if submatches == nil {
+		return build.NilableObject{ClassObject: class}, nil
+	}
+	props := make(map[string]interface{})
+	for name := range class.Fields() {
+		idx := rl.re.SubexpIndex(name)
+		if idx == -1 {
+			return nil, fmt.Errorf("pattern %s has no group named %s", rl.re.String(), name)
+		}
+		// groups that didn't take part in the match are left out so that
+		// optional fields can be used for optional groups
+		if start := submatches[2*idx]; start >= 0 {
+			props[name] = str[start:submatches[2*idx+1]]
+		}
+	}
+	obj, err := build.FromInterfaceAs(props, class)
+	if err != nil {
+		return nil, err
+	}
+	return build.NilableObject{ClassObject: class, Object: obj}, nil
+}
+
+// Match represents a single match of a Regex
+type Match struct{}
+
+func (m Match) ClassName() string {
+	return "Match"
+}
+func (m Match) Fields() map[string]build.Class {
+	return map[string]build.Class{
+		"text":   build.String{},
+		"start":  build.Integer{},
+		"end":    build.Integer{},
+		"groups": build.NewIterable(build.String{}, 0),
+	}
+}
+func (m Match) Constructors() build.ConstructorMap {
+	csMap := build.NewConstructorMap()
+	csMap.AddConstructor(Match{}, func(obj build.ValueObject) (build.ValueObject, error) {
+		return obj, nil
+	})
+	return csMap
+}
+func (m Match) Get(key string) build.Object {
+	return nil
+}
+
+// MatchLiteral holds the text of a match and where it was found. Positions are
+// counted in characters so that they can be used to index the string
+type MatchLiteral struct {
+	Text   string
+	Start  int
+	End    int
+	Groups []string
+}
+
+func (ml MatchLiteral) Class() build.Class {
+	return Match{}
+}
+func (ml MatchLiteral) Value() interface{} {
+	return map[string]interface{}{
+		"text":   ml.Text,
+		"start":  ml.Start,
+		"end":    ml.End,
+		"groups": ml.Groups,
+	}
+}
+func (ml MatchLiteral) Set(key string, obj build.ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, ml.Class().ClassName())
+}
+func (ml MatchLiteral) Get(key string) build.Object {
+	switch key {
+	case "text":
+		return build.StringLiteral(ml.Text)
+	case "start":
+		return build.IntegerLiteral(ml.Start)
+	case "end":
+		return build.IntegerLiteral(ml.End)
+	case "groups":
+		groups := build.NewIterable(build.String{}, len(ml.Groups))
+		for idx, group := range ml.Groups {
+			groups.Items[idx] = build.StringLiteral(group)
+		}
+		return groups
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package packages
+
+import (
+	"strings"
+	"testing"
+)
+
+const regexSource = `import "regex"
+
+context acme.parcels {
+	type Tracking {
+		carrier String
+		number String
+		suffix String?
+	}
+
+	query Parse(code: String) Tracking? {
+		return regex.compile("(?P<carrier>[A-Z]+)-(?P<number>[0-9]+)(?:-(?P<suffix>[a-z]+))?").groups<Tracking>(code)
+	}
+}`
+
+// CAN MATCH, FIND, REPLACE AND SPLIT WITH A REGEX, COUNTING POSITIONS IN RUNES
+func TestRegex(t *testing.T) {
+	fixtures := map[string]string{
+		`regex.compile("[0-9]+").matches("123")`:                                  "true",
+		`regex.compile("[0-9]+").matches("123a")`:                                 "false",
+		`regex.compile("a|ab").matches("ab")`:                                     "true",
+		`regex.compile("é+").find("café éé").start`:                               "3",
+		`regex.compile("é+").find("café éé").end`:                                 "4",
+		`len(regex.compile("[0-9]").findAll("a1b22"))`:                            "3",
+		`regex.compile("\\s+").replace("a  b   c", " ") == "a b c"`:               "true",
+		`regex.compile("(\\w+)@(\\w+)").replace("me@host", "$2:$1") == "host:me"`: "true",
+		`len(regex.compile(",\\s*").split("a, b,c"))`:                             "3",
+		`regex.Regex("^a.c$").matches("abc")`:                                     "true",
+		`regex.compile(regex.escape("a.c")).matches("abc")`:                       "false",
+	}
+	for expr, expected := range fixtures {
+		returns := "Bool"
+		if expected != "true" && expected != "false" {
+			returns = "Int"
+		}
+		if out := mustEvaluate(t, "regex", returns, expr); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
+
+// CAN FIND THE FIRST MATCH OF A REGEX, WITH THE GROUPS THAT DIDN'T TAKE PART
+// IN IT LEFT EMPTY
+func TestRegexFind(t *testing.T) {
+	fixtures := map[string]string{
+		`regex.compile("(.)(\\d)?").find("é!")`: `{"end":1,"groups":["é",""],"start":0,"text":"é"}`,
+		`regex.compile("x").find("abc")`:        "null",
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "regex", "regex.Match?", expr); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
+
+// CAN READ THE NAMED GROUPS OF A MATCH AS A TYPE
+func TestRegexGroups(t *testing.T) {
+	ctx, err := newContext(setupBuildContext(), regexSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := map[string]string{
+		`{"code": "UPS-123-x"}`: `{"carrier":"UPS","number":"123","suffix":"x"}`,
+		`{"code": "DHL-42"}`:    `{"carrier":"DHL","number":"42","suffix":null}`,
+		`{"code": "none"}`:      `null`,
+	}
+	for payload, expected := range fixtures {
+		out, err := ctx.Invoke("Parse", []byte(payload))
+		if err != nil {
+			t.Errorf("cannot parse %s: %s", payload, err)
+		} else if string(out) != expected {
+			t.Errorf("expected %s for %s, got %s", expected, payload, out)
+		}
+	}
+}
+
+// CANNOT COMPILE AN INVALID PATTERN OR READ GROUPS THE PATTERN DOESN'T HAVE
+func TestRegexErrors(t *testing.T) {
+	fixtures := map[string]string{
+		`regex.compile("(").matches("")`:    "missing closing )",
+		`regex.Regex("[a-").matches("")`:    "missing closing ]",
+		`regex.compile("a**").matches("")`:  "invalid nested repetition operator",
+		`regex.compile("(?<x").matches("")`: "invalid named capture",
+	}
+	for expr, message := range fixtures {
+		_, err := evaluate(setupBuildContext(), "regex", "Bool", expr)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %s to fail with %q, got %v", expr, message, err)
+		}
+	}
+	src := strings.Replace(regexSource, "(?P<carrier>", "(?P<company>", 1)
+	ctx, err := newContext(setupBuildContext(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctx.Invoke("Parse", []byte(`{"code": "UPS-1"}`)); err == nil || !strings.Contains(err.Error(), "has no group named carrier") {
+		t.Errorf("expected a missing group to fail, got %v", err)
+	}
+}
+
+// CAN KEEP THE PATTERNS THAT WERE COMPILED MOST RECENTLY, AND ONLY SO MANY
+func TestRegexCache(t *testing.T) {
+	cache := newRegexCache()
+	cache.size = 2
+	first, err := cache.compile("a+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := cache.compile("a+"); again != first {
+		t.Errorf("expected the compiled pattern to be reused")
+	}
+	cache.compile("b+")
+	cache.compile("a+") // a+ is now used more recently than b+
+	cache.compile("c+")
+	if _, ok := cache.patterns["b+"]; ok {
+		t.Errorf("expected the pattern used the longest time ago to be forgotten")
+	}
+	if again, _ := cache.compile("a+"); again != first || cache.order.Len() != 2 {
+		t.Errorf("expected a+ to be kept and only 2 patterns, got %d", cache.order.Len())
+	}
+	if _, err := cache.compile("("); err == nil {
+		t.Errorf("expected an invalid pattern to fail")
+	}
+	if _, ok := cache.patterns["("]; ok || cache.order.Len() != 2 {
+		t.Errorf("expected an invalid pattern not to be kept")
+	}
+}
@@ -0,0 +1,76 @@
+package packages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hntrl/lang/build"
+)
+
+type StringsPackage struct{}
+
+func (sp StringsPackage) Get(key string) build.Object {
+	methods := map[string]build.Object{
+		"join": build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.NewIterable(build.String{}, 0),
+				build.String{},
+			},
+			Returns: build.String{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				items := args[0].(build.Iterable).Items
+				parts := make([]string, len(items))
+				for idx, item := range items {
+					parts[idx] = string(item.(build.StringLiteral))
+				}
+				return build.StringLiteral(strings.Join(parts, string(args[1].(build.StringLiteral)))), nil
+			},
+		}),
+		"format": build.NewGenericFunction(build.GenericFunctionOptions{
+			Validator: func(args []build.Class) (build.Class, error) {
+				if len(args) == 0 {
+					return nil, fmt.Errorf("expected format string")
+				}
+				if err := build.ShouldConstruct(build.String{}, args[0]); err != nil {
+					return nil, err
+				}
+				return build.String{}, nil
+			},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				format, err := build.Construct(build.String{}, args[0])
+				if err != nil {
+					return nil, err
+				}
+				values := make([]interface{}, len(args)-1)
+				for idx, arg := range args[1:] {
+					values[idx], err = formatValue(arg)
+					if err != nil {
+						return nil, err
+					}
+				}
+				return build.StringLiteral(fmt.Sprintf(string(format.(build.StringLiteral)), values...)), nil
+			},
+		}),
+	}
+	return methods[key]
+}
+
+// Returns what's given to fmt.Sprintf for an argument of strings.format.
+// Numbers and booleans are left as they are so that verbs like %d and %.2f
+// work, and anything else is formatted with its String conversion, the same
+// as in template literals
+func formatValue(obj build.ValueObject) (interface{}, error) {
+	switch obj := obj.(type) {
+	case build.NilableObject:
+		if obj.Object == nil {
+			return "nil", nil
+		}
+		return formatValue(obj.Object)
+	case build.IntegerLiteral, build.FloatLiteral, build.DoubleLiteral, build.NumberLiteral, build.BooleanLiteral:
+		return obj.Value(), nil
+	}
+	if str, err := build.Construct(build.String{}, obj); err == nil {
+		return string(str.(build.StringLiteral)), nil
+	}
+	return nil, fmt.Errorf("cannot format %s", obj.Class().ClassName())
+}
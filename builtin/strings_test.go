@@ -0,0 +1,42 @@
+package packages
+
+import (
+	"strings"
+	"testing"
+)
+
+// CAN JOIN STRINGS AND FORMAT VALUES INTO A STRING
+func TestStrings(t *testing.T) {
+	fixtures := map[string]string{
+		`strings.join([]String{"a", "b", "c"}, ", ")`:  `"a, b, c"`,
+		`strings.join([]String{}, ",")`:                `""`,
+		`strings.format("%d items", 3)`:                `"3 items"`,
+		`strings.format("%.2f", 1.005)`:                `"1.00"`,
+		`strings.format("%s is %v", "ready", true)`:    `"ready is true"`,
+		`strings.format("%05d|%-4s|", 42, "ab")`:       `"00042|ab  |"`,
+		`strings.format("took %s", Duration("PT90S"))`: `"took PT1M30S"`,
+		`strings.format("on %s", Date("2024-02-29"))`:  `"on 2024-02-29"`,
+		`strings.format("%s", Decimal("0.10"))`:        `"0.10"`,
+		`strings.join([]Int{1, 2}, "+")`:               `"1+2"`,
+		`strings.format("no verbs")`:                   `"no verbs"`,
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "strings", "String", expr); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
+
+// CANNOT FORMAT WITHOUT A FORMAT STRING OR WITH VALUES THAT HAVE NO STRING FORM
+func TestStringsErrors(t *testing.T) {
+	fixtures := map[string]string{
+		`strings.format()`:                    "expected format string",
+		`strings.format("%v", []String{"a"})`: "cannot format",
+	}
+	for expr, message := range fixtures {
+		_, err := evaluate(setupBuildContext(), "strings", "String", expr)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %s to fail with %q, got %v", expr, message, err)
+		}
+	}
+}
@@ -1,61 +1,502 @@
 package packages
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
 	"github.com/hntrl/lang/build"
+	"github.com/hntrl/lang/language/tokens"
 )
 
 type UnitsPackage struct{}
 
 func (up UnitsPackage) Get(key string) build.Object {
 	objects := map[string]build.Object{
+		"Quantity":  Quantity{},
 		"Dimension": Dimension{},
 	}
+	for _, class := range quantityClasses {
+		objects[class.ClassName()] = class
+	}
 	return objects[key]
 }
 
-type Dimension struct {
-	Width  build.FloatLiteral
-	Height build.FloatLiteral
+// The physical dimensions a quantity can be measured in
+const (
+	Length      = "length"
+	Mass        = "mass"
+	Volume      = "volume"
+	Time        = "time"
+	Temperature = "temperature"
+)
+
+var dimensionClassNames = map[string]string{
+	Length:      "Length",
+	Mass:        "Mass",
+	Volume:      "Volume",
+	Time:        "Time",
+	Temperature: "Temperature",
+}
+
+// The classes of quantities that are measured in a dimension. Each dimension
+// has a class named after it, and some have others that go by another name
+var quantityClasses = []Quantity{
+	{Dimension: Length},
+	{Dimension: Mass},
+	{Dimension: Volume},
+	{Dimension: Time},
+	{Dimension: Temperature},
+	// weight is how carriers refer to the mass of a package
+	{Dimension: Mass, Name: "Weight"},
+}
+
+// A unit converts to the base unit of its dimension as value*factor + offset.
+// Only temperatures have an offset
+type unit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+var units = map[string]unit{
+	"mm": {dimension: Length, factor: 0.001},
+	"cm": {dimension: Length, factor: 0.01},
+	"m":  {dimension: Length, factor: 1},
+	"km": {dimension: Length, factor: 1000},
+	"in": {dimension: Length, factor: 0.0254},
+	"ft": {dimension: Length, factor: 0.3048},
+	"yd": {dimension: Length, factor: 0.9144},
+	"mi": {dimension: Length, factor: 1609.344},
+
+	"mg": {dimension: Mass, factor: 0.000001},
+	"g":  {dimension: Mass, factor: 0.001},
+	"kg": {dimension: Mass, factor: 1},
+	"t":  {dimension: Mass, factor: 1000},
+	"oz": {dimension: Mass, factor: 0.028349523125},
+	"lb": {dimension: Mass, factor: 0.45359237},
+
+	"ml":   {dimension: Volume, factor: 0.001},
+	"cl":   {dimension: Volume, factor: 0.01},
+	"l":    {dimension: Volume, factor: 1},
+	"cm3":  {dimension: Volume, factor: 0.001},
+	"m3":   {dimension: Volume, factor: 1000},
+	"floz": {dimension: Volume, factor: 0.0295735295625},
+	"gal":  {dimension: Volume, factor: 3.785411784},
+
+	"ms":  {dimension: Time, factor: 0.001},
+	"s":   {dimension: Time, factor: 1},
+	"min": {dimension: Time, factor: 60},
+	"h":   {dimension: Time, factor: 3600},
+	"d":   {dimension: Time, factor: 86400},
+
+	"K": {dimension: Temperature, factor: 1},
+	"C": {dimension: Temperature, factor: 1, offset: 273.15},
+	"F": {dimension: Temperature, factor: 5.0 / 9.0, offset: 459.67 * 5.0 / 9.0},
+}
+
+// The unit a zero value of each dimension is measured in
+var baseUnits = map[string]string{
+	Length:      "m",
+	Mass:        "kg",
+	Volume:      "l",
+	Time:        "s",
+	Temperature: "K",
+}
+
+func lookupUnit(symbol string) (unit, error) {
+	if u, ok := units[symbol]; ok {
+		return u, nil
+	}
+	symbols := make([]string, 0, len(units))
+	for symbol := range units {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return unit{}, fmt.Errorf("unknown unit %q, expected one of [%s]", symbol, strings.Join(symbols, ", "))
+}
+
+// Quantity is an amount measured in a unit. When the dimension is known the
+// class only holds quantities of that dimension, so mixing dimensions can be
+// caught when an expression is validated. Quantities without a dimension are
+// checked when they're evaluated
+type Quantity struct {
+	Dimension string
+	// The name of the class if it isn't the name of its dimension, i.e. Weight
+	Name string
+}
+
+func (q Quantity) ClassName() string {
+	if q.Name != "" {
+		return q.Name
+	}
+	if name, ok := dimensionClassNames[q.Dimension]; ok {
+		return name
+	}
+	return "Quantity"
+}
+func (q Quantity) Fields() map[string]build.Class {
+	return map[string]build.Class{
+		"value": build.Float{},
+		"unit":  build.String{},
+	}
+}
+
+// Returns an error if a quantity can't be held by the class
+func (q Quantity) check(ql QuantityLiteral) error {
+	if q.Dimension != "" && ql.Dimension() != q.Dimension {
+		return fmt.Errorf("cannot use %s (%s) as %s", ql.String(), ql.Dimension(), q.ClassName())
+	}
+	return nil
+}
+
+func (q Quantity) Constructors() build.ConstructorMap {
+	csMap := build.NewConstructorMap()
+	csMap.AddConstructor(q, func(obj build.ValueObject) (build.ValueObject, error) {
+		return obj, nil
+	})
+	for _, class := range q.compatible() {
+		if class == q {
+			continue
+		}
+		csMap.AddConstructor(class, func(obj build.ValueObject) (build.ValueObject, error) {
+			ql := obj.(QuantityLiteral)
+			if err := q.check(ql); err != nil {
+				return nil, err
+			}
+			return QuantityLiteral{Amount: ql.Amount, Unit: ql.Unit, class: q}, nil
+		})
+	}
+	if q.Dimension == Time {
+		csMap.AddConstructor(build.Duration{}, func(obj build.ValueObject) (build.ValueObject, error) {
+			return QuantityLiteral{Amount: time.Duration(obj.(build.DurationLiteral)).Seconds(), Unit: "s", class: q}, nil
+		})
+	}
+	csMap.AddConstructor(build.String{}, func(obj build.ValueObject) (build.ValueObject, error) {
+		ql, err := ParseQuantity(string(obj.(build.StringLiteral)))
+		if err != nil {
+			return nil, err
+		}
+		if err := q.check(ql); err != nil {
+			return nil, err
+		}
+		ql.class = q
+		return ql, nil
+	})
+	csMap.AddGenericConstructor(q, func(fields map[string]build.ValueObject) (build.ValueObject, error) {
+		symbol := string(fields["unit"].(build.StringLiteral))
+		u, err := lookupUnit(symbol)
+		if err != nil {
+			return nil, err
+		}
+		ql := QuantityLiteral{Amount: float64(fields["value"].(build.FloatLiteral)), Unit: symbol}
+		if q.Dimension != "" && u.dimension != q.Dimension {
+			return nil, fmt.Errorf("cannot use %s (%s) as %s", ql.String(), u.dimension, q.ClassName())
+		}
+		ql.class = q
+		return ql, nil
+	})
+	return csMap
+}
+
+func (q Quantity) ToString(obj build.ValueObject) (string, error) {
+	return obj.(QuantityLiteral).String(), nil
+}
+
+// Returns the classes a quantity can be combined with, which are Quantity and
+// the classes of its dimension
+func (q Quantity) compatible() []build.Class {
+	classes := []build.Class{Quantity{}}
+	for _, class := range quantityClasses {
+		if q.Dimension == "" || class.Dimension == q.Dimension {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+func quantityComparePredicate(cb func(a, b float64) bool) build.OperatorFn {
+	return func(a, b build.ValueObject) (build.ValueObject, error) {
+		qa, qb := a.(QuantityLiteral), b.(QuantityLiteral)
+		if qa.Dimension() != qb.Dimension() {
+			return nil, fmt.Errorf("cannot compare %s and %s", qa.Dimension(), qb.Dimension())
+		}
+		return build.BooleanLiteral(cb(qa.base(), qb.base())), nil
+	}
+}
+func (q Quantity) ComparableRules() build.ComparatorRules {
+	rules := build.NewComparatorRules()
+	for _, class := range q.compatible() {
+		rules.AddComparator(class, tokens.EQUALS, quantityComparePredicate(func(a, b float64) bool {
+			return a == b
+		}))
+		rules.AddComparator(class, tokens.NOT_EQUALS, quantityComparePredicate(func(a, b float64) bool {
+			return a != b
+		}))
+		rules.AddComparator(class, tokens.LESS, quantityComparePredicate(func(a, b float64) bool {
+			return a < b
+		}))
+		rules.AddComparator(class, tokens.GREATER, quantityComparePredicate(func(a, b float64) bool {
+			return a > b
+		}))
+		rules.AddComparator(class, tokens.LESS_EQUAL, quantityComparePredicate(func(a, b float64) bool {
+			return a <= b
+		}))
+		rules.AddComparator(class, tokens.GREATER_EQUAL, quantityComparePredicate(func(a, b float64) bool {
+			return a >= b
+		}))
+	}
+	return rules
+}
+
+// Adding or subtracting treats the right operand as a difference in the unit
+// of the left, so 20 C + 10 K is 30 C
+func quantityOperatorPredicate(token tokens.Token) build.OperatorFn {
+	verb := map[tokens.Token]string{tokens.ADD: "add", tokens.SUB: "subtract"}[token]
+	return func(a, b build.ValueObject) (build.ValueObject, error) {
+		qa, qb := a.(QuantityLiteral), b.(QuantityLiteral)
+		if qa.Dimension() != qb.Dimension() {
+			return nil, fmt.Errorf("cannot %s %s and %s", verb, qa.Dimension(), qb.Dimension())
+		}
+		delta := qb.Amount * units[qb.Unit].factor / units[qa.Unit].factor
+		if token == tokens.SUB {
+			delta = -delta
+		}
+		return QuantityLiteral{Amount: qa.Amount + delta, Unit: qa.Unit, class: qa.class}, nil
+	}
+}
+func quantityRatioPredicate(a, b build.ValueObject) (build.ValueObject, error) {
+	qa, qb := a.(QuantityLiteral), b.(QuantityLiteral)
+	if qa.Dimension() != qb.Dimension() {
+		return nil, fmt.Errorf("cannot divide %s by %s", qa.Dimension(), qb.Dimension())
+	}
+	if qb.Amount == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return build.FloatLiteral(qa.Amount * units[qa.Unit].factor / (qb.Amount * units[qb.Unit].factor)), nil
+}
+func quantityScalePredicate(token tokens.Token) build.OperatorFn {
+	return func(a, b build.ValueObject) (build.ValueObject, error) {
+		qa := a.(QuantityLiteral)
+		factor := floatValue(b)
+		if token == tokens.QUO {
+			if factor == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return QuantityLiteral{Amount: qa.Amount / factor, Unit: qa.Unit, class: qa.class}, nil
+		}
+		return QuantityLiteral{Amount: qa.Amount * factor, Unit: qa.Unit, class: qa.class}, nil
+	}
+}
+func (q Quantity) OperatorRules() build.OperatorRules {
+	rules := build.NewOperatorRules()
+	for _, class := range q.compatible() {
+		rules.AddOperator(class, tokens.ADD, quantityOperatorPredicate(tokens.ADD))
+		rules.AddOperator(class, tokens.SUB, quantityOperatorPredicate(tokens.SUB))
+		rules.AddOperatorReturning(class, tokens.QUO, build.Float{}, quantityRatioPredicate)
+	}
+	for _, class := range []build.Class{build.Integer{}, build.Float{}, build.Double{}, build.Number{}} {
+		rules.AddOperator(class, tokens.MUL, quantityScalePredicate(tokens.MUL))
+		rules.AddOperator(class, tokens.QUO, quantityScalePredicate(tokens.QUO))
+	}
+	return rules
+}
+
+func (q Quantity) Get(key string) build.Object {
+	// methods available on the instance so they can be validated without a value
+	return QuantityLiteral{Unit: baseUnits[q.Dimension], class: q}.Get(key)
+}
+
+// QuantityLiteral represents an amount in one of the known units
+type QuantityLiteral struct {
+	Amount float64
+	Unit   string
+	// the class the quantity was made as, which has no dimension when it was
+	// made as a Quantity
+	class Quantity
+}
+
+// Parses a quantity from its string representation, i.e. "2.5 kg" or "30cm"
+func ParseQuantity(str string) (QuantityLiteral, error) {
+	str = strings.TrimSpace(str)
+	parts := strings.Fields(str)
+	if len(parts) == 1 {
+		// the unit starts at the first letter that isn't an exponent
+		idx := strings.IndexFunc(str, func(r rune) bool {
+			return unicode.IsLetter(r) && r != 'e' && r != 'E'
+		})
+		if idx <= 0 {
+			return QuantityLiteral{}, fmt.Errorf("cannot parse %q as Quantity", str)
+		}
+		parts = []string{str[:idx], str[idx:]}
+	}
+	if len(parts) != 2 {
+		return QuantityLiteral{}, fmt.Errorf("cannot parse %q as Quantity", str)
+	}
+	amount, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return QuantityLiteral{}, fmt.Errorf("cannot parse %q as Quantity", str)
+	}
+	if _, err := lookupUnit(parts[1]); err != nil {
+		return QuantityLiteral{}, err
+	}
+	return QuantityLiteral{Amount: amount, Unit: parts[1]}, nil
+}
+
+// Returns the dimension of the unit the quantity is measured in
+func (ql QuantityLiteral) Dimension() string {
+	return units[ql.Unit].dimension
+}
+
+// Returns the amount in the base unit of the dimension
+func (ql QuantityLiteral) base() float64 {
+	u := units[ql.Unit]
+	return ql.Amount*u.factor + u.offset
+}
+
+// Returns the quantity measured in another unit of the same dimension
+func (ql QuantityLiteral) To(symbol string) (QuantityLiteral, error) {
+	target, err := lookupUnit(symbol)
+	if err != nil {
+		return QuantityLiteral{}, err
+	}
+	if target.dimension != ql.Dimension() {
+		return QuantityLiteral{}, fmt.Errorf("cannot convert %s (%s) to %s (%s)", ql.Unit, ql.Dimension(), symbol, target.dimension)
+	}
+	return QuantityLiteral{Amount: (ql.base() - target.offset) / target.factor, Unit: symbol, class: ql.class}, nil
+}
+
+func (ql QuantityLiteral) Class() build.Class {
+	return ql.class
+}
+func (ql QuantityLiteral) Value() interface{} {
+	return map[string]interface{}{
+		"value": ql.Amount,
+		"unit":  ql.Unit,
+	}
+}
+func (ql QuantityLiteral) Set(key string, obj build.ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, ql.Class().ClassName())
+}
+func (ql QuantityLiteral) Get(key string) build.Object {
+	switch key {
+	case "value":
+		return build.FloatLiteral(ql.Amount)
+	case "unit":
+		return build.StringLiteral(ql.Unit)
+	case "dimension":
+		return build.StringLiteral(ql.Dimension())
+	case "to":
+		return build.NewFunction(build.FunctionOptions{
+			Arguments: []build.Class{
+				build.String{},
+			},
+			Returns: ql.Class(),
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return ql.To(string(args[0].(build.StringLiteral)))
+			},
+		})
+	case "format":
+		return build.NewFunction(build.FunctionOptions{
+			Returns: build.String{},
+			Handler: func(args []build.ValueObject, proto build.ValueObject) (build.ValueObject, error) {
+				return build.StringLiteral(ql.String()), nil
+			},
+		})
+	}
+	return nil
+}
+
+func (ql QuantityLiteral) String() string {
+	return strconv.FormatFloat(ql.Amount, 'f', -1, 64) + " " + ql.Unit
+}
+
+type quantityJSON struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
 }
 
+func (ql QuantityLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(quantityJSON{Value: ql.Amount, Unit: ql.Unit})
+}
+func (ql *QuantityLiteral) UnmarshalJSON(data []byte) error {
+	var obj quantityJSON
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if _, err := lookupUnit(obj.Unit); err != nil {
+		return err
+	}
+	*ql = QuantityLiteral{Amount: obj.Value, Unit: obj.Unit}
+	return nil
+}
+
+// Dimension is the size of a package as its width and height
+type Dimension struct{}
+
 func (dm Dimension) ClassName() string {
 	return "Dimension"
 }
 func (dm Dimension) Fields() map[string]build.Class {
 	return map[string]build.Class{
-		"width":  build.Float{},
-		"height": build.Float{},
+		"width":  Quantity{Dimension: Length},
+		"height": Quantity{Dimension: Length},
 	}
 }
-func (dm Dimension) Prototype(*build.ValueObject) map[string]build.ValueObject {
-	return nil
-}
 func (dm Dimension) Constructors() build.ConstructorMap {
 	csMap := build.NewConstructorMap()
+	csMap.AddConstructor(dm, func(obj build.ValueObject) (build.ValueObject, error) {
+		return obj, nil
+	})
 	csMap.AddGenericConstructor(dm, func(fields map[string]build.ValueObject) (build.ValueObject, error) {
-		return Dimension{
-			Width:  fields["width"].(build.FloatLiteral),
-			Height: fields["height"].(build.FloatLiteral),
+		return DimensionLiteral{
+			Width:  fields["width"].(QuantityLiteral),
+			Height: fields["height"].(QuantityLiteral),
 		}, nil
 	})
 	return csMap
 }
 func (dm Dimension) Get(key string) build.Object {
+	// methods available on the instance so they can be validated without a value
+	return DimensionLiteral{}.Get(key)
+}
+
+type DimensionLiteral struct {
+	Width  QuantityLiteral
+	Height QuantityLiteral
+}
+
+func (dl DimensionLiteral) Class() build.Class {
+	return Dimension{}
+}
+func (dl DimensionLiteral) Value() interface{} {
+	return map[string]interface{}{
+		"width":  dl.Width.Value(),
+		"height": dl.Height.Value(),
+	}
+}
+func (dl DimensionLiteral) Set(key string, obj build.ValueObject) error {
+	return fmt.Errorf("cannot set property %s of %s", key, dl.Class().ClassName())
+}
+func (dl DimensionLiteral) Get(key string) build.Object {
 	switch key {
 	case "width":
-		return dm.Width
+		return QuantityLiteral{Amount: dl.Width.Amount, Unit: dl.Width.Unit, class: Quantity{Dimension: Length}}
 	case "height":
-		return dm.Height
+		return QuantityLiteral{Amount: dl.Height.Amount, Unit: dl.Height.Unit, class: Quantity{Dimension: Length}}
 	}
 	return nil
 }
 
-func (dm Dimension) Class() build.Class {
-	return dm
+type dimensionJSON struct {
+	Width  QuantityLiteral `json:"width"`
+	Height QuantityLiteral `json:"height"`
 }
-func (dm Dimension) Value() interface{} {
-	return nil
-}
-func (dm Dimension) Set(key string, obj build.ValueObject) error {
-	return nil
+
+func (dl DimensionLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dimensionJSON(dl))
 }
@@ -0,0 +1,91 @@
+package packages
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// CAN CONVERT QUANTITIES BETWEEN THE UNITS OF THEIR DIMENSION
+func TestQuantityConversion(t *testing.T) {
+	fixtures := map[string]string{
+		`units.Length("5 km").to("m")`:                       `"5000 m"`,
+		`units.Length("1 in").to("mm")`:                      `"25.4 mm"`,
+		`units.Mass("2 lb").to("g")`:                         `"907.18474 g"`,
+		`units.Weight("1500 g").to("kg")`:                    `"1.5 kg"`,
+		`units.Time("90 min").to("h")`:                       `"1.5 h"`,
+		`units.Temperature("0 C").to("K")`:                   `"273.15 K"`,
+		`units.Length("1 m") + units.Length("50 cm")`:        `"1.5 m"`,
+		`units.Mass("1 kg") - units.Weight("250 g")`:         `"0.75 kg"`,
+		`units.Length("3 m") * 2`:                            `"6 m"`,
+		`units.Quantity({ value: 2.5, unit: "l" }).to("ml")`: `"2500 ml"`,
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "units", "String", "String("+expr+")"); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+	if out := mustEvaluate(t, "units", "Float", `units.Length("1 km") / units.Length("250 m")`); out != "4" {
+		t.Errorf("expected the ratio of two lengths, got %s", out)
+	}
+	out := mustEvaluate(t, "units", "Float", `units.Temperature("100 C").to("F").value`)
+	if value, err := strconv.ParseFloat(out, 64); err != nil || math.Abs(value-212) > 1e-9 {
+		t.Errorf("expected water to boil at 212 F, got %s", out)
+	}
+}
+
+// CANNOT MIX QUANTITIES OF DIFFERENT DIMENSIONS
+func TestQuantityDimensions(t *testing.T) {
+	fixtures := map[string]string{
+		`units.Length("1 m").to("kg")`:                   "cannot convert m (length) to kg (mass)",
+		`units.Length("1 kg")`:                           "cannot use 1 kg (mass) as Length",
+		`units.Quantity("1 m") + units.Quantity("1 kg")`: "cannot add length and mass",
+		`units.Quantity("1 m") - units.Quantity("1 s")`:  "cannot subtract length and time",
+		`units.Quantity({ value: 1.0, unit: "parsec" })`: "unknown unit",
+		`units.Length({ value: 1.0, unit: "l" })`:        "cannot use 1 l (volume) as Length",
+	}
+	for expr, message := range fixtures {
+		_, err := evaluate(setupBuildContext(), "units", "units.Quantity", expr)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %s to fail with %q, got %v", expr, message, err)
+		}
+	}
+	_, err := evaluate(setupBuildContext(), "units", "Float", `units.Quantity("1 m") / units.Quantity("1 kg")`)
+	if err == nil || !strings.Contains(err.Error(), "cannot divide length by mass") {
+		t.Errorf("expected dividing a length by a mass to fail, got %v", err)
+	}
+	if _, err := evaluate(setupBuildContext(), "units", "units.Length", `units.Length("1 m") + units.Mass("1 kg")`); err == nil {
+		t.Errorf("expected adding a length and a mass to fail to validate")
+	}
+}
+
+// CANNOT DIVIDE A QUANTITY BY ZERO
+func TestQuantityDivisionByZero(t *testing.T) {
+	fixtures := map[string]string{
+		`units.Length("1 m") / 0`:                    "units.Length",
+		`units.Length("1 m") / 0.0`:                  "units.Length",
+		`units.Length("1 m") / units.Length("0 cm")`: "Float",
+	}
+	for expr, returns := range fixtures {
+		_, err := evaluate(setupBuildContext(), "units", returns, expr)
+		if err == nil || !strings.Contains(err.Error(), "division by zero") {
+			t.Errorf("expected %s to fail with a division by zero, got %v", expr, err)
+		}
+	}
+}
+
+// CAN MAKE A QUANTITY INTO A STRING AND BACK
+func TestQuantityString(t *testing.T) {
+	fixtures := map[string]string{
+		`String(units.Length("2.5 km"))`:                    `"2.5 km"`,
+		"`weighs ${units.Weight(\"3 kg\")}`":                `"weighs 3 kg"`,
+		`units.Length(String(units.Length("2.5 km"))).unit`: `"km"`,
+		`strings.format("%s left", units.Volume("330 ml"))`: `"330 ml left"`,
+	}
+	for expr, expected := range fixtures {
+		if out := mustEvaluate(t, "units strings", "String", expr); out != expected {
+			t.Errorf("expected %s to be %s, got %s", expr, expected, out)
+		}
+	}
+}
@@ -1,9 +1,28 @@
 module github.com/hntrl/lang
 
-go 1.19
+go 1.25.0
 
 require (
 	github.com/go-test/deep v1.0.8
 	github.com/mitchellh/hashstructure v1.1.0
+	github.com/nats-io/nats.go v1.53.1
 	github.com/pkg/errors v0.9.1
+	go.mongodb.org/mongo-driver v1.17.10
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/golang/snappy v0.0.4 // indirect
+	github.com/klauspost/compress v1.18.5 // indirect
+	github.com/montanaflynn/stats v0.7.1 // indirect
+	github.com/nats-io/nkeys v0.4.15 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
+	github.com/xdg-go/scram v1.1.2 // indirect
+	github.com/xdg-go/stringprep v1.0.4 // indirect
+	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
+	golang.org/x/crypto v0.49.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
+	golang.org/x/sys v0.42.0 // indirect
+	golang.org/x/text v0.35.0 // indirect
 )
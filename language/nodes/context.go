@@ -90,8 +90,11 @@ func ParseContext(p *parser.Parser) (*Context, error) {
 			if tok == tokens.PRIVATE {
 				p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
 			}
-			p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
 			_, tok, _ = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+			// a method can have options between its class and its name
+			if tok != tokens.LPAREN {
+				_, tok, _ = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+			}
 			p.Rollback(startIndex - 1)
 			if tok == tokens.LPAREN {
 				method, err := ParseContextMethod(p)
@@ -259,17 +262,23 @@ func ParseContextObjectMethod(p *parser.Parser) (*ContextObjectMethod, error) {
 	return &method, nil
 }
 
-// ContextMethod :: COMMENT? PRIVATE? IDENT IDENT FunctionBlock
+// ContextMethod :: COMMENT? PRIVATE? IDENT CallExpression? IDENT FunctionBlock
 type ContextMethod struct {
 	pos     tokens.Position
 	Private bool
 	Class   string
+	Options []Expression
 	Name    string
 	Block   FunctionBlock
 	Comment string
 }
 
 func (c ContextMethod) Validate() error {
+	for _, option := range c.Options {
+		if err := option.Validate(); err != nil {
+			return err
+		}
+	}
 	return c.Block.Validate()
 }
 
@@ -296,6 +305,15 @@ func ParseContextMethod(p *parser.Parser) (*ContextMethod, error) {
 	method.pos = pos
 
 	pos, tok, lit = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+	if tok == tokens.LPAREN {
+		p.Unscan()
+		options, err := ParseCallExpression(p)
+		if err != nil {
+			return nil, err
+		}
+		method.Options = options.Arguments
+		pos, tok, lit = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+	}
 	if tok != tokens.IDENT {
 		return nil, ExpectedError(pos, tokens.IDENT, lit)
 	}
@@ -397,6 +397,49 @@ func TestContextMethodPrivate(t *testing.T) {
 	}
 }
 
+// CAN CREATE CONTEXT METHOD WITH OPTIONS
+func TestContextMethodOptions(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: "foo(\"a\") bar() {}",
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseContextMethod(p)
+		},
+		expects: &ContextMethod{
+			pos:     tokens.Position{Line: 1, Column: 1},
+			Private: false,
+			Class:   "foo",
+			Options: []Expression{
+				{
+					pos: tokens.Position{Line: 1, Column: 5},
+					Init: Literal{
+						pos:   tokens.Position{Line: 1, Column: 5},
+						Value: "a",
+					},
+				},
+			},
+			Name: "bar",
+			Block: FunctionBlock{
+				pos: tokens.Position{Line: 1, Column: 13},
+				Arguments: ArgumentList{
+					pos:   tokens.Position{Line: 1, Column: 13},
+					Items: make([]Node, 0),
+				},
+				ReturnType: nil,
+				Body: Block{
+					pos:        tokens.Position{Line: 1, Column: 18},
+					Statements: []BlockStatement{},
+				},
+			},
+			Comment: "",
+		},
+		expectsError: nil,
+		endingToken:  tokens.EOF,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 // CAN CREATE CONTEXT METHOD WITH LEADING COMMENT
 func TestContextMethodComment(t *testing.T) {
 	err := evaluateTest(TestFixture{
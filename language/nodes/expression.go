@@ -58,6 +58,7 @@ func ParseTypeExpression(p *parser.Parser) (*TypeExpression, error) {
 
 // Expression :: Literal
 //
+//	| TemplateLiteral
 //	| ArrayExpression
 //	| InstanceExpression
 //	| UnaryExpression
@@ -68,7 +69,7 @@ func ParseTypeExpression(p *parser.Parser) (*TypeExpression, error) {
 //	| LPAREN Expression RPAREN
 type Expression struct {
 	pos  tokens.Position
-	Init Node `types:"Literal,ArrayExpression,InstanceExpression,UnaryExpression,BinaryExpression,ObjectPattern,FunctionExpression,ValueExpression,Expression"`
+	Init Node `types:"Literal,TemplateLiteral,ArrayExpression,InstanceExpression,UnaryExpression,BinaryExpression,ObjectPattern,FunctionExpression,ValueExpression,Expression"`
 }
 
 func (e Expression) Validate() error {
@@ -76,6 +77,10 @@ func (e Expression) Validate() error {
 		if err := lit.Validate(); err != nil {
 			return err
 		}
+	} else if tmpl, ok := e.Init.(TemplateLiteral); ok {
+		if err := tmpl.Validate(); err != nil {
+			return err
+		}
 	} else if arr, ok := e.Init.(ArrayExpression); ok {
 		if err := arr.Validate(); err != nil {
 			return err
@@ -130,6 +135,13 @@ func ParseExpression(p *parser.Parser) (*Expression, error) {
 			return nil, err
 		}
 		expr.Init = *literal
+	case tokens.TEMPLATE, tokens.TEMPLATE_HEAD:
+		p.Unscan()
+		template, err := ParseTemplateLiteral(p)
+		if err != nil {
+			return nil, err
+		}
+		expr.Init = *template
 	case tokens.LSQUARE:
 		p.Unscan()
 		arr, err := ParseArrayExpression(p)
@@ -489,8 +501,20 @@ func ParseValueExpression(p *parser.Parser) (*ValueExpression, error) {
 	ve.Members = append(ve.Members, ValueExpressionMember{Init: lit})
 
 	for {
+		startIndex := p.Index()
 		_, tok, _ := p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
 		p.Unscan()
+		if tok == tokens.LESS {
+			// a LESS only starts type arguments if it's followed by a call,
+			// otherwise it's a comparison
+			member, err := ParseValueExpressionMember(p)
+			if err != nil {
+				p.Rollback(startIndex)
+				break
+			}
+			ve.Members = append(ve.Members, *member)
+			continue
+		}
 		if tok != tokens.PERIOD && tok != tokens.LSQUARE && tok != tokens.LPAREN {
 			break
 		}
@@ -544,7 +568,7 @@ func ParseValueExpressionMember(p *parser.Parser) (*ValueExpressionMember, error
 			return nil, ExpectedError(pos, tokens.IDENT, lit)
 		}
 		member.Init = lit
-	case tokens.LPAREN:
+	case tokens.LPAREN, tokens.LESS:
 		p.Unscan()
 		call, err := ParseCallExpression(p)
 		if err != nil {
@@ -564,13 +588,19 @@ func ParseValueExpressionMember(p *parser.Parser) (*ValueExpressionMember, error
 	return &member, nil
 }
 
-// CallExpression :: LPAREN ((Expression COMMA) | Expression)* RPAREN
+// CallExpression :: (LESS ((TypeExpression COMMA) | TypeExpression)* GREATER)? LPAREN ((Expression COMMA) | Expression)* RPAREN
 type CallExpression struct {
-	pos       tokens.Position
-	Arguments []Expression
+	pos           tokens.Position
+	TypeArguments []TypeExpression
+	Arguments     []Expression
 }
 
 func (c CallExpression) Validate() error {
+	for _, typeArg := range c.TypeArguments {
+		if err := typeArg.Validate(); err != nil {
+			return err
+		}
+	}
 	for _, arg := range c.Arguments {
 		if err := arg.Validate(); err != nil {
 			return err
@@ -587,6 +617,24 @@ func ParseCallExpression(p *parser.Parser) (*CallExpression, error) {
 	pos, tok, lit := p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
 	call := CallExpression{pos: pos, Arguments: []Expression{}}
 
+	if tok == tokens.LESS {
+		for {
+			typeArg, err := ParseTypeExpression(p)
+			if err != nil {
+				return nil, err
+			}
+			call.TypeArguments = append(call.TypeArguments, *typeArg)
+
+			pos, tok, lit = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+			if tok == tokens.GREATER {
+				break
+			}
+			if tok != tokens.COMMA {
+				return nil, ExpectedError(pos, tokens.GREATER, lit)
+			}
+		}
+		pos, tok, lit = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+	}
 	if tok != tokens.LPAREN {
 		return nil, ExpectedError(pos, tokens.LPAREN, lit)
 	}
@@ -659,26 +707,27 @@ func ParseIndexExpression(p *parser.Parser) (*IndexExpression, error) {
 		index.Left = expr
 	}
 
-	_, tok, _ = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+	pos, tok, lit = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
 	if tok == tokens.RSQUARE {
 		return &index, nil
 	} else if tok == tokens.COLON {
 		index.IsRange = true
 		_, tok, _ = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
-		p.Unscan()
 		if tok != tokens.RSQUARE {
+			p.Unscan()
 			expr, err := ParseExpression(p)
 			if err != nil {
 				return nil, err
 			}
 			index.Right = expr
-			_, tok, _ = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+			pos, tok, lit = p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
 			if tok != tokens.RSQUARE {
 				return nil, ExpectedError(pos, tokens.RSQUARE, lit)
 			}
 		}
+		return &index, nil
 	}
-	return &index, nil
+	return nil, ExpectedError(pos, tokens.RSQUARE, lit)
 }
 
 // AssignmentExpression :: Selector token(IsAssignmentOperator) Expression
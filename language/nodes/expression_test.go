@@ -362,6 +362,48 @@ func TestValueExpression(t *testing.T) {
 	}
 }
 
+// CAN PARSE OPEN ENDED INDEX RANGE
+func TestIndexExpressionOpenRange(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: "[1:]",
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseIndexExpression(p)
+		},
+		expects: &IndexExpression{
+			pos: tokens.Position{Line: 1, Column: 1},
+			Left: &Expression{
+				pos: tokens.Position{Line: 1, Column: 2},
+				Init: Literal{
+					pos:   tokens.Position{Line: 1, Column: 2},
+					Value: int64(1),
+				},
+			},
+			IsRange: true,
+			Right:   nil,
+		},
+		expectsError: nil,
+		endingToken:  tokens.EOF,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// REJECTS UNTERMINATED INDEX EXPRESSION
+func TestIndexExpressionUnterminated(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: "[1 2]",
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseIndexExpression(p)
+		},
+		expects:      nil,
+		expectsError: ExpectedError(tokens.Position{Line: 1, Column: 4}, tokens.RSQUARE, "2"),
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 // REJECTS CALL EXPRESSION WITH INVALID ARGUMENTS
 func TestCallExpressionInvalidArguments(t *testing.T) {
 	err := evaluateTest(TestFixture{
@@ -377,6 +419,85 @@ func TestCallExpressionInvalidArguments(t *testing.T) {
 	}
 }
 
+// CAN PARSE CALL EXPRESSION WITH TYPE ARGUMENTS
+func TestCallExpressionWithTypeArguments(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: "fn<Foo>()",
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseExpression(p)
+		},
+		expects: &Expression{
+			pos: tokens.Position{Line: 1, Column: 1},
+			Init: ValueExpression{
+				pos: tokens.Position{Line: 1, Column: 1},
+				Members: []ValueExpressionMember{
+					{
+						pos:  tokens.Position{Line: 1, Column: 1},
+						Init: "fn",
+					},
+					{
+						pos: tokens.Position{Line: 1, Column: 3},
+						Init: CallExpression{
+							pos: tokens.Position{Line: 1, Column: 3},
+							TypeArguments: []TypeExpression{
+								{
+									pos: tokens.Position{Line: 1, Column: 4},
+									Selector: Selector{
+										pos:     tokens.Position{Line: 1, Column: 4},
+										Members: []string{"Foo"},
+									},
+								},
+							},
+							Arguments: []Expression{},
+						},
+					},
+				},
+			},
+		},
+		expectsError: nil,
+		endingToken:  tokens.EOF,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// CAN PARSE LESS THAN AS A COMPARISON WHEN IT ISN'T FOLLOWED BY A CALL
+func TestLessThanIsNotTypeArguments(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: "a < b",
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseExpression(p)
+		},
+		expects: &Expression{
+			pos: tokens.Position{Line: 1, Column: 1},
+			Init: BinaryExpression{
+				pos:      tokens.Position{Line: 1, Column: 1},
+				Operator: tokens.LESS,
+				Left: Expression{
+					pos: tokens.Position{Line: 1, Column: 1},
+					Init: ValueExpression{
+						pos:     tokens.Position{Line: 1, Column: 1},
+						Members: []ValueExpressionMember{{pos: tokens.Position{Line: 1, Column: 1}, Init: "a"}},
+					},
+				},
+				Right: Expression{
+					pos: tokens.Position{Line: 1, Column: 5},
+					Init: ValueExpression{
+						pos:     tokens.Position{Line: 1, Column: 5},
+						Members: []ValueExpressionMember{{pos: tokens.Position{Line: 1, Column: 5}, Init: "b"}},
+					},
+				},
+			},
+		},
+		expectsError: nil,
+		endingToken:  tokens.EOF,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 // CAN PARSE EMBEDDED EXPRESSION
 func TestEmbeddedExpression(t *testing.T) {
 	err := evaluateTest(TestFixture{
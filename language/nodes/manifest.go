@@ -45,11 +45,14 @@ func ParseManifest(p *parser.Parser) (*Manifest, error) {
 
 	pos, tok, lit := p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
 	if tok == tokens.CONTEXT {
-		p.Rollback(p.Index() - 2)
-		_, tok, _ = p.Scan()
-		if tok != tokens.COMMENT {
-			p.ScanIgnore(tokens.NEWLINE)
+		if p.Index() > 0 {
+			p.Rollback(p.Index() - 2)
+			_, tok, _ = p.Scan()
+			if tok != tokens.COMMENT {
+				p.ScanIgnore(tokens.NEWLINE)
+			}
 		}
+		// there's nothing to look back on if the context starts the manifest
 		p.Unscan()
 		context, err := ParseContext(p)
 		if err != nil {
@@ -37,6 +37,30 @@ func TestManifest(t *testing.T) {
 	}
 }
 
+// CAN PARSE MANIFEST WITHOUT IMPORTS
+func TestManifestWithoutImports(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: `context bar {}`,
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseManifest(p)
+		},
+		expects: &Manifest{
+			Imports: []ImportStatement{},
+			Context: Context{
+				pos:     tokens.Position{Line: 1, Column: 1},
+				Name:    "bar",
+				Objects: []Node{},
+				Comment: "",
+			},
+		},
+		expectsError: nil,
+		endingToken:  tokens.EOF,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 // ImportStatement
 // CAN PARSE IMPORT STATEMENT
 func TestImportStatement(t *testing.T) {
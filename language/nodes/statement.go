@@ -161,17 +161,23 @@ func ParseEnumStatement(p *parser.Parser) (*EnumStatement, error) {
 	return &es, nil
 }
 
-// TypeStatement :: IDENT TypeExpression
+// TypeStatement :: IDENT TypeExpression (ASSIGN Expression)?
 type TypeStatement struct {
-	pos  tokens.Position
-	Name string
-	Init TypeExpression
+	pos     tokens.Position
+	Name    string
+	Init    TypeExpression
+	Default *Expression
 }
 
 func (t TypeStatement) Validate() error {
 	if err := t.Init.Validate(); err != nil {
 		return err
 	}
+	if t.Default != nil {
+		if err := t.Default.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -193,5 +199,17 @@ func ParseTypeStatement(p *parser.Parser) (*TypeStatement, error) {
 	}
 	ts.Init = *te
 
+	startIndex := p.Index()
+	_, tok, _ = p.ScanIgnore(tokens.COMMENT)
+	if tok != tokens.ASSIGN {
+		p.Rollback(startIndex)
+		return &ts, nil
+	}
+	expr, err := ParseExpression(p)
+	if err != nil {
+		return nil, err
+	}
+	ts.Default = expr
+
 	return &ts, nil
 }
@@ -96,6 +96,49 @@ func TestFieldStatementWithTypeStatement(t *testing.T) {
 	}
 }
 
+// CAN PARSE FIELD STATEMENT WITH TYPE STATEMENT AND DEFAULT
+func TestFieldStatementWithTypeStatementDefault(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: `foo Bar = baz`,
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseFieldStatement(p)
+		},
+		expects: &FieldStatement{
+			pos: tokens.Position{Line: 1, Column: 1},
+			Init: TypeStatement{
+				pos:  tokens.Position{Line: 1, Column: 5},
+				Name: "foo",
+				Init: TypeExpression{
+					pos:        tokens.Position{Line: 1, Column: 9},
+					IsArray:    false,
+					IsOptional: false,
+					Selector: Selector{
+						pos:     tokens.Position{Line: 1, Column: 9},
+						Members: []string{"Bar"},
+					},
+				},
+				Default: &Expression{
+					pos: tokens.Position{Line: 1, Column: 15},
+					Init: ValueExpression{
+						pos: tokens.Position{Line: 1, Column: 15},
+						Members: []ValueExpressionMember{
+							{
+								pos:  tokens.Position{Line: 1, Column: 15},
+								Init: "baz",
+							},
+						},
+					},
+				},
+			},
+		},
+		expectsError: nil,
+		endingToken:  tokens.EOF,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 // CAN PARSE FIELD STATEMENT WITH COMMENT
 func TestFieldStatementWithComment(t *testing.T) {
 	err := evaluateTest(TestFixture{
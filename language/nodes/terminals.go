@@ -82,3 +82,57 @@ func ParseLiteral(p *parser.Parser) (*Literal, error) {
 		return nil, ExpectedError(pos, tokens.INT, lit)
 	}
 }
+
+// TemplateLiteral :: TEMPLATE
+//
+//	| TEMPLATE_HEAD Expression (TEMPLATE_MIDDLE Expression)* TEMPLATE_TAIL
+type TemplateLiteral struct {
+	pos tokens.Position
+	// Strings always has one more item than Expressions, where each expression
+	// goes between the strings that surround it
+	Strings     []string
+	Expressions []Expression
+}
+
+func (t TemplateLiteral) Validate() error {
+	for _, expr := range t.Expressions {
+		if err := expr.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t TemplateLiteral) Pos() tokens.Position {
+	return t.pos
+}
+
+func ParseTemplateLiteral(p *parser.Parser) (*TemplateLiteral, error) {
+	pos, tok, lit := p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+	template := TemplateLiteral{pos: pos, Strings: []string{lit}, Expressions: []Expression{}}
+	switch tok {
+	case tokens.TEMPLATE:
+		return &template, nil
+	case tokens.TEMPLATE_HEAD:
+	default:
+		return nil, ExpectedError(pos, tokens.TEMPLATE, lit)
+	}
+	for {
+		expr, err := ParseExpression(p)
+		if err != nil {
+			return nil, err
+		}
+		template.Expressions = append(template.Expressions, *expr)
+
+		pos, tok, lit := p.ScanIgnore(tokens.NEWLINE, tokens.COMMENT)
+		switch tok {
+		case tokens.TEMPLATE_MIDDLE:
+			template.Strings = append(template.Strings, lit)
+		case tokens.TEMPLATE_TAIL:
+			template.Strings = append(template.Strings, lit)
+			return &template, nil
+		default:
+			return nil, ExpectedError(pos, tokens.TEMPLATE_TAIL, lit)
+		}
+	}
+}
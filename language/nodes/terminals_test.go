@@ -130,3 +130,85 @@ func TestBooleanLiteral(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// TemplateLiteral
+// CAN PARSE TEMPLATE LITERAL WITHOUT SUBSTITUTIONS
+func TestTemplateLiteral(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: "`foo`",
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseExpression(p)
+		},
+		expects: &Expression{
+			pos: tokens.Position{Line: 1, Column: 1},
+			Init: TemplateLiteral{
+				pos:         tokens.Position{Line: 1, Column: 1},
+				Strings:     []string{"foo"},
+				Expressions: []Expression{},
+			},
+		},
+		expectsError: nil,
+		endingToken:  tokens.EOF,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// CAN PARSE TEMPLATE LITERAL WITH SUBSTITUTIONS
+func TestTemplateLiteralWithSubstitutions(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: "`a${b}c${1}`",
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseExpression(p)
+		},
+		expects: &Expression{
+			pos: tokens.Position{Line: 1, Column: 1},
+			Init: TemplateLiteral{
+				pos:     tokens.Position{Line: 1, Column: 1},
+				Strings: []string{"a", "c", ""},
+				Expressions: []Expression{
+					{
+						pos: tokens.Position{Line: 1, Column: 5},
+						Init: ValueExpression{
+							pos: tokens.Position{Line: 1, Column: 5},
+							Members: []ValueExpressionMember{
+								{
+									pos:  tokens.Position{Line: 1, Column: 5},
+									Init: "b",
+								},
+							},
+						},
+					},
+					{
+						pos: tokens.Position{Line: 1, Column: 10},
+						Init: Literal{
+							pos:   tokens.Position{Line: 1, Column: 10},
+							Value: int64(1),
+						},
+					},
+				},
+			},
+		},
+		expectsError: nil,
+		endingToken:  tokens.EOF,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// FAILS WHEN A SUBSTITUTION ISN'T CLOSED
+func TestTemplateLiteralUnterminatedSubstitution(t *testing.T) {
+	err := evaluateTest(TestFixture{
+		lit: "`a${b c}`",
+		parseFn: func(p *parser.Parser) (Node, error) {
+			return ParseExpression(p)
+		},
+		expects:      nil,
+		expectsError: ExpectedError(tokens.Position{Line: 1, Column: 7}, tokens.TEMPLATE_TAIL, "c"),
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
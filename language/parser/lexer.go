@@ -3,6 +3,7 @@ package parser
 import (
 	"bufio"
 	"io"
+	"strconv"
 	"unicode"
 
 	"github.com/hntrl/lang/language/tokens"
@@ -14,6 +15,10 @@ type Lexer struct {
 	pos    tokens.Position
 	reader *bufio.Reader
 	err    ErrorHandler
+	// the depth of curly braces inside of each template substitution that's
+	// currently open, so the closing brace of a substitution can be told apart
+	// from the closing brace of a nested object
+	templates []int
 }
 
 func NewLexer(reader *bufio.Reader, errHandler ErrorHandler) *Lexer {
@@ -24,9 +29,12 @@ func NewLexer(reader *bufio.Reader, errHandler ErrorHandler) *Lexer {
 	}
 }
 
+// Reads the next rune, which is 0 at the end of the input. Reaching the end
+// isn't reported here since only the caller knows whether something was left
+// unfinished
 func (l *Lexer) read() rune {
 	r, _, err := l.reader.ReadRune()
-	if err != nil {
+	if err != nil && err != io.EOF {
 		l.err(l.pos, err.Error())
 	}
 	l.pos.Column++
@@ -92,33 +100,23 @@ func (l *Lexer) lexNumber() (tokens.Token, string) {
 	}
 }
 func (l *Lexer) lexString() string {
-	var lit string
-
 	terminator := l.read()
 	if terminator != '"' && terminator != '\'' {
 		l.backup()
 		return ""
 	}
+	return l.lexStringBody(terminator)
+}
+func (l *Lexer) lexStringBody(terminator rune) string {
+	var lit string
+	start := l.pos
 	for {
 		r := l.read()
 		if r == '\\' {
-			r = l.read()
-			switch r {
-			case '\\':
-				lit += "\\"
-			case 'n':
-				lit += "\n"
-			case 't':
-				lit += "\t"
-			case 'r':
-				lit += "\r"
-			case 'x':
-				lit += "\\x" + l.digits()
-			case '\'':
-				lit += "'"
-			case '"':
-				lit += "\""
-			}
+			lit += l.lexEscape()
+		} else if r == 0 {
+			l.err(start, "unterminated string")
+			break
 		} else if r == terminator {
 			break
 		} else {
@@ -127,6 +125,114 @@ func (l *Lexer) lexString() string {
 	}
 	return lit
 }
+func (l *Lexer) lexEscape() string {
+	r := l.read()
+	switch r {
+	case '\\':
+		return "\\"
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case 'x':
+		return "\\x" + l.digits()
+	case 'u':
+		pos := l.pos
+		if l.read() != '{' {
+			l.err(pos, "expected { in unicode escape")
+			return ""
+		}
+		var hex string
+		for {
+			r := l.read()
+			if r == 0 {
+				l.err(pos, "unterminated unicode escape")
+				return ""
+			}
+			if r == '}' {
+				break
+			}
+			hex += string(r)
+		}
+		code, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || code > unicode.MaxRune {
+			l.err(pos, "invalid unicode escape \\u{"+hex+"}")
+			return ""
+		}
+		return string(rune(code))
+	case '\'':
+		return "'"
+	case '"':
+		return "\""
+	case '`':
+		return "`"
+	case '$':
+		return "$"
+	}
+	return ""
+}
+
+// Lexes the contents of a raw string after the opening """ up to the closing
+// """. Newlines are kept and escapes aren't interpreted
+func (l *Lexer) lexRawString() string {
+	var lit string
+	start := l.pos
+	quotes := 0
+	for {
+		r := l.read()
+		if r == 0 {
+			l.err(start, "unterminated raw string")
+			break
+		}
+		if r == '"' {
+			quotes++
+			if quotes == 3 {
+				break
+			}
+			continue
+		}
+		for ; quotes > 0; quotes-- {
+			lit += "\""
+		}
+		if r == '\n' {
+			l.resetPosition()
+		}
+		lit += string(r)
+	}
+	return lit
+}
+
+// Lexes the text of a template literal up to the next substitution or the
+// closing backtick. Returns whether the template is closed
+func (l *Lexer) lexTemplateText() (string, bool) {
+	var lit string
+	start := l.pos
+	for {
+		r := l.read()
+		switch r {
+		case 0:
+			l.err(start, "unterminated template literal")
+			return lit, true
+		case '`':
+			return lit, true
+		case '\\':
+			lit += l.lexEscape()
+		case '$':
+			if l.peek() == '{' {
+				l.read()
+				return lit, false
+			}
+			lit += string(r)
+		case '\n':
+			l.resetPosition()
+			lit += string(r)
+		default:
+			lit += string(r)
+		}
+	}
+}
 func (l *Lexer) lexComment() string {
 	var lit string
 	first := l.read()
@@ -159,6 +265,7 @@ func (l *Lexer) lexComment() string {
 			}
 		}
 	} else if first == '*' {
+		start := l.pos
 		for {
 			r := l.read()
 			if r == '\n' {
@@ -170,6 +277,7 @@ func (l *Lexer) lexComment() string {
 					break
 				}
 			} else if r == 0 {
+				l.err(start, "unterminated comment")
 				break
 			} else {
 				lit += string(r)
@@ -210,8 +318,25 @@ func (l *Lexer) Lex() (tokens.Position, tokens.Token, string) {
 				l.resetPosition()
 				tok, lit = tokens.NEWLINE, "\\n"
 			case '"', '\'':
-				l.backup()
-				tok, lit = tokens.STRING, l.lexString()
+				if r == '"' && l.peek() == '"' {
+					l.read()
+					if l.peek() == '"' {
+						l.read()
+						tok, lit = tokens.STRING, l.lexRawString()
+					} else {
+						tok, lit = tokens.STRING, ""
+					}
+				} else {
+					tok, lit = tokens.STRING, l.lexStringBody(r)
+				}
+			case '`':
+				text, closed := l.lexTemplateText()
+				if closed {
+					tok, lit = tokens.TEMPLATE, text
+				} else {
+					l.templates = append(l.templates, 0)
+					tok, lit = tokens.TEMPLATE_HEAD, text
+				}
 			case '&':
 				if l.peek() == '&' {
 					l.read()
@@ -275,8 +400,25 @@ func (l *Lexer) Lex() (tokens.Position, tokens.Token, string) {
 			case '?':
 				tok, lit = tokens.QUESTION, "?"
 			case '{':
+				if len(l.templates) > 0 {
+					l.templates[len(l.templates)-1]++
+				}
 				tok, lit = tokens.LCURLY, "{"
 			case '}':
+				if len(l.templates) > 0 {
+					depth := len(l.templates) - 1
+					if l.templates[depth] == 0 {
+						text, closed := l.lexTemplateText()
+						if closed {
+							l.templates = l.templates[:depth]
+							tok, lit = tokens.TEMPLATE_TAIL, text
+						} else {
+							tok, lit = tokens.TEMPLATE_MIDDLE, text
+						}
+						break
+					}
+					l.templates[depth]--
+				}
 				tok, lit = tokens.RCURLY, "}"
 			case '[':
 				tok, lit = tokens.LSQUARE, "["
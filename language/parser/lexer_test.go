@@ -141,6 +141,141 @@ func TestLexStringWithEscapeCharacters(t *testing.T) {
 	}
 }
 
+// CAN HANDLE UNICODE ESCAPES
+func TestLexStringWithUnicodeEscape(t *testing.T) {
+	// Setup
+	lexer := setupLexer("\"caf\\u{e9} \\u{1F600}\"")
+	lit := lexer.lexString()
+
+	// Assert
+	if lit != "café 😀" {
+		t.Errorf("Expected café 😀, got %v", lit)
+	}
+}
+
+// CAN IDENTIFY A RAW MULTI-LINE STRING
+func TestLexRawString(t *testing.T) {
+	// Setup
+	lexer := setupLexer("\"\"\"a\n\"b\"\\n\"\"\"")
+	pos, tok, lit := lexer.Lex()
+
+	// Assert
+	if tok != tokens.STRING {
+		t.Errorf("Expected STRING, got %v", tok)
+	}
+	if lit != "a\n\"b\"\\n" {
+		t.Errorf("Expected a\\n\"b\"\\\\n, got %v", lit)
+	}
+	if pos.Line != 1 || lexer.pos.Line != 2 {
+		t.Errorf("Expected raw string to span 2 lines, got %v", lexer.pos.Line)
+	}
+}
+
+// CAN IDENTIFY AN EMPTY STRING
+func TestLexEmptyString(t *testing.T) {
+	// Setup
+	lexer := setupLexer("\"\" abc")
+	_, tok, lit := lexer.Lex()
+
+	// Assert
+	if tok != tokens.STRING || lit != "" {
+		t.Errorf("Expected empty STRING, got %v %v", tok, lit)
+	}
+	_, tok, _ = lexer.Lex()
+	if tok != tokens.IDENT {
+		t.Errorf("Expected IDENT, got %v", tok)
+	}
+}
+
+// CAN TOKENIZE TEMPLATE LITERALS
+func TestLexTemplate(t *testing.T) {
+	type TokenFixture struct {
+		Token   tokens.Token
+		Literal string
+	}
+	// Setup
+	expected := []TokenFixture{
+		{tokens.TEMPLATE_HEAD, "a "},
+		{tokens.IDENT, "b"},
+		{tokens.TEMPLATE_MIDDLE, " c "},
+		{tokens.IDENT, "d"},
+		{tokens.LCURLY, "{"},
+		{tokens.RCURLY, "}"},
+		{tokens.TEMPLATE_TAIL, " $e`"},
+		{tokens.TEMPLATE, "f\ng"},
+	}
+	lexer := setupLexer("`a ${b} c ${d{}} $e\\``\n`f\ng`")
+
+	// Assert
+	for _, expected := range expected {
+		_, tok, lit := lexer.Lex()
+		for tok == tokens.NEWLINE {
+			_, tok, lit = lexer.Lex()
+		}
+		if tok != expected.Token {
+			t.Errorf("Expected %v, got %v", expected.Token, tok)
+		}
+		if lit != expected.Literal {
+			t.Errorf("Expected %v, got %v", expected.Literal, lit)
+		}
+	}
+}
+
+// CAN REPORT STRINGS AND TEMPLATES THAT AREN'T CLOSED BEFORE THE END OF THE INPUT
+func TestLexUnterminated(t *testing.T) {
+	type UnterminatedFixture struct {
+		Source  string
+		Message string
+	}
+	// Setup
+	fixtures := []UnterminatedFixture{
+		{"\"abc", "unterminated string"},
+		{"'abc\\'", "unterminated string"},
+		{"\"\\u{e9", "unterminated unicode escape"},
+		{"\"\"\"abc\"\"", "unterminated raw string"},
+		{"`abc", "unterminated template literal"},
+		{"`a ${b} c", "unterminated template literal"},
+		{"/* abc", "unterminated comment"},
+	}
+
+	// Assert
+	for _, fixture := range fixtures {
+		var errors []string
+		lexer := NewLexer(bufio.NewReader(strings.NewReader(fixture.Source)), func(pos tokens.Position, msg string) {
+			errors = append(errors, msg)
+		})
+		for {
+			_, tok, _ := lexer.Lex()
+			if tok == tokens.EOF {
+				break
+			}
+		}
+		if len(errors) == 0 || errors[0] != fixture.Message {
+			t.Errorf("Expected %v for %v, got %v", fixture.Message, fixture.Source, errors)
+		}
+	}
+}
+
+// CAN REACH THE END OF THE INPUT WITHOUT REPORTING AN ERROR
+func TestLexEOF(t *testing.T) {
+	// Setup
+	var errors []string
+	lexer := NewLexer(bufio.NewReader(strings.NewReader("a \"b\" `c ${d}`")), func(pos tokens.Position, msg string) {
+		errors = append(errors, msg)
+	})
+	for {
+		_, tok, _ := lexer.Lex()
+		if tok == tokens.EOF {
+			break
+		}
+	}
+
+	// Assert
+	if len(errors) != 0 {
+		t.Errorf("Expected no errors, got %v", errors)
+	}
+}
+
 // lexComment()
 // CAN IDENTIFY A MULTI-LINE COMMENT
 func TestLexMultiLineComment(t *testing.T) {
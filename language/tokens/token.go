@@ -14,6 +14,12 @@ const (
 	FLOAT  // 215.34
 	STRING // "abc"
 
+	// Template literals
+	TEMPLATE        // `abc`
+	TEMPLATE_HEAD   // `abc${
+	TEMPLATE_MIDDLE // }abc${
+	TEMPLATE_TAIL   // }abc`
+
 	operator_beg
 	// Operators and delimiters
 	ADD // +
@@ -102,6 +108,11 @@ var tokens = []string{
 	FLOAT:  "FLOAT",
 	STRING: "STRING",
 
+	TEMPLATE:        "TEMPLATE",
+	TEMPLATE_HEAD:   "TEMPLATE_HEAD",
+	TEMPLATE_MIDDLE: "TEMPLATE_MIDDLE",
+	TEMPLATE_TAIL:   "TEMPLATE_TAIL",
+
 	AND: "&&",
 	OR:  "||",
 	INC: "++",
@@ -0,0 +1,178 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Batch is a StorageDriver that holds onto writes until they're committed to
+// the driver it wraps. Reads made through the batch see its writes as if
+// they'd already been made
+type Batch struct {
+	driver StorageDriver
+	writes []Write
+	// the documents as they are after the writes, nil if they've been removed
+	pending map[string]map[string][]byte
+}
+
+func NewBatch(driver StorageDriver) *Batch {
+	return &Batch{
+		driver:  driver,
+		pending: make(map[string]map[string][]byte),
+	}
+}
+
+func (b *Batch) Attach() (Resource, error) {
+	return b, nil
+}
+func (b *Batch) Detach() error {
+	return nil
+}
+
+func (b *Batch) write(write Write) error {
+	var data []byte
+	if write.Document != nil {
+		var err error
+		data, err = json.Marshal(write.Document)
+		if err != nil {
+			return err
+		}
+	}
+	if b.pending[write.Collection] == nil {
+		b.pending[write.Collection] = make(map[string][]byte)
+	}
+	b.pending[write.Collection][write.Key] = data
+	b.writes = append(b.writes, write)
+	return nil
+}
+
+func (b *Batch) Get(collection, key string) (Document, error) {
+	if data, ok := b.pending[collection][key]; ok {
+		if data == nil {
+			return nil, fmt.Errorf("%s %s: %w", collection, key, ErrNotFound)
+		}
+		return decodeDocument(data)
+	}
+	return b.driver.Get(collection, key)
+}
+func (b *Batch) Find(collection string, filter Document) ([]Record, error) {
+	stored, err := b.driver.Find(collection, filter)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	pending := b.pending[collection]
+	out := []Record{}
+	for _, record := range stored {
+		if _, ok := pending[record.Key]; !ok {
+			out = append(out, record)
+		}
+	}
+	for key, data := range pending {
+		if data == nil {
+			continue
+		}
+		doc, err := decodeDocument(data)
+		if err != nil {
+			return nil, err
+		}
+		if matchesFilter(doc, normalized) {
+			out = append(out, Record{Key: key, Document: doc})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+	return out, nil
+}
+func (b *Batch) Count(collection string, filter Document) (int, error) {
+	records, err := b.Find(collection, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+func (b *Batch) Insert(collection, key string, doc Document) error {
+	_, err := b.Get(collection, key)
+	if err == nil {
+		return fmt.Errorf("%s %s: %w", collection, key, ErrExists)
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return b.write(Write{Collection: collection, Key: key, Document: doc, Insert: true})
+}
+func (b *Batch) Put(collection, key string, doc Document) error {
+	return b.write(Write{Collection: collection, Key: key, Document: doc})
+}
+func (b *Batch) Delete(collection, key string) error {
+	if _, err := b.Get(collection, key); err != nil {
+		return err
+	}
+	return b.write(Write{Collection: collection, Key: key})
+}
+func (b *Batch) Apply(writes []Write) error {
+	for _, write := range writes {
+		// a write with fields to match is kept with them, so that they're matched
+		// again when the batch is committed
+		if write.Match != nil {
+			if err := b.match(write); err != nil {
+				return err
+			}
+			if err := b.write(write); err != nil {
+				return err
+			}
+			continue
+		}
+		var err error
+		switch {
+		case write.Document == nil:
+			err = b.Delete(write.Collection, write.Key)
+		case write.Insert:
+			err = b.Insert(write.Collection, write.Key, write.Document)
+		default:
+			err = b.Put(write.Collection, write.Key, write.Document)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Batch) match(write Write) error {
+	if write.Insert {
+		return fmt.Errorf("%s %s: cannot insert a document that has to match another", write.Collection, write.Key)
+	}
+	doc, err := b.Get(write.Collection, write.Key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return matchWrite(write, data, true)
+}
+
+// Makes the writes in the order they were made through the batch, all of them
+// or none. The documents they touch can have changed since, so an insert can
+// still fail
+func (b *Batch) Commit() error {
+	writes := b.writes
+	b.Discard()
+	if len(writes) == 0 {
+		return nil
+	}
+	return b.driver.Apply(writes)
+}
+
+// Forgets every write that hasn't been committed
+func (b *Batch) Discard() {
+	b.writes = nil
+	b.pending = make(map[string]map[string][]byte)
+}
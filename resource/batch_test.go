@@ -0,0 +1,176 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// Apply
+// CAN MAKE EVERY WRITE OR NONE OF THEM
+func TestMemoryStoreApply(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.Put("c", "a", Document{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	err := store.Apply([]Write{
+		{Collection: "c", Key: "b", Document: Document{"n": 2}},
+		{Collection: "c", Key: "a", Document: Document{"n": 3}, Insert: true},
+	})
+	if !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+	if _, err := store.Get("c", "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected b to be left out, got %v", err)
+	}
+
+	err = store.Apply([]Write{
+		{Collection: "c", Key: "a"},
+		{Collection: "c", Key: "a", Document: Document{"n": 4}, Insert: true},
+		{Collection: "c", Key: "b"},
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if doc, _ := store.Get("c", "a"); doc["n"] != json.Number("1") {
+		t.Errorf("expected a to be left as it was, got %v", doc)
+	}
+
+	err = store.Apply([]Write{
+		{Collection: "c", Key: "a"},
+		{Collection: "c", Key: "a", Document: Document{"n": 4}, Insert: true},
+		{Collection: "c", Key: "b", Document: Document{"n": 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := store.Count("c", Document{}); count != 2 {
+		t.Errorf("expected 2 documents, got %d", count)
+	}
+	if doc, _ := store.Get("c", "a"); doc["n"] != json.Number("4") {
+		t.Errorf("expected a to be replaced, got %v", doc)
+	}
+}
+
+// CAN ONLY MAKE WRITES TO DOCUMENTS THAT MATCH
+func TestMemoryStoreApplyMatch(t *testing.T) {
+	store := NewMemoryStore()
+	store.Put("c", "a", Document{"n": 1, "v": "x"})
+	err := store.Apply([]Write{
+		{Collection: "c", Key: "a", Match: Document{"v": "y"}},
+		{Collection: "c", Key: "a", Document: Document{"n": 2}, Insert: true},
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+	err = store.Apply([]Write{{Collection: "c", Key: "b", Match: Document{"v": "x"}}})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	err = store.Apply([]Write{
+		{Collection: "c", Key: "a", Match: Document{"v": "x"}},
+		{Collection: "c", Key: "a", Document: Document{"n": 2, "v": "y"}, Insert: true},
+		{Collection: "c", Key: "a", Document: Document{"n": 3, "v": "y"}, Match: Document{"n": 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, _ := store.Get("c", "a"); doc["n"] != json.Number("3") {
+		t.Errorf("expected a to be replaced, got %v", doc)
+	}
+}
+
+// Batch
+// CAN READ WRITES BEFORE THEY'RE COMMITTED
+func TestBatchReads(t *testing.T) {
+	store := NewMemoryStore()
+	store.Put("c", "a", Document{"n": 1})
+	store.Put("c", "b", Document{"n": 1})
+	batch := NewBatch(store)
+	batch.Put("c", "a", Document{"n": 2})
+	batch.Delete("c", "b")
+	batch.Insert("c", "d", Document{"n": 1})
+
+	if doc, _ := batch.Get("c", "a"); doc["n"] != json.Number("2") {
+		t.Errorf("expected the pending document, got %v", doc)
+	}
+	if _, err := batch.Get("c", "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected b to be removed, got %v", err)
+	}
+	records, err := batch.Find("c", Document{"n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Key != "d" {
+		t.Errorf("expected only d, got %+v", records)
+	}
+	if err := batch.Insert("c", "a", Document{}); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+	if doc, _ := store.Get("c", "a"); doc["n"] != json.Number("1") {
+		t.Errorf("expected the store to be left as it was, got %v", doc)
+	}
+}
+
+// CAN COMMIT WRITES TOGETHER
+func TestBatchCommit(t *testing.T) {
+	store := NewMemoryStore()
+	batch := NewBatch(store)
+	batch.Insert("c", "a", Document{"n": 1})
+	batch.Insert("c", "b", Document{"n": 1})
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := store.Count("c", Document{}); count != 2 {
+		t.Errorf("expected 2 documents, got %d", count)
+	}
+
+	// the key is used by the time the batch is committed
+	batch.Put("c", "a", Document{"n": 2})
+	batch.Insert("c", "e", Document{"n": 1})
+	store.Insert("c", "e", Document{"n": 0})
+	if err := batch.Commit(); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+	if doc, _ := store.Get("c", "a"); doc["n"] != json.Number("1") {
+		t.Errorf("expected none of the writes to be made, got %v", doc)
+	}
+	if _, err := batch.Get("c", "a"); err != nil {
+		t.Errorf("expected the batch to be emptied, got %v", err)
+	}
+}
+
+// CAN MATCH DOCUMENTS AGAIN WHEN THE BATCH IS COMMITTED
+func TestBatchMatch(t *testing.T) {
+	store := NewMemoryStore()
+	store.Put("c", "a", Document{"v": "x"})
+	batch := NewBatch(store)
+	if err := batch.Apply([]Write{{Collection: "c", Key: "a", Match: Document{"v": "y"}}}); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+	if err := batch.Apply([]Write{{Collection: "c", Key: "a", Document: Document{"v": "z"}, Match: Document{"v": "x"}}}); err != nil {
+		t.Fatal(err)
+	}
+	// the document changes before the batch is committed
+	store.Put("c", "a", Document{"v": "w"})
+	if err := batch.Commit(); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+	if doc, _ := store.Get("c", "a"); doc["v"] != "w" {
+		t.Errorf("expected a to be left as it was, got %v", doc)
+	}
+}
+
+// CAN DISCARD WRITES
+func TestBatchDiscard(t *testing.T) {
+	store := NewMemoryStore()
+	batch := NewBatch(store)
+	batch.Put("c", "a", Document{"n": 1})
+	batch.Discard()
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := store.Count("c", Document{}); count != 0 {
+		t.Errorf("expected no documents, got %d", count)
+	}
+}
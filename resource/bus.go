@@ -0,0 +1,113 @@
+package resource
+
+import (
+	"errors"
+	"sync"
+)
+
+// Message represents an event as it's carried by an EventBus
+type Message struct {
+	Subject string
+	Data    []byte
+}
+
+// Subscription represents the interest of a handler in a subject
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// EventBus represents anything that can deliver published messages to the
+// handlers subscribed to their subject
+type EventBus interface {
+	Resource
+	Publish(msg Message) error
+	// Delivers every message published to the subject to the handler. Handlers
+	// that share a queue group take turns so that only one of them gets each
+	// message, and an empty queue group means the handler gets every message
+	Subscribe(subject, queue string, handler func(Message) error) (Subscription, error)
+}
+
+// MemoryBus is an EventBus that delivers messages to its handlers before
+// Publish returns, so that anything depending on events can be tested without
+// waiting on them
+type MemoryBus struct {
+	mu   *sync.Mutex
+	subs map[string][]*memorySubscription
+	// the index of the handler that's next in line for each queue group
+	turns map[string]int
+}
+
+type memorySubscription struct {
+	bus     MemoryBus
+	subject string
+	queue   string
+	handler func(Message) error
+}
+
+func NewMemoryBus() MemoryBus {
+	return MemoryBus{
+		mu:    &sync.Mutex{},
+		subs:  make(map[string][]*memorySubscription),
+		turns: make(map[string]int),
+	}
+}
+
+func (bus MemoryBus) Attach() (Resource, error) {
+	if bus.subs == nil {
+		return NewMemoryBus(), nil
+	}
+	return bus, nil
+}
+func (bus MemoryBus) Detach() error {
+	return nil
+}
+
+// Delivers the message to its handlers, returning the errors they return
+func (bus MemoryBus) Publish(msg Message) error {
+	bus.mu.Lock()
+	handlers := []func(Message) error{}
+	groups := make(map[string][]*memorySubscription)
+	for _, sub := range bus.subs[msg.Subject] {
+		if sub.queue == "" {
+			handlers = append(handlers, sub.handler)
+		} else {
+			groups[sub.queue] = append(groups[sub.queue], sub)
+		}
+	}
+	for queue, subs := range groups {
+		key := msg.Subject + " " + queue
+		turn := bus.turns[key] % len(subs)
+		bus.turns[key] = turn + 1
+		handlers = append(handlers, subs[turn].handler)
+	}
+	bus.mu.Unlock()
+
+	errs := []error{}
+	for _, handler := range handlers {
+		if err := handler(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+func (bus MemoryBus) Subscribe(subject, queue string, handler func(Message) error) (Subscription, error) {
+	sub := &memorySubscription{bus: bus, subject: subject, queue: queue, handler: handler}
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	bus.subs[subject] = append(bus.subs[subject], sub)
+	return sub, nil
+}
+
+func (sub *memorySubscription) Unsubscribe() error {
+	bus := sub.bus
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	subs := bus.subs[sub.subject]
+	for idx, other := range subs {
+		if other == sub {
+			bus.subs[sub.subject] = append(subs[:idx:idx], subs[idx+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+// CAN READ THE HOST AND CREDENTIALS OF A BLANK CONNECTION FROM THE ENVIRONMENT
+func TestMongoConnectionFromEnv(t *testing.T) {
+	t.Setenv("MONGO_HOST", "db")
+	t.Setenv("MONGO_USER", "admin")
+	t.Setenv("MONGO_PASSWORD", "secret")
+
+	opts := MongoConnection{}.clientOptions()
+	if len(opts.Hosts) != 1 || opts.Hosts[0] != "db:27017" {
+		t.Errorf("expected db:27017, got %v", opts.Hosts)
+	}
+	if opts.Auth == nil || opts.Auth.Username != "admin" || opts.Auth.Password != "secret" {
+		t.Errorf("expected the credentials of the environment, got %+v", opts.Auth)
+	}
+
+	// settings that are given aren't replaced
+	opts = MongoConnection{URL: "mongodb://other:27018", Username: "app", Password: "pw"}.clientOptions()
+	if len(opts.Hosts) != 1 || opts.Hosts[0] != "other:27018" || opts.Auth.Username != "app" || opts.Auth.Password != "pw" {
+		t.Errorf("expected the settings of the connection, got %v %+v", opts.Hosts, opts.Auth)
+	}
+}
+
+// CAN CONNECT TO THE DEFAULT SERVER WITHOUT CREDENTIALS WHEN NOTHING IS SET
+func TestMongoConnectionDefaults(t *testing.T) {
+	t.Setenv("MONGO_HOST", "")
+	t.Setenv("MONGO_PASSWORD", "")
+
+	opts := MongoConnection{}.clientOptions()
+	if len(opts.Hosts) != 1 || opts.Hosts[0] != "localhost:27017" || opts.Auth != nil {
+		t.Errorf("expected localhost without credentials, got %v %+v", opts.Hosts, opts.Auth)
+	}
+}
+
+// CAN READ THE HOST OF A BLANK NATS CONNECTION FROM THE ENVIRONMENT
+func TestNatsConnectionFromEnv(t *testing.T) {
+	t.Setenv("NATS_HOST", "queue")
+	if url := (NatsConnection{}).url(); url != "nats://queue:4222" {
+		t.Errorf("expected nats://queue:4222, got %s", url)
+	}
+	if url := (NatsConnection{URL: "nats://other:4223"}).url(); url != "nats://other:4223" {
+		t.Errorf("expected the URL of the connection, got %s", url)
+	}
+	t.Setenv("NATS_HOST", "")
+	if url := (NatsConnection{}).url(); url != nats.DefaultURL {
+		t.Errorf("expected %s, got %s", nats.DefaultURL, url)
+	}
+}
@@ -0,0 +1,195 @@
+package resource
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileEventStore is an EventStore that appends events and snapshots to files
+// in a directory, one JSON object per line. Every event is also kept in memory
+// once the store is attached
+type FileEventStore struct {
+	// The directory the files are kept in
+	Dir       string
+	mu        *sync.RWMutex
+	index     *eventIndex
+	events    *os.File
+	snapshots *os.File
+}
+
+func NewFileEventStore(dir string) FileEventStore {
+	return FileEventStore{Dir: dir}
+}
+
+// Reads every line of a file into values made by a function, and returns how
+// long the file is up to the end of its last line. A line that isn't ended, i.e.
+// because writing it was cut short, is left out
+func readLines(path string, each func(data []byte) error) (int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(file, 64*1024)
+	var size int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return size, nil
+		} else if err != nil {
+			return 0, err
+		}
+		size += int64(len(line))
+		line = line[:len(line)-1]
+		if len(line) == 0 {
+			continue
+		}
+		if err := each(line); err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+}
+
+// Opens a file to append lines to, leaving out anything after the end of its
+// last line so the next line starts on its own
+func openLines(path string, size int64) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func (store FileEventStore) Attach() (Resource, error) {
+	if store.Dir == "" {
+		return nil, fmt.Errorf("no directory for event store")
+	}
+	if err := os.MkdirAll(store.Dir, 0755); err != nil {
+		return nil, err
+	}
+	store.mu = &sync.RWMutex{}
+	store.index = newEventIndex()
+
+	eventsPath := filepath.Join(store.Dir, "events.jsonl")
+	eventsSize, err := readLines(eventsPath, func(data []byte) error {
+		var event StoredEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		store.index.add(event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	snapshotsPath := filepath.Join(store.Dir, "snapshots.jsonl")
+	snapshotsSize, err := readLines(snapshotsPath, func(data []byte) error {
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return err
+		}
+		store.index.snapshots[snapshot.Stream] = snapshot
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store.events, err = openLines(eventsPath, eventsSize)
+	if err != nil {
+		return nil, err
+	}
+	store.snapshots, err = openLines(snapshotsPath, snapshotsSize)
+	if err != nil {
+		store.events.Close()
+		return nil, err
+	}
+	return store, nil
+}
+func (store FileEventStore) Detach() error {
+	if store.events == nil {
+		return nil
+	}
+	return errors.Join(store.events.Close(), store.snapshots.Close())
+}
+
+// Writes values to the end of a file, one per line, and waits for them to be
+// on disk. If they can't be written the file is cut back to how it was, so
+// that none of them are kept
+func appendLines[T any](file *os.File, values ...T) error {
+	buf := []byte{}
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, data...), '\n')
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf); err != nil {
+		return errors.Join(err, file.Truncate(info.Size()))
+	}
+	if err := file.Sync(); err != nil {
+		return errors.Join(err, file.Truncate(info.Size()))
+	}
+	return nil
+}
+
+func (store FileEventStore) Append(stream string, expected int, events []json.RawMessage) ([]StoredEvent, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	stored, err := store.index.prepare(stream, expected, events)
+	if err != nil {
+		return nil, err
+	}
+	if err := appendLines(store.events, stored...); err != nil {
+		return nil, err
+	}
+	store.index.add(stored...)
+	return stored, nil
+}
+func (store FileEventStore) Load(stream string, after int) ([]StoredEvent, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.index.load(stream, after), nil
+}
+func (store FileEventStore) ReadAll(after int64, limit int) ([]StoredEvent, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.index.readAll(after, limit), nil
+}
+func (store FileEventStore) Head() (int64, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return int64(len(store.index.events)), nil
+}
+func (store FileEventStore) SaveSnapshot(snapshot Snapshot) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if err := appendLines(store.snapshots, snapshot); err != nil {
+		return err
+	}
+	store.index.snapshots[snapshot.Stream] = snapshot
+	return nil
+}
+func (store FileEventStore) LoadSnapshot(stream string) (Snapshot, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.index.loadSnapshot(stream)
+}
@@ -0,0 +1,177 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrConflict = errors.New("version conflict")
+
+// StoredEvent represents an event as it's kept in an event store
+type StoredEvent struct {
+	Stream string `json:"stream"`
+	// The position of the event in its stream, starting at 1
+	Version int `json:"version"`
+	// The position of the event among every event in the store, starting at 1
+	Position int64           `json:"position"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Snapshot represents the state of a stream at a version, so that it doesn't
+// need to be replayed from the start
+type Snapshot struct {
+	Stream  string          `json:"stream"`
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// EventStore represents anything that can keep streams of events, where every
+// event is only ever added to the end of its stream
+type EventStore interface {
+	Resource
+	// Adds events to the end of a stream, or returns ErrConflict if the stream
+	// isn't at the expected version. A stream that doesn't exist is at 0
+	Append(stream string, expected int, events []json.RawMessage) ([]StoredEvent, error)
+	// Returns the events of a stream that come after a version
+	Load(stream string, after int) ([]StoredEvent, error)
+	// Returns up to a limit of events from every stream that come after a
+	// position, in the order they were added
+	ReadAll(after int64, limit int) ([]StoredEvent, error)
+	// Returns the position of the last event that was added
+	Head() (int64, error)
+	SaveSnapshot(snapshot Snapshot) error
+	// Returns the latest snapshot of a stream, or ErrNotFound if there isn't one
+	LoadSnapshot(stream string) (Snapshot, error)
+}
+
+// eventIndex keeps the events and snapshots of an event store in memory
+type eventIndex struct {
+	events []StoredEvent
+	// the indexes of the events of each stream in the order they were added
+	streams   map[string][]int
+	snapshots map[string]Snapshot
+}
+
+func newEventIndex() *eventIndex {
+	return &eventIndex{
+		streams:   make(map[string][]int),
+		snapshots: make(map[string]Snapshot),
+	}
+}
+
+// Returns the events that would be added to a stream, without adding them
+func (index *eventIndex) prepare(stream string, expected int, events []json.RawMessage) ([]StoredEvent, error) {
+	if version := len(index.streams[stream]); version != expected {
+		return nil, fmt.Errorf("%s is at version %d, expected %d: %w", stream, version, expected, ErrConflict)
+	}
+	out := make([]StoredEvent, len(events))
+	for idx, data := range events {
+		out[idx] = StoredEvent{
+			Stream:   stream,
+			Version:  expected + idx + 1,
+			Position: int64(len(index.events) + idx + 1),
+			Data:     data,
+		}
+	}
+	return out, nil
+}
+func (index *eventIndex) add(events ...StoredEvent) {
+	for _, event := range events {
+		index.streams[event.Stream] = append(index.streams[event.Stream], len(index.events))
+		index.events = append(index.events, event)
+	}
+}
+func (index *eventIndex) load(stream string, after int) []StoredEvent {
+	out := []StoredEvent{}
+	for _, idx := range index.streams[stream] {
+		if index.events[idx].Version > after {
+			out = append(out, index.events[idx])
+		}
+	}
+	return out
+}
+func (index *eventIndex) readAll(after int64, limit int) []StoredEvent {
+	if after < 0 {
+		after = 0
+	}
+	if after >= int64(len(index.events)) {
+		return []StoredEvent{}
+	}
+	end := len(index.events)
+	if limit > 0 && int(after)+limit < end {
+		end = int(after) + limit
+	}
+	out := make([]StoredEvent, end-int(after))
+	copy(out, index.events[after:end])
+	return out
+}
+func (index *eventIndex) loadSnapshot(stream string) (Snapshot, error) {
+	snapshot, ok := index.snapshots[stream]
+	if !ok {
+		return Snapshot{}, fmt.Errorf("snapshot of %s: %w", stream, ErrNotFound)
+	}
+	return snapshot, nil
+}
+
+// MemoryEventStore is an EventStore that keeps every event in memory. Nothing
+// is persisted, so it's meant for tests and local development
+type MemoryEventStore struct {
+	mu    *sync.RWMutex
+	index *eventIndex
+}
+
+func NewMemoryEventStore() MemoryEventStore {
+	return MemoryEventStore{
+		mu:    &sync.RWMutex{},
+		index: newEventIndex(),
+	}
+}
+
+func (store MemoryEventStore) Attach() (Resource, error) {
+	if store.index == nil {
+		return NewMemoryEventStore(), nil
+	}
+	return store, nil
+}
+func (store MemoryEventStore) Detach() error {
+	return nil
+}
+
+func (store MemoryEventStore) Append(stream string, expected int, events []json.RawMessage) ([]StoredEvent, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	stored, err := store.index.prepare(stream, expected, events)
+	if err != nil {
+		return nil, err
+	}
+	store.index.add(stored...)
+	return stored, nil
+}
+func (store MemoryEventStore) Load(stream string, after int) ([]StoredEvent, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.index.load(stream, after), nil
+}
+func (store MemoryEventStore) ReadAll(after int64, limit int) ([]StoredEvent, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.index.readAll(after, limit), nil
+}
+func (store MemoryEventStore) Head() (int64, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return int64(len(store.index.events)), nil
+}
+func (store MemoryEventStore) SaveSnapshot(snapshot Snapshot) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.index.snapshots[snapshot.Stream] = snapshot
+	return nil
+}
+func (store MemoryEventStore) LoadSnapshot(stream string) (Snapshot, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.index.loadSnapshot(stream)
+}
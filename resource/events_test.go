@@ -0,0 +1,179 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func attachEventStore(t *testing.T, store EventStore) EventStore {
+	t.Helper()
+	attached, err := store.Attach()
+	if err != nil {
+		t.Fatalf("cannot attach: %s", err)
+	}
+	t.Cleanup(func() {
+		attached.Detach()
+	})
+	return attached.(EventStore)
+}
+
+func eventData(values ...string) []json.RawMessage {
+	out := make([]json.RawMessage, len(values))
+	for idx, value := range values {
+		out[idx] = json.RawMessage(`"` + value + `"`)
+	}
+	return out
+}
+
+func eventStores(t *testing.T) map[string]EventStore {
+	return map[string]EventStore{
+		"memory": attachEventStore(t, NewMemoryEventStore()),
+		"file":   attachEventStore(t, NewFileEventStore(t.TempDir())),
+	}
+}
+
+// Append
+// CAN APPEND TO STREAMS AND LOAD THEM
+func TestEventStoreAppend(t *testing.T) {
+	for name, store := range eventStores(t) {
+		stored, err := store.Append("a", 0, eventData("a1", "a2"))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if len(stored) != 2 || stored[1].Version != 2 || stored[1].Position != 2 {
+			t.Errorf("%s: unexpected stored events %+v", name, stored)
+		}
+		if _, err := store.Append("b", 0, eventData("b1")); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if _, err := store.Append("a", 2, eventData("a3")); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		events, err := store.Load("a", 1)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if len(events) != 2 || string(events[0].Data) != `"a2"` || string(events[1].Data) != `"a3"` {
+			t.Errorf("%s: unexpected events after version 1 %+v", name, events)
+		}
+		all, err := store.ReadAll(1, 2)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if len(all) != 2 || all[0].Stream != "a" || all[1].Stream != "b" {
+			t.Errorf("%s: unexpected events after position 1 %+v", name, all)
+		}
+		if head, _ := store.Head(); head != 4 {
+			t.Errorf("%s: expected head 4, got %d", name, head)
+		}
+	}
+}
+
+// CANNOT APPEND TO A STREAM THAT ISN'T AT THE EXPECTED VERSION
+func TestEventStoreConflict(t *testing.T) {
+	for name, store := range eventStores(t) {
+		if _, err := store.Append("a", 0, eventData("a1")); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		for _, expected := range []int{0, 2} {
+			if _, err := store.Append("a", expected, eventData("a2")); !errors.Is(err, ErrConflict) {
+				t.Errorf("%s: expected a conflict at version %d, got %v", name, expected, err)
+			}
+		}
+		if events, _ := store.Load("a", 0); len(events) != 1 {
+			t.Errorf("%s: expected the conflicting events to be left out, got %+v", name, events)
+		}
+	}
+}
+
+// Snapshots
+// CAN KEEP THE LATEST SNAPSHOT OF A STREAM
+func TestEventStoreSnapshot(t *testing.T) {
+	for name, store := range eventStores(t) {
+		if _, err := store.LoadSnapshot("a"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected no snapshot, got %v", name, err)
+		}
+		for _, version := range []int{1, 2} {
+			err := store.SaveSnapshot(Snapshot{Stream: "a", Version: version, Data: json.RawMessage(`{}`)})
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+		snapshot, err := store.LoadSnapshot("a")
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if snapshot.Version != 2 {
+			t.Errorf("%s: expected the snapshot at version 2, got %d", name, snapshot.Version)
+		}
+	}
+}
+
+// FileEventStore
+// CAN READ WHAT WAS APPENDED BEFORE IT WAS ATTACHED
+func TestFileEventStoreReattach(t *testing.T) {
+	dir := t.TempDir()
+	store := attachEventStore(t, NewFileEventStore(dir))
+	if _, err := store.Append("a", 0, eventData("a1", "a2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveSnapshot(Snapshot{Stream: "a", Version: 1, Data: json.RawMessage(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	store.Detach()
+
+	store = attachEventStore(t, NewFileEventStore(dir))
+	if events, _ := store.Load("a", 0); len(events) != 2 {
+		t.Errorf("expected 2 events, got %+v", events)
+	}
+	if snapshot, err := store.LoadSnapshot("a"); err != nil || snapshot.Version != 1 {
+		t.Errorf("expected the snapshot at version 1, got %+v %v", snapshot, err)
+	}
+	if _, err := store.Append("a", 2, eventData("a3")); err != nil {
+		t.Error(err)
+	}
+}
+
+// CAN ATTACH WITH A LINE THAT WASN'T WRITTEN IN FULL
+func TestFileEventStorePartialLine(t *testing.T) {
+	dir := t.TempDir()
+	store := attachEventStore(t, NewFileEventStore(dir))
+	if _, err := store.Append("a", 0, eventData("a1")); err != nil {
+		t.Fatal(err)
+	}
+	store.Detach()
+
+	path := filepath.Join(dir, "events.jsonl")
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"stream":"a","vers`)
+	file.Close()
+
+	store = attachEventStore(t, NewFileEventStore(dir))
+	if _, err := store.Append("a", 1, eventData("a2")); err != nil {
+		t.Fatal(err)
+	}
+	store.Detach()
+
+	store = attachEventStore(t, NewFileEventStore(dir))
+	events, err := store.Load("a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || string(events[1].Data) != `"a2"` {
+		t.Errorf("expected the partial line to be left out, got %+v", events)
+	}
+}
+
+// CANNOT ATTACH WITHOUT A DIRECTORY
+func TestFileEventStoreDir(t *testing.T) {
+	if _, err := NewFileEventStore("").Attach(); err == nil {
+		t.Error("expected an error without a directory")
+	}
+}
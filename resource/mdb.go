@@ -10,21 +10,51 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// The server a MongoConnection connects to when it isn't given a URL
+const DefaultMongoURL = "mongodb://localhost:27017/?directConnection=true"
+
+// MongoConnection connects to a MongoDB server. Its settings are usually filled
+// from configuration by the host, i.e. with packages.ConfigSources.Configure
 type MongoConnection struct {
-	Client *mongo.Client
+	// The connection string of the server. If it's empty the server is read
+	// from MONGO_HOST, and otherwise it's DefaultMongoURL
+	URL string
+	// The credentials to connect with, if the server has authentication
+	// enabled. Without a password they're read from MONGO_USER and
+	// MONGO_PASSWORD
+	Username string
+	Password string
+	Client   *mongo.Client
 }
 
-func (conn MongoConnection) Attach() (Resource, error) {
-	url := fmt.Sprintf("mongodb://%s:%d/?directConnection=true", os.Getenv("MONGO_HOST"), 27017)
+// Returns the options the connection connects with, falling back to the
+// environment for the settings it doesn't have
+func (conn MongoConnection) clientOptions() *options.ClientOptions {
+	url := conn.URL
+	if url == "" {
+		url = DefaultMongoURL
+		if host := os.Getenv("MONGO_HOST"); host != "" {
+			url = fmt.Sprintf("mongodb://%s:%d/?directConnection=true", host, 27017)
+		}
+	}
 	opts := options.Client().ApplyURI(url)
-	if os.Getenv("MONGO_PASSWORD") != "" {
+	username, password := conn.Username, conn.Password
+	if password == "" {
+		username, password = os.Getenv("MONGO_USER"), os.Getenv("MONGO_PASSWORD")
+	}
+	if password != "" {
 		// creds enabled
 		cred := options.Credential{
-			Username: os.Getenv("MONGO_USER"),
-			Password: os.Getenv("MONGO_PASSWORD"),
+			Username: username,
+			Password: password,
 		}
 		opts = opts.SetAuth(cred)
 	}
+	return opts
+}
+
+func (conn MongoConnection) Attach() (Resource, error) {
+	opts := conn.clientOptions()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -0,0 +1,236 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// MemoryStore is a StorageDriver that keeps every document in memory. Nothing
+// is persisted, so it's meant for tests and local development
+type MemoryStore struct {
+	mu          *sync.RWMutex
+	collections map[string]map[string][]byte
+}
+
+func NewMemoryStore() MemoryStore {
+	return MemoryStore{
+		mu:          &sync.RWMutex{},
+		collections: make(map[string]map[string][]byte),
+	}
+}
+
+func (store MemoryStore) Attach() (Resource, error) {
+	if store.collections == nil {
+		return NewMemoryStore(), nil
+	}
+	return store, nil
+}
+func (store MemoryStore) Detach() error {
+	return nil
+}
+
+// Documents are kept as JSON so that nothing outside of the store can change
+// them once they've been written
+func decodeDocument(data []byte) (Document, error) {
+	var doc Document
+	if err := decodeJSON(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// Returns a value as it would be after being stored
+func normalizeValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := decodeJSON(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func normalizeFilter(filter Document) (map[string]interface{}, error) {
+	normalized := make(map[string]interface{}, len(filter))
+	for key, value := range filter {
+		value, err := normalizeValue(value)
+		if err != nil {
+			return nil, err
+		}
+		normalized[key] = value
+	}
+	return normalized, nil
+}
+
+func matchesFilter(doc Document, filter map[string]interface{}) bool {
+	for key, value := range filter {
+		if !reflect.DeepEqual(doc[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks that a document has the fields a write has to match
+func matchWrite(write Write, data []byte, exists bool) error {
+	if !exists {
+		return fmt.Errorf("%s %s: %w", write.Collection, write.Key, ErrNotFound)
+	}
+	doc, err := decodeDocument(data)
+	if err != nil {
+		return err
+	}
+	filter, err := normalizeFilter(write.Match)
+	if err != nil {
+		return err
+	}
+	if !matchesFilter(doc, filter) {
+		return fmt.Errorf("%s %s has changed: %w", write.Collection, write.Key, ErrConflict)
+	}
+	return nil
+}
+
+func (store MemoryStore) Get(collection, key string) (Document, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	data, ok := store.collections[collection][key]
+	if !ok {
+		return nil, fmt.Errorf("%s %s: %w", collection, key, ErrNotFound)
+	}
+	return decodeDocument(data)
+}
+func (store MemoryStore) Find(collection string, filter Document) ([]Record, error) {
+	normalized, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	keys := make([]string, 0, len(store.collections[collection]))
+	for key := range store.collections[collection] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := []Record{}
+	for _, key := range keys {
+		doc, err := decodeDocument(store.collections[collection][key])
+		if err != nil {
+			return nil, err
+		}
+		if matchesFilter(doc, normalized) {
+			out = append(out, Record{Key: key, Document: doc})
+		}
+	}
+	return out, nil
+}
+func (store MemoryStore) Count(collection string, filter Document) (int, error) {
+	records, err := store.Find(collection, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+func (store MemoryStore) Insert(collection, key string, doc Document) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.collections[collection][key]; ok {
+		return fmt.Errorf("%s %s: %w", collection, key, ErrExists)
+	}
+	if store.collections[collection] == nil {
+		store.collections[collection] = make(map[string][]byte)
+	}
+	store.collections[collection][key] = data
+	return nil
+}
+func (store MemoryStore) Put(collection, key string, doc Document) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.collections[collection] == nil {
+		store.collections[collection] = make(map[string][]byte)
+	}
+	store.collections[collection][key] = data
+	return nil
+}
+func (store MemoryStore) Delete(collection, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.collections[collection][key]; !ok {
+		return fmt.Errorf("%s %s: %w", collection, key, ErrNotFound)
+	}
+	delete(store.collections[collection], key)
+	return nil
+}
+func (store MemoryStore) Apply(writes []Write) error {
+	data := make([][]byte, len(writes))
+	for idx, write := range writes {
+		if write.Document == nil {
+			continue
+		}
+		var err error
+		data[idx], err = json.Marshal(write.Document)
+		if err != nil {
+			return err
+		}
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	// every write is checked against the documents as they'd be after the ones
+	// before it, before any of them are made
+	written := make(map[string]map[string][]byte)
+	stored := func(collection, key string) ([]byte, bool) {
+		if data, ok := written[collection][key]; ok {
+			return data, data != nil
+		}
+		data, ok := store.collections[collection][key]
+		return data, ok
+	}
+	for idx, write := range writes {
+		current, exists := stored(write.Collection, write.Key)
+		switch {
+		case write.Document == nil && !exists:
+			return fmt.Errorf("%s %s: %w", write.Collection, write.Key, ErrNotFound)
+		case write.Insert && exists:
+			return fmt.Errorf("%s %s: %w", write.Collection, write.Key, ErrExists)
+		}
+		if write.Match != nil {
+			if err := matchWrite(write, current, exists); err != nil {
+				return err
+			}
+		}
+		if written[write.Collection] == nil {
+			written[write.Collection] = make(map[string][]byte)
+		}
+		written[write.Collection][write.Key] = data[idx]
+	}
+	for idx, write := range writes {
+		if data[idx] == nil {
+			delete(store.collections[write.Collection], write.Key)
+			continue
+		}
+		if store.collections[write.Collection] == nil {
+			store.collections[write.Collection] = make(map[string][]byte)
+		}
+		store.collections[write.Collection][write.Key] = data[idx]
+	}
+	return nil
+}
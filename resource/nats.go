@@ -9,13 +9,29 @@ import (
 	"github.com/nats-io/nats.go"
 )
 
+// NatsConnection connects to a NATS server. Its settings are usually filled
+// from configuration by the host, i.e. with packages.ConfigSources.Configure
 type NatsConnection struct {
+	// The URL of the server. If it's empty the server is read from NATS_HOST,
+	// and otherwise it's nats.DefaultURL
+	URL    string
 	Client *nats.Conn
 }
 
+// Returns the URL the connection connects to
+func (conn NatsConnection) url() string {
+	if conn.URL != "" {
+		return conn.URL
+	}
+	if host := os.Getenv("NATS_HOST"); host != "" {
+		return fmt.Sprintf("nats://%s:%d", host, 4222)
+	}
+	return nats.DefaultURL
+}
+
 func (conn NatsConnection) Attach() (Resource, error) {
 	nc, err := nats.Connect(
-		fmt.Sprintf("nats://%s:%d", os.Getenv("NATS_HOST"), 4222),
+		conn.url(),
 		nats.PingInterval(20*time.Second),
 		nats.MaxPingsOutstanding(5),
 		// TODO: this will never stop reconnecting. should it?
@@ -43,3 +59,38 @@ func (conn NatsConnection) Attach() (Resource, error) {
 func (conn NatsConnection) Detach() error {
 	return nil
 }
+
+// NatsBus is an EventBus that carries messages over a NatsConnection. Messages
+// are delivered asynchronously, so errors from handlers are only logged
+type NatsBus struct {
+	Connection NatsConnection
+}
+
+func (bus NatsBus) Attach() (Resource, error) {
+	if bus.Connection.Client == nil {
+		conn, err := bus.Connection.Attach()
+		if err != nil {
+			return nil, err
+		}
+		bus.Connection = conn.(NatsConnection)
+	}
+	return bus, nil
+}
+func (bus NatsBus) Detach() error {
+	return bus.Connection.Detach()
+}
+
+func (bus NatsBus) Publish(msg Message) error {
+	return bus.Connection.Client.Publish(msg.Subject, msg.Data)
+}
+func (bus NatsBus) Subscribe(subject, queue string, handler func(Message) error) (Subscription, error) {
+	callback := func(m *nats.Msg) {
+		if err := handler(Message{Subject: m.Subject, Data: m.Data}); err != nil {
+			log.Printf("cannot handle message on %q: %v", m.Subject, err)
+		}
+	}
+	if queue != "" {
+		return bus.Connection.Client.QueueSubscribe(subject, queue, callback)
+	}
+	return bus.Connection.Client.Subscribe(subject, callback)
+}
@@ -0,0 +1,56 @@
+package resource
+
+import (
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrExists   = errors.New("already exists")
+)
+
+// Document represents a stored record as plain JSON values
+type Document map[string]interface{}
+
+// Record represents a stored document along with the key it's stored with
+type Record struct {
+	Key      string
+	Document Document
+}
+
+// StorageDriver represents anything that can store documents in collections,
+// each document identified by a key that's unique to its collection
+type StorageDriver interface {
+	Resource
+	// Returns the document with the key, or ErrNotFound if there isn't one
+	Get(collection, key string) (Document, error)
+	// Returns every document whose fields are equal to the ones in the filter,
+	// ordered by their keys
+	Find(collection string, filter Document) ([]Record, error)
+	Count(collection string, filter Document) (int, error)
+	// Adds a document, or returns ErrExists if the key is already used
+	Insert(collection, key string, doc Document) error
+	// Adds or replaces a document
+	Put(collection, key string, doc Document) error
+	// Removes a document, or returns ErrNotFound if there isn't one
+	Delete(collection, key string) error
+	// Makes every write or none of them. Writes fail the same way Insert and
+	// Delete do, seeing the writes before them as if they'd already been made.
+	// A write with fields to match fails with ErrConflict if the document
+	// doesn't have them
+	Apply(writes []Write) error
+}
+
+// Write represents a change to a document that's made along with others
+type Write struct {
+	Collection string
+	Key        string
+	// nil if the write removes the document
+	Document Document
+	// whether the write fails if the key is already used
+	Insert bool
+	// the fields the stored document has to have for the write to be made, so
+	// that a document can be replaced or removed only if it hasn't changed since
+	// it was read
+	Match Document
+}